@@ -0,0 +1,780 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v6.33.2
+// source: proto/chunk.proto
+
+package api
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// WriteChunk messages
+type WriteChunkRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*WriteChunkRequest_Header
+	//	*WriteChunkRequest_Chunk
+	Data          isWriteChunkRequest_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteChunkRequest) Reset() {
+	*x = WriteChunkRequest{}
+	mi := &file_proto_chunk_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteChunkRequest) ProtoMessage() {}
+
+func (x *WriteChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteChunkRequest.ProtoReflect.Descriptor instead.
+func (*WriteChunkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *WriteChunkRequest) GetData() isWriteChunkRequest_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *WriteChunkRequest) GetHeader() *ChunkHeader {
+	if x != nil {
+		if x, ok := x.Data.(*WriteChunkRequest_Header); ok {
+			return x.Header
+		}
+	}
+	return nil
+}
+
+func (x *WriteChunkRequest) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*WriteChunkRequest_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isWriteChunkRequest_Data interface {
+	isWriteChunkRequest_Data()
+}
+
+type WriteChunkRequest_Header struct {
+	Header *ChunkHeader `protobuf:"bytes,1,opt,name=header,proto3,oneof"` // First message identifies the chunk
+}
+
+type WriteChunkRequest_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Subsequent messages contain chunk data
+}
+
+func (*WriteChunkRequest_Header) isWriteChunkRequest_Data() {}
+
+func (*WriteChunkRequest_Chunk) isWriteChunkRequest_Data() {}
+
+type ChunkHeader struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	// Chunkservers to forward the data to after storing it locally.
+	// The receiver passes the data to pipeline[0] with pipeline[1:].
+	Pipeline      []string `protobuf:"bytes,2,rep,name=pipeline,proto3" json:"pipeline,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ChunkHeader) Reset() {
+	*x = ChunkHeader{}
+	mi := &file_proto_chunk_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkHeader) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkHeader) ProtoMessage() {}
+
+func (x *ChunkHeader) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkHeader.ProtoReflect.Descriptor instead.
+func (*ChunkHeader) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ChunkHeader) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ChunkHeader) GetPipeline() []string {
+	if x != nil {
+		return x.Pipeline
+	}
+	return nil
+}
+
+type WriteChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Size          int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`         // Bytes stored
+	Replicas      int32                  `protobuf:"varint,4,opt,name=replicas,proto3" json:"replicas,omitempty"` // Replicas stored, counting this chunkserver and everything downstream
+	Crc32C        uint32                 `protobuf:"varint,5,opt,name=crc32c,proto3" json:"crc32c,omitempty"`     // CRC-32C (Castagnoli) of the data as stored
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WriteChunkResponse) Reset() {
+	*x = WriteChunkResponse{}
+	mi := &file_proto_chunk_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteChunkResponse) ProtoMessage() {}
+
+func (x *WriteChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteChunkResponse.ProtoReflect.Descriptor instead.
+func (*WriteChunkResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WriteChunkResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *WriteChunkResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *WriteChunkResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *WriteChunkResponse) GetReplicas() int32 {
+	if x != nil {
+		return x.Replicas
+	}
+	return 0
+}
+
+func (x *WriteChunkResponse) GetCrc32C() uint32 {
+	if x != nil {
+		return x.Crc32C
+	}
+	return 0
+}
+
+// ReadChunk messages
+type ReadChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // Where in the chunk to start reading
+	Length        int64                  `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"` // Bytes to read; 0 reads to the end of the chunk
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadChunkRequest) Reset() {
+	*x = ReadChunkRequest{}
+	mi := &file_proto_chunk_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadChunkRequest) ProtoMessage() {}
+
+func (x *ReadChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadChunkRequest.ProtoReflect.Descriptor instead.
+func (*ReadChunkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ReadChunkRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ReadChunkRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ReadChunkRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+type ReadChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Chunk         []byte                 `protobuf:"bytes,1,opt,name=chunk,proto3" json:"chunk,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadChunkResponse) Reset() {
+	*x = ReadChunkResponse{}
+	mi := &file_proto_chunk_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadChunkResponse) ProtoMessage() {}
+
+func (x *ReadChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadChunkResponse.ProtoReflect.Descriptor instead.
+func (*ReadChunkResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ReadChunkResponse) GetChunk() []byte {
+	if x != nil {
+		return x.Chunk
+	}
+	return nil
+}
+
+// DeleteChunk messages
+type DeleteChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteChunkRequest) Reset() {
+	*x = DeleteChunkRequest{}
+	mi := &file_proto_chunk_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteChunkRequest) ProtoMessage() {}
+
+func (x *DeleteChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteChunkRequest.ProtoReflect.Descriptor instead.
+func (*DeleteChunkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DeleteChunkRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+type DeleteChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteChunkResponse) Reset() {
+	*x = DeleteChunkResponse{}
+	mi := &file_proto_chunk_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteChunkResponse) ProtoMessage() {}
+
+func (x *DeleteChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteChunkResponse.ProtoReflect.Descriptor instead.
+func (*DeleteChunkResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteChunkResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteChunkResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// ReplicateChunk messages
+type ReplicateChunkRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId       string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Targets       []string               `protobuf:"bytes,2,rep,name=targets,proto3" json:"targets,omitempty"` // Chunkservers to copy the chunk to
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateChunkRequest) Reset() {
+	*x = ReplicateChunkRequest{}
+	mi := &file_proto_chunk_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateChunkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateChunkRequest) ProtoMessage() {}
+
+func (x *ReplicateChunkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateChunkRequest.ProtoReflect.Descriptor instead.
+func (*ReplicateChunkRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ReplicateChunkRequest) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ReplicateChunkRequest) GetTargets() []string {
+	if x != nil {
+		return x.Targets
+	}
+	return nil
+}
+
+type ReplicateChunkResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplicateChunkResponse) Reset() {
+	*x = ReplicateChunkResponse{}
+	mi := &file_proto_chunk_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplicateChunkResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplicateChunkResponse) ProtoMessage() {}
+
+func (x *ReplicateChunkResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplicateChunkResponse.ProtoReflect.Descriptor instead.
+func (*ReplicateChunkResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReplicateChunkResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ReplicateChunkResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Heartbeat messages
+type HeartbeatRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Address       string                 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`                                   // Address the master and clients use to reach this chunkserver
+	CapacityBytes int64                  `protobuf:"varint,2,opt,name=capacity_bytes,json=capacityBytes,proto3" json:"capacity_bytes,omitempty"` // Total space available for chunks
+	UsedBytes     int64                  `protobuf:"varint,3,opt,name=used_bytes,json=usedBytes,proto3" json:"used_bytes,omitempty"`             // Space used by stored chunks
+	ChunkIds      []string               `protobuf:"bytes,4,rep,name=chunk_ids,json=chunkIds,proto3" json:"chunk_ids,omitempty"`                 // Every chunk stored on this chunkserver
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HeartbeatRequest) Reset() {
+	*x = HeartbeatRequest{}
+	mi := &file_proto_chunk_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatRequest) ProtoMessage() {}
+
+func (x *HeartbeatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *HeartbeatRequest) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *HeartbeatRequest) GetCapacityBytes() int64 {
+	if x != nil {
+		return x.CapacityBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetUsedBytes() int64 {
+	if x != nil {
+		return x.UsedBytes
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetChunkIds() []string {
+	if x != nil {
+		return x.ChunkIds
+	}
+	return nil
+}
+
+type HeartbeatResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	DeleteChunkIds []string               `protobuf:"bytes,1,rep,name=delete_chunk_ids,json=deleteChunkIds,proto3" json:"delete_chunk_ids,omitempty"` // Chunks the master no longer references
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *HeartbeatResponse) Reset() {
+	*x = HeartbeatResponse{}
+	mi := &file_proto_chunk_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HeartbeatResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatResponse) ProtoMessage() {}
+
+func (x *HeartbeatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_chunk_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatResponse.ProtoReflect.Descriptor instead.
+func (*HeartbeatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_chunk_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *HeartbeatResponse) GetDeleteChunkIds() []string {
+	if x != nil {
+		return x.DeleteChunkIds
+	}
+	return nil
+}
+
+var File_proto_chunk_proto protoreflect.FileDescriptor
+
+const file_proto_chunk_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/chunk.proto\x12\x03dfs\"_\n" +
+	"\x11WriteChunkRequest\x12*\n" +
+	"\x06header\x18\x01 \x01(\v2\x10.dfs.ChunkHeaderH\x00R\x06header\x12\x16\n" +
+	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
+	"\x04data\"D\n" +
+	"\vChunkHeader\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x1a\n" +
+	"\bpipeline\x18\x02 \x03(\tR\bpipeline\"\x90\x01\n" +
+	"\x12WriteChunkResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x1a\n" +
+	"\breplicas\x18\x04 \x01(\x05R\breplicas\x12\x16\n" +
+	"\x06crc32c\x18\x05 \x01(\rR\x06crc32c\"]\n" +
+	"\x10ReadChunkRequest\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\")\n" +
+	"\x11ReadChunkResponse\x12\x14\n" +
+	"\x05chunk\x18\x01 \x01(\fR\x05chunk\"/\n" +
+	"\x12DeleteChunkRequest\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\"I\n" +
+	"\x13DeleteChunkResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"L\n" +
+	"\x15ReplicateChunkRequest\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x18\n" +
+	"\atargets\x18\x02 \x03(\tR\atargets\"L\n" +
+	"\x16ReplicateChunkResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\x8f\x01\n" +
+	"\x10HeartbeatRequest\x12\x18\n" +
+	"\aaddress\x18\x01 \x01(\tR\aaddress\x12%\n" +
+	"\x0ecapacity_bytes\x18\x02 \x01(\x03R\rcapacityBytes\x12\x1d\n" +
+	"\n" +
+	"used_bytes\x18\x03 \x01(\x03R\tusedBytes\x12\x1b\n" +
+	"\tchunk_ids\x18\x04 \x03(\tR\bchunkIds\"=\n" +
+	"\x11HeartbeatResponse\x12(\n" +
+	"\x10delete_chunk_ids\x18\x01 \x03(\tR\x0edeleteChunkIds2\x9a\x02\n" +
+	"\fChunkService\x12?\n" +
+	"\n" +
+	"WriteChunk\x12\x16.dfs.WriteChunkRequest\x1a\x17.dfs.WriteChunkResponse(\x01\x12<\n" +
+	"\tReadChunk\x12\x15.dfs.ReadChunkRequest\x1a\x16.dfs.ReadChunkResponse0\x01\x12@\n" +
+	"\vDeleteChunk\x12\x17.dfs.DeleteChunkRequest\x1a\x18.dfs.DeleteChunkResponse\x12I\n" +
+	"\x0eReplicateChunk\x12\x1a.dfs.ReplicateChunkRequest\x1a\x1b.dfs.ReplicateChunkResponse2K\n" +
+	"\rMasterService\x12:\n" +
+	"\tHeartbeat\x12\x15.dfs.HeartbeatRequest\x1a\x16.dfs.HeartbeatResponseB$Z\"github.com/darshanmadesh/godfs/apib\x06proto3"
+
+var (
+	file_proto_chunk_proto_rawDescOnce sync.Once
+	file_proto_chunk_proto_rawDescData []byte
+)
+
+func file_proto_chunk_proto_rawDescGZIP() []byte {
+	file_proto_chunk_proto_rawDescOnce.Do(func() {
+		file_proto_chunk_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_chunk_proto_rawDesc), len(file_proto_chunk_proto_rawDesc)))
+	})
+	return file_proto_chunk_proto_rawDescData
+}
+
+var file_proto_chunk_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_proto_chunk_proto_goTypes = []any{
+	(*WriteChunkRequest)(nil),      // 0: dfs.WriteChunkRequest
+	(*ChunkHeader)(nil),            // 1: dfs.ChunkHeader
+	(*WriteChunkResponse)(nil),     // 2: dfs.WriteChunkResponse
+	(*ReadChunkRequest)(nil),       // 3: dfs.ReadChunkRequest
+	(*ReadChunkResponse)(nil),      // 4: dfs.ReadChunkResponse
+	(*DeleteChunkRequest)(nil),     // 5: dfs.DeleteChunkRequest
+	(*DeleteChunkResponse)(nil),    // 6: dfs.DeleteChunkResponse
+	(*ReplicateChunkRequest)(nil),  // 7: dfs.ReplicateChunkRequest
+	(*ReplicateChunkResponse)(nil), // 8: dfs.ReplicateChunkResponse
+	(*HeartbeatRequest)(nil),       // 9: dfs.HeartbeatRequest
+	(*HeartbeatResponse)(nil),      // 10: dfs.HeartbeatResponse
+}
+var file_proto_chunk_proto_depIdxs = []int32{
+	1,  // 0: dfs.WriteChunkRequest.header:type_name -> dfs.ChunkHeader
+	0,  // 1: dfs.ChunkService.WriteChunk:input_type -> dfs.WriteChunkRequest
+	3,  // 2: dfs.ChunkService.ReadChunk:input_type -> dfs.ReadChunkRequest
+	5,  // 3: dfs.ChunkService.DeleteChunk:input_type -> dfs.DeleteChunkRequest
+	7,  // 4: dfs.ChunkService.ReplicateChunk:input_type -> dfs.ReplicateChunkRequest
+	9,  // 5: dfs.MasterService.Heartbeat:input_type -> dfs.HeartbeatRequest
+	2,  // 6: dfs.ChunkService.WriteChunk:output_type -> dfs.WriteChunkResponse
+	4,  // 7: dfs.ChunkService.ReadChunk:output_type -> dfs.ReadChunkResponse
+	6,  // 8: dfs.ChunkService.DeleteChunk:output_type -> dfs.DeleteChunkResponse
+	8,  // 9: dfs.ChunkService.ReplicateChunk:output_type -> dfs.ReplicateChunkResponse
+	10, // 10: dfs.MasterService.Heartbeat:output_type -> dfs.HeartbeatResponse
+	6,  // [6:11] is the sub-list for method output_type
+	1,  // [1:6] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_proto_chunk_proto_init() }
+func file_proto_chunk_proto_init() {
+	if File_proto_chunk_proto != nil {
+		return
+	}
+	file_proto_chunk_proto_msgTypes[0].OneofWrappers = []any{
+		(*WriteChunkRequest_Header)(nil),
+		(*WriteChunkRequest_Chunk)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_chunk_proto_rawDesc), len(file_proto_chunk_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_proto_chunk_proto_goTypes,
+		DependencyIndexes: file_proto_chunk_proto_depIdxs,
+		MessageInfos:      file_proto_chunk_proto_msgTypes,
+	}.Build()
+	File_proto_chunk_proto = out.File
+	file_proto_chunk_proto_goTypes = nil
+	file_proto_chunk_proto_depIdxs = nil
+}
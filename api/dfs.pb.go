@@ -21,6 +21,268 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+// WriteMode controls how an upload treats an existing file of the same name.
+type WriteMode int32
+
+const (
+	WriteMode_WRITE_MODE_CREATE              WriteMode = 0 // Fail if the file exists (the default)
+	WriteMode_WRITE_MODE_OVERWRITE           WriteMode = 1 // Replace the file if it exists
+	WriteMode_WRITE_MODE_IF_GENERATION_MATCH WriteMode = 2 // Replace only if its generation equals if_generation; 0 means it must not exist
+)
+
+// Enum value maps for WriteMode.
+var (
+	WriteMode_name = map[int32]string{
+		0: "WRITE_MODE_CREATE",
+		1: "WRITE_MODE_OVERWRITE",
+		2: "WRITE_MODE_IF_GENERATION_MATCH",
+	}
+	WriteMode_value = map[string]int32{
+		"WRITE_MODE_CREATE":              0,
+		"WRITE_MODE_OVERWRITE":           1,
+		"WRITE_MODE_IF_GENERATION_MATCH": 2,
+	}
+)
+
+func (x WriteMode) Enum() *WriteMode {
+	p := new(WriteMode)
+	*p = x
+	return p
+}
+
+func (x WriteMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WriteMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dfs_proto_enumTypes[0].Descriptor()
+}
+
+func (WriteMode) Type() protoreflect.EnumType {
+	return &file_proto_dfs_proto_enumTypes[0]
+}
+
+func (x WriteMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WriteMode.Descriptor instead.
+func (WriteMode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{0}
+}
+
+// ChunkingMode is how an upload is cut into chunks. Content-defined
+// chunking cuts where the data says to, so a small edit to a big file
+// leaves most chunks as they were and they are stored only once.
+type ChunkingMode int32
+
+const (
+	ChunkingMode_CHUNKING_MODE_DEFAULT         ChunkingMode = 0 // Whatever the master is configured with
+	ChunkingMode_CHUNKING_MODE_FIXED           ChunkingMode = 1 // Every chunk-size bytes
+	ChunkingMode_CHUNKING_MODE_CONTENT_DEFINED ChunkingMode = 2 // At content-defined boundaries (FastCDC)
+)
+
+// Enum value maps for ChunkingMode.
+var (
+	ChunkingMode_name = map[int32]string{
+		0: "CHUNKING_MODE_DEFAULT",
+		1: "CHUNKING_MODE_FIXED",
+		2: "CHUNKING_MODE_CONTENT_DEFINED",
+	}
+	ChunkingMode_value = map[string]int32{
+		"CHUNKING_MODE_DEFAULT":         0,
+		"CHUNKING_MODE_FIXED":           1,
+		"CHUNKING_MODE_CONTENT_DEFINED": 2,
+	}
+)
+
+func (x ChunkingMode) Enum() *ChunkingMode {
+	p := new(ChunkingMode)
+	*p = x
+	return p
+}
+
+func (x ChunkingMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ChunkingMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dfs_proto_enumTypes[1].Descriptor()
+}
+
+func (ChunkingMode) Type() protoreflect.EnumType {
+	return &file_proto_dfs_proto_enumTypes[1]
+}
+
+func (x ChunkingMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ChunkingMode.Descriptor instead.
+func (ChunkingMode) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{1}
+}
+
+// Compression is how data is compressed at rest. Compressed chunks are
+// decompressed when read, through the master or straight from chunkservers.
+type Compression int32
+
+const (
+	Compression_COMPRESSION_DEFAULT Compression = 0 // Whatever the master's rules say (upload only)
+	Compression_COMPRESSION_NONE    Compression = 1
+	Compression_COMPRESSION_GZIP    Compression = 2
+	Compression_COMPRESSION_ZSTD    Compression = 3
+)
+
+// Enum value maps for Compression.
+var (
+	Compression_name = map[int32]string{
+		0: "COMPRESSION_DEFAULT",
+		1: "COMPRESSION_NONE",
+		2: "COMPRESSION_GZIP",
+		3: "COMPRESSION_ZSTD",
+	}
+	Compression_value = map[string]int32{
+		"COMPRESSION_DEFAULT": 0,
+		"COMPRESSION_NONE":    1,
+		"COMPRESSION_GZIP":    2,
+		"COMPRESSION_ZSTD":    3,
+	}
+)
+
+func (x Compression) Enum() *Compression {
+	p := new(Compression)
+	*p = x
+	return p
+}
+
+func (x Compression) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Compression) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dfs_proto_enumTypes[2].Descriptor()
+}
+
+func (Compression) Type() protoreflect.EnumType {
+	return &file_proto_dfs_proto_enumTypes[2]
+}
+
+func (x Compression) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Compression.Descriptor instead.
+func (Compression) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{2}
+}
+
+// ListSort is the order entries are listed in. Ties are broken by path.
+type ListSort int32
+
+const (
+	ListSort_LIST_SORT_NAME     ListSort = 0 // By path (the default)
+	ListSort_LIST_SORT_SIZE     ListSort = 1 // By size in bytes
+	ListSort_LIST_SORT_MODIFIED ListSort = 2 // By modification time
+)
+
+// Enum value maps for ListSort.
+var (
+	ListSort_name = map[int32]string{
+		0: "LIST_SORT_NAME",
+		1: "LIST_SORT_SIZE",
+		2: "LIST_SORT_MODIFIED",
+	}
+	ListSort_value = map[string]int32{
+		"LIST_SORT_NAME":     0,
+		"LIST_SORT_SIZE":     1,
+		"LIST_SORT_MODIFIED": 2,
+	}
+)
+
+func (x ListSort) Enum() *ListSort {
+	p := new(ListSort)
+	*p = x
+	return p
+}
+
+func (x ListSort) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ListSort) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dfs_proto_enumTypes[3].Descriptor()
+}
+
+func (ListSort) Type() protoreflect.EnumType {
+	return &file_proto_dfs_proto_enumTypes[3]
+}
+
+func (x ListSort) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ListSort.Descriptor instead.
+func (ListSort) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{3}
+}
+
+// WatchEventType is the kind of change an event describes.
+type WatchEventType int32
+
+const (
+	WatchEventType_WATCH_EVENT_CREATE     WatchEventType = 0 // A file or directory was created
+	WatchEventType_WATCH_EVENT_UPDATE     WatchEventType = 1 // A file's contents were replaced
+	WatchEventType_WATCH_EVENT_DELETE     WatchEventType = 2 // A file or directory was deleted (or moved to the trash)
+	WatchEventType_WATCH_EVENT_RENAME     WatchEventType = 3 // A file or directory moved from old_path to path
+	WatchEventType_WATCH_EVENT_ATTRIBUTES WatchEventType = 4 // A file's or directory's user metadata changed
+)
+
+// Enum value maps for WatchEventType.
+var (
+	WatchEventType_name = map[int32]string{
+		0: "WATCH_EVENT_CREATE",
+		1: "WATCH_EVENT_UPDATE",
+		2: "WATCH_EVENT_DELETE",
+		3: "WATCH_EVENT_RENAME",
+		4: "WATCH_EVENT_ATTRIBUTES",
+	}
+	WatchEventType_value = map[string]int32{
+		"WATCH_EVENT_CREATE":     0,
+		"WATCH_EVENT_UPDATE":     1,
+		"WATCH_EVENT_DELETE":     2,
+		"WATCH_EVENT_RENAME":     3,
+		"WATCH_EVENT_ATTRIBUTES": 4,
+	}
+)
+
+func (x WatchEventType) Enum() *WatchEventType {
+	p := new(WatchEventType)
+	*p = x
+	return p
+}
+
+func (x WatchEventType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WatchEventType) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_dfs_proto_enumTypes[4].Descriptor()
+}
+
+func (WatchEventType) Type() protoreflect.EnumType {
+	return &file_proto_dfs_proto_enumTypes[4]
+}
+
+func (x WatchEventType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WatchEventType.Descriptor instead.
+func (WatchEventType) EnumDescriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{4}
+}
+
 // Upload messages
 type UploadRequest struct {
 	state protoimpl.MessageState `protogen:"open.v1"`
@@ -105,9 +367,21 @@ func (*UploadRequest_Metadata) isUploadRequest_Data() {}
 func (*UploadRequest_Chunk) isUploadRequest_Data() {}
 
 type FileMetadata struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Filename     string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size         int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	Replication  int32                  `protobuf:"varint,3,opt,name=replication,proto3" json:"replication,omitempty"`                       // Number of replicas to store; 0 uses the cluster default
+	Checksum     string                 `protobuf:"bytes,4,opt,name=checksum,proto3" json:"checksum,omitempty"`                              // SHA-256 of the contents, hex. Optional on upload; if set, the server verifies it
+	Mode         WriteMode              `protobuf:"varint,5,opt,name=mode,proto3,enum=dfs.WriteMode" json:"mode,omitempty"`                  // What to do if the file already exists (upload only)
+	IfGeneration int64                  `protobuf:"varint,6,opt,name=if_generation,json=ifGeneration,proto3" json:"if_generation,omitempty"` // Expected current generation for WRITE_MODE_IF_GENERATION_MATCH
+	Parents      bool                   `protobuf:"varint,7,opt,name=parents,proto3" json:"parents,omitempty"`                               // Create missing parent directories (upload only)
+	// User metadata: free-form key/value attributes ("owner": "ml-infra",
+	// "build": "4711") and tags ("release"). Stored with the file, replaced
+	// by the next upload, and changed in place with SetAttributes.
+	Attributes    map[string]string `protobuf:"bytes,8,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Tags          []string          `protobuf:"bytes,9,rep,name=tags,proto3" json:"tags,omitempty"`
+	Chunking      ChunkingMode      `protobuf:"varint,10,opt,name=chunking,proto3,enum=dfs.ChunkingMode" json:"chunking,omitempty"`      // How to cut the file into chunks (upload only)
+	Compression   Compression       `protobuf:"varint,11,opt,name=compression,proto3,enum=dfs.Compression" json:"compression,omitempty"` // How to compress the file at rest (upload only)
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -156,11 +430,80 @@ func (x *FileMetadata) GetSize() int64 {
 	return 0
 }
 
+func (x *FileMetadata) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *FileMetadata) GetMode() WriteMode {
+	if x != nil {
+		return x.Mode
+	}
+	return WriteMode_WRITE_MODE_CREATE
+}
+
+func (x *FileMetadata) GetIfGeneration() int64 {
+	if x != nil {
+		return x.IfGeneration
+	}
+	return 0
+}
+
+func (x *FileMetadata) GetParents() bool {
+	if x != nil {
+		return x.Parents
+	}
+	return false
+}
+
+func (x *FileMetadata) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *FileMetadata) GetChunking() ChunkingMode {
+	if x != nil {
+		return x.Chunking
+	}
+	return ChunkingMode_CHUNKING_MODE_DEFAULT
+}
+
+func (x *FileMetadata) GetCompression() Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return Compression_COMPRESSION_DEFAULT
+}
+
 type UploadResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	FileId        string                 `protobuf:"bytes,3,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Success    bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message    string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FileId     string                 `protobuf:"bytes,3,opt,name=file_id,json=fileId,proto3" json:"file_id,omitempty"`
+	Generation int64                  `protobuf:"varint,4,opt,name=generation,proto3" json:"generation,omitempty"` // Generation of the file as written
+	// Deduplication: of the file's chunks, how many (and how many bytes)
+	// were already stored and are shared rather than stored again.
+	Chunks        int32 `protobuf:"varint,5,opt,name=chunks,proto3" json:"chunks,omitempty"`
+	DedupChunks   int32 `protobuf:"varint,6,opt,name=dedup_chunks,json=dedupChunks,proto3" json:"dedup_chunks,omitempty"`
+	DedupBytes    int64 `protobuf:"varint,7,opt,name=dedup_bytes,json=dedupBytes,proto3" json:"dedup_bytes,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -216,28 +559,56 @@ func (x *UploadResponse) GetFileId() string {
 	return ""
 }
 
-// Download messages
-type DownloadRequest struct {
+func (x *UploadResponse) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *UploadResponse) GetChunks() int32 {
+	if x != nil {
+		return x.Chunks
+	}
+	return 0
+}
+
+func (x *UploadResponse) GetDedupChunks() int32 {
+	if x != nil {
+		return x.DedupChunks
+	}
+	return 0
+}
+
+func (x *UploadResponse) GetDedupBytes() int64 {
+	if x != nil {
+		return x.DedupBytes
+	}
+	return 0
+}
+
+// Upload session messages
+type StartUploadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Metadata      *FileMetadata          `protobuf:"bytes,1,opt,name=metadata,proto3" json:"metadata,omitempty"` // Same as the first message of Upload
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DownloadRequest) Reset() {
-	*x = DownloadRequest{}
+func (x *StartUploadRequest) Reset() {
+	*x = StartUploadRequest{}
 	mi := &file_proto_dfs_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DownloadRequest) String() string {
+func (x *StartUploadRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DownloadRequest) ProtoMessage() {}
+func (*StartUploadRequest) ProtoMessage() {}
 
-func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+func (x *StartUploadRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_dfs_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -249,43 +620,39 @@ func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
-func (*DownloadRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use StartUploadRequest.ProtoReflect.Descriptor instead.
+func (*StartUploadRequest) Descriptor() ([]byte, []int) {
 	return file_proto_dfs_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *DownloadRequest) GetFilename() string {
+func (x *StartUploadRequest) GetMetadata() *FileMetadata {
 	if x != nil {
-		return x.Filename
+		return x.Metadata
 	}
-	return ""
+	return nil
 }
 
-type DownloadResponse struct {
-	state protoimpl.MessageState `protogen:"open.v1"`
-	// Types that are valid to be assigned to Data:
-	//
-	//	*DownloadResponse_Metadata
-	//	*DownloadResponse_Chunk
-	Data          isDownloadResponse_Data `protobuf_oneof:"data"`
+type StartUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DownloadResponse) Reset() {
-	*x = DownloadResponse{}
+func (x *StartUploadResponse) Reset() {
+	*x = StartUploadResponse{}
 	mi := &file_proto_dfs_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DownloadResponse) String() string {
+func (x *StartUploadResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DownloadResponse) ProtoMessage() {}
+func (*StartUploadResponse) ProtoMessage() {}
 
-func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
+func (x *StartUploadResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_dfs_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -297,74 +664,41 @@ func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
-func (*DownloadResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use StartUploadResponse.ProtoReflect.Descriptor instead.
+func (*StartUploadResponse) Descriptor() ([]byte, []int) {
 	return file_proto_dfs_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *DownloadResponse) GetData() isDownloadResponse_Data {
-	if x != nil {
-		return x.Data
-	}
-	return nil
-}
-
-func (x *DownloadResponse) GetMetadata() *FileMetadata {
-	if x != nil {
-		if x, ok := x.Data.(*DownloadResponse_Metadata); ok {
-			return x.Metadata
-		}
-	}
-	return nil
-}
-
-func (x *DownloadResponse) GetChunk() []byte {
+func (x *StartUploadResponse) GetSessionId() string {
 	if x != nil {
-		if x, ok := x.Data.(*DownloadResponse_Chunk); ok {
-			return x.Chunk
-		}
+		return x.SessionId
 	}
-	return nil
-}
-
-type isDownloadResponse_Data interface {
-	isDownloadResponse_Data()
-}
-
-type DownloadResponse_Metadata struct {
-	Metadata *FileMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"` // First message contains metadata
-}
-
-type DownloadResponse_Chunk struct {
-	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Subsequent messages contain file chunks
+	return ""
 }
 
-func (*DownloadResponse_Metadata) isDownloadResponse_Data() {}
-
-func (*DownloadResponse_Chunk) isDownloadResponse_Data() {}
-
-// List messages
-type ListRequest struct {
+type WriteUploadRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Optional prefix filter
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"` // Read from the first message only
+	Offset        int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`                       // File offset of this message's data; read from the first message only
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListRequest) Reset() {
-	*x = ListRequest{}
+func (x *WriteUploadRequest) Reset() {
+	*x = WriteUploadRequest{}
 	mi := &file_proto_dfs_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListRequest) String() string {
+func (x *WriteUploadRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListRequest) ProtoMessage() {}
+func (*WriteUploadRequest) ProtoMessage() {}
 
-func (x *ListRequest) ProtoReflect() protoreflect.Message {
+func (x *WriteUploadRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_dfs_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -376,40 +710,2199 @@ func (x *ListRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
-func (*ListRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use WriteUploadRequest.ProtoReflect.Descriptor instead.
+func (*WriteUploadRequest) Descriptor() ([]byte, []int) {
 	return file_proto_dfs_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *ListRequest) GetPrefix() string {
+func (x *WriteUploadRequest) GetSessionId() string {
 	if x != nil {
-		return x.Prefix
+		return x.SessionId
 	}
 	return ""
 }
 
+func (x *WriteUploadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *WriteUploadRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type WriteUploadResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	CommittedOffset int64                  `protobuf:"varint,1,opt,name=committed_offset,json=committedOffset,proto3" json:"committed_offset,omitempty"` // Bytes durably stored so far
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *WriteUploadResponse) Reset() {
+	*x = WriteUploadResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WriteUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WriteUploadResponse) ProtoMessage() {}
+
+func (x *WriteUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WriteUploadResponse.ProtoReflect.Descriptor instead.
+func (*WriteUploadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *WriteUploadResponse) GetCommittedOffset() int64 {
+	if x != nil {
+		return x.CommittedOffset
+	}
+	return 0
+}
+
+type QueryUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryUploadRequest) Reset() {
+	*x = QueryUploadRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryUploadRequest) ProtoMessage() {}
+
+func (x *QueryUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryUploadRequest.ProtoReflect.Descriptor instead.
+func (*QueryUploadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *QueryUploadRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type QueryUploadResponse struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	CommittedOffset int64                  `protobuf:"varint,1,opt,name=committed_offset,json=committedOffset,proto3" json:"committed_offset,omitempty"` // Resume writing from here
+	Size            int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`                                              // Declared size of the file
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *QueryUploadResponse) Reset() {
+	*x = QueryUploadResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryUploadResponse) ProtoMessage() {}
+
+func (x *QueryUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryUploadResponse.ProtoReflect.Descriptor instead.
+func (*QueryUploadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *QueryUploadResponse) GetCommittedOffset() int64 {
+	if x != nil {
+		return x.CommittedOffset
+	}
+	return 0
+}
+
+func (x *QueryUploadResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type FinishUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FinishUploadRequest) Reset() {
+	*x = FinishUploadRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FinishUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FinishUploadRequest) ProtoMessage() {}
+
+func (x *FinishUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FinishUploadRequest.ProtoReflect.Descriptor instead.
+func (*FinishUploadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *FinishUploadRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type AbortUploadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SessionId     string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbortUploadRequest) Reset() {
+	*x = AbortUploadRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbortUploadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbortUploadRequest) ProtoMessage() {}
+
+func (x *AbortUploadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbortUploadRequest.ProtoReflect.Descriptor instead.
+func (*AbortUploadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AbortUploadRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+type AbortUploadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AbortUploadResponse) Reset() {
+	*x = AbortUploadResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AbortUploadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AbortUploadResponse) ProtoMessage() {}
+
+func (x *AbortUploadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AbortUploadResponse.ProtoReflect.Descriptor instead.
+func (*AbortUploadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *AbortUploadResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *AbortUploadResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Download messages
+type DownloadRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Filename string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	// Byte range to read. A negative offset counts back from the end of the
+	// file (-4096 reads the last 4KB); length 0 reads to the end.
+	Offset        int64 `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Length        int64 `protobuf:"varint,3,opt,name=length,proto3" json:"length,omitempty"`
+	Version       int64 `protobuf:"varint,4,opt,name=version,proto3" json:"version,omitempty"` // Generation of an old version to read; 0 reads the current one
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadRequest) Reset() {
+	*x = DownloadRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadRequest) ProtoMessage() {}
+
+func (x *DownloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadRequest.ProtoReflect.Descriptor instead.
+func (*DownloadRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DownloadRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *DownloadRequest) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetLength() int64 {
+	if x != nil {
+		return x.Length
+	}
+	return 0
+}
+
+func (x *DownloadRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type DownloadResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Data:
+	//
+	//	*DownloadResponse_Metadata
+	//	*DownloadResponse_Chunk
+	Data          isDownloadResponse_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DownloadResponse) Reset() {
+	*x = DownloadResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DownloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DownloadResponse) ProtoMessage() {}
+
+func (x *DownloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DownloadResponse.ProtoReflect.Descriptor instead.
+func (*DownloadResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DownloadResponse) GetData() isDownloadResponse_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *DownloadResponse) GetMetadata() *FileMetadata {
+	if x != nil {
+		if x, ok := x.Data.(*DownloadResponse_Metadata); ok {
+			return x.Metadata
+		}
+	}
+	return nil
+}
+
+func (x *DownloadResponse) GetChunk() []byte {
+	if x != nil {
+		if x, ok := x.Data.(*DownloadResponse_Chunk); ok {
+			return x.Chunk
+		}
+	}
+	return nil
+}
+
+type isDownloadResponse_Data interface {
+	isDownloadResponse_Data()
+}
+
+type DownloadResponse_Metadata struct {
+	Metadata *FileMetadata `protobuf:"bytes,1,opt,name=metadata,proto3,oneof"` // First message contains metadata
+}
+
+type DownloadResponse_Chunk struct {
+	Chunk []byte `protobuf:"bytes,2,opt,name=chunk,proto3,oneof"` // Subsequent messages contain file chunks
+}
+
+func (*DownloadResponse_Metadata) isDownloadResponse_Data() {}
+
+func (*DownloadResponse_Chunk) isDownloadResponse_Data() {}
+
+// List messages
+type ListRequest struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Prefix    string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`        // Optional path prefix filter, across all directories
+	Directory string                 `protobuf:"bytes,2,opt,name=directory,proto3" json:"directory,omitempty"`  // If set, list this directory's entries instead
+	Recursive bool                   `protobuf:"varint,3,opt,name=recursive,proto3" json:"recursive,omitempty"` // With directory: include everything below it, not just its children
+	// Listings come a page at a time. page_size caps the number of entries
+	// (0 for the server default); pass the previous response's
+	// next_page_token to get the page after it, with the same request
+	// otherwise.
+	PageSize   int32    `protobuf:"varint,4,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	PageToken  string   `protobuf:"bytes,5,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	Sort       ListSort `protobuf:"varint,6,opt,name=sort,proto3,enum=dfs.ListSort" json:"sort,omitempty"`
+	Descending bool     `protobuf:"varint,7,opt,name=descending,proto3" json:"descending,omitempty"`
+	// Filters, evaluated on the server; entries must match all that are set.
+	// A glob containing "/" matches paths, relative to the directory listed
+	// (or absolute, with a leading "/"), and "*" doesn't match "/":
+	// "logs/2026-*/*.gz". Without recursive, a directory listing only has
+	// its entries, and a glob reaching below them is rejected. Without "/",
+	// it matches entry names at any depth listed: "*.gz". regex (RE2
+	// syntax) must match somewhere in the path.
+	Glob           string `protobuf:"bytes,8,opt,name=glob,proto3" json:"glob,omitempty"`
+	Regex          string `protobuf:"bytes,9,opt,name=regex,proto3" json:"regex,omitempty"`
+	MinSize        int64  `protobuf:"varint,10,opt,name=min_size,json=minSize,proto3" json:"min_size,omitempty"`                      // Bytes, inclusive; 0 for no bound
+	MaxSize        int64  `protobuf:"varint,11,opt,name=max_size,json=maxSize,proto3" json:"max_size,omitempty"`                      // Bytes, inclusive; 0 for no bound
+	ModifiedAfter  int64  `protobuf:"varint,12,opt,name=modified_after,json=modifiedAfter,proto3" json:"modified_after,omitempty"`    // Unix timestamp, inclusive; 0 for no bound
+	ModifiedBefore int64  `protobuf:"varint,13,opt,name=modified_before,json=modifiedBefore,proto3" json:"modified_before,omitempty"` // Unix timestamp, inclusive; 0 for no bound
+	// User metadata filters: each attribute must have the value given, or
+	// with an empty value, just be set; each tag must be present.
+	Attributes    map[string]string `protobuf:"bytes,14,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Tags          []string          `protobuf:"bytes,15,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListRequest) Reset() {
+	*x = ListRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListRequest) ProtoMessage() {}
+
+func (x *ListRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListRequest.ProtoReflect.Descriptor instead.
+func (*ListRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *ListRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *ListRequest) GetDirectory() string {
+	if x != nil {
+		return x.Directory
+	}
+	return ""
+}
+
+func (x *ListRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+func (x *ListRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListRequest) GetSort() ListSort {
+	if x != nil {
+		return x.Sort
+	}
+	return ListSort_LIST_SORT_NAME
+}
+
+func (x *ListRequest) GetDescending() bool {
+	if x != nil {
+		return x.Descending
+	}
+	return false
+}
+
+func (x *ListRequest) GetGlob() string {
+	if x != nil {
+		return x.Glob
+	}
+	return ""
+}
+
+func (x *ListRequest) GetRegex() string {
+	if x != nil {
+		return x.Regex
+	}
+	return ""
+}
+
+func (x *ListRequest) GetMinSize() int64 {
+	if x != nil {
+		return x.MinSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetMaxSize() int64 {
+	if x != nil {
+		return x.MaxSize
+	}
+	return 0
+}
+
+func (x *ListRequest) GetModifiedAfter() int64 {
+	if x != nil {
+		return x.ModifiedAfter
+	}
+	return 0
+}
+
+func (x *ListRequest) GetModifiedBefore() int64 {
+	if x != nil {
+		return x.ModifiedBefore
+	}
+	return 0
+}
+
+func (x *ListRequest) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *ListRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
 type ListResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	Files         []*FileInfo            `protobuf:"bytes,1,rep,name=files,proto3" json:"files,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"` // Empty on the last page
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListResponse) Reset() {
+	*x = ListResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListResponse) ProtoMessage() {}
+
+func (x *ListResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
+func (*ListResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ListResponse) GetFiles() []*FileInfo {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *ListResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type FileInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	CreatedAt     int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`                                                            // Unix timestamp
+	ModifiedAt    int64                  `protobuf:"varint,4,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"`                                                         // Unix timestamp
+	Replication   int32                  `protobuf:"varint,5,opt,name=replication,proto3" json:"replication,omitempty"`                                                                         // Target number of replicas per chunk
+	Checksum      string                 `protobuf:"bytes,6,opt,name=checksum,proto3" json:"checksum,omitempty"`                                                                                // SHA-256 of the contents, hex
+	Generation    int64                  `protobuf:"varint,7,opt,name=generation,proto3" json:"generation,omitempty"`                                                                           // Bumped every time the file's contents are replaced
+	IsDir         bool                   `protobuf:"varint,8,opt,name=is_dir,json=isDir,proto3" json:"is_dir,omitempty"`                                                                        // A directory rather than a file
+	SupersededAt  int64                  `protobuf:"varint,9,opt,name=superseded_at,json=supersededAt,proto3" json:"superseded_at,omitempty"`                                                   // For an old version: when a newer one replaced it (Unix timestamp)
+	Attributes    map[string]string      `protobuf:"bytes,10,rep,name=attributes,proto3" json:"attributes,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // User metadata
+	Tags          []string               `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`                                                                                       // Sorted
+	StoredSize    int64                  `protobuf:"varint,12,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"`                                                        // Bytes stored per replica, after compression; size is the logical size
+	KeyId         string                 `protobuf:"bytes,13,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`                                                                        // Master key wrapping the file's data key; empty if not encrypted
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FileInfo) Reset() {
+	*x = FileInfo{}
+	mi := &file_proto_dfs_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FileInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FileInfo) ProtoMessage() {}
+
+func (x *FileInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
+func (*FileInfo) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *FileInfo) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *FileInfo) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FileInfo) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *FileInfo) GetModifiedAt() int64 {
+	if x != nil {
+		return x.ModifiedAt
+	}
+	return 0
+}
+
+func (x *FileInfo) GetReplication() int32 {
+	if x != nil {
+		return x.Replication
+	}
+	return 0
+}
+
+func (x *FileInfo) GetChecksum() string {
+	if x != nil {
+		return x.Checksum
+	}
+	return ""
+}
+
+func (x *FileInfo) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+func (x *FileInfo) GetIsDir() bool {
+	if x != nil {
+		return x.IsDir
+	}
+	return false
+}
+
+func (x *FileInfo) GetSupersededAt() int64 {
+	if x != nil {
+		return x.SupersededAt
+	}
+	return 0
+}
+
+func (x *FileInfo) GetAttributes() map[string]string {
+	if x != nil {
+		return x.Attributes
+	}
+	return nil
+}
+
+func (x *FileInfo) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *FileInfo) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
+func (x *FileInfo) GetKeyId() string {
+	if x != nil {
+		return x.KeyId
+	}
+	return ""
+}
+
+// Delete messages
+type DeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Permanent     bool                   `protobuf:"varint,2,opt,name=permanent,proto3" json:"permanent,omitempty"` // Delete the file outright instead of moving it to the trash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteRequest) Reset() {
+	*x = DeleteRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteRequest) ProtoMessage() {}
+
+func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
+func (*DeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *DeleteRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *DeleteRequest) GetPermanent() bool {
+	if x != nil {
+		return x.Permanent
+	}
+	return false
+}
+
+type DeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	TrashId       string                 `protobuf:"bytes,3,opt,name=trash_id,json=trashId,proto3" json:"trash_id,omitempty"` // Where the file went in the trash; empty if deleted permanently
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteResponse) Reset() {
+	*x = DeleteResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteResponse) ProtoMessage() {}
+
+func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
+func (*DeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *DeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *DeleteResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DeleteResponse) GetTrashId() string {
+	if x != nil {
+		return x.TrashId
+	}
+	return ""
+}
+
+// Directory messages
+type MkdirRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Parents       bool                   `protobuf:"varint,2,opt,name=parents,proto3" json:"parents,omitempty"` // Create missing parents too, and don't fail if it exists (mkdir -p)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MkdirRequest) Reset() {
+	*x = MkdirRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MkdirRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MkdirRequest) ProtoMessage() {}
+
+func (x *MkdirRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MkdirRequest.ProtoReflect.Descriptor instead.
+func (*MkdirRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *MkdirRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *MkdirRequest) GetParents() bool {
+	if x != nil {
+		return x.Parents
+	}
+	return false
+}
+
+type MkdirResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MkdirResponse) Reset() {
+	*x = MkdirResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MkdirResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MkdirResponse) ProtoMessage() {}
+
+func (x *MkdirResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MkdirResponse.ProtoReflect.Descriptor instead.
+func (*MkdirResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *MkdirResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *MkdirResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type RmdirRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Path          string                 `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	Recursive     bool                   `protobuf:"varint,2,opt,name=recursive,proto3" json:"recursive,omitempty"` // Remove everything below it too; otherwise it must be empty
+	Permanent     bool                   `protobuf:"varint,3,opt,name=permanent,proto3" json:"permanent,omitempty"` // Delete the files below outright instead of moving them to the trash
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RmdirRequest) Reset() {
+	*x = RmdirRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RmdirRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RmdirRequest) ProtoMessage() {}
+
+func (x *RmdirRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RmdirRequest.ProtoReflect.Descriptor instead.
+func (*RmdirRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *RmdirRequest) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *RmdirRequest) GetRecursive() bool {
+	if x != nil {
+		return x.Recursive
+	}
+	return false
+}
+
+func (x *RmdirRequest) GetPermanent() bool {
+	if x != nil {
+		return x.Permanent
+	}
+	return false
+}
+
+type RmdirResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	FilesDeleted  int32                  `protobuf:"varint,3,opt,name=files_deleted,json=filesDeleted,proto3" json:"files_deleted,omitempty"` // Files removed along with the directory (trashed unless permanent)
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RmdirResponse) Reset() {
+	*x = RmdirResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RmdirResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RmdirResponse) ProtoMessage() {}
+
+func (x *RmdirResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RmdirResponse.ProtoReflect.Descriptor instead.
+func (*RmdirResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *RmdirResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RmdirResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RmdirResponse) GetFilesDeleted() int32 {
+	if x != nil {
+		return x.FilesDeleted
+	}
+	return 0
+}
+
+// Rename messages
+type RenameRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Destination   string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	Overwrite     bool                   `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"` // Replace the destination if it is an existing file; otherwise fail
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameRequest) Reset() {
+	*x = RenameRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameRequest) ProtoMessage() {}
+
+func (x *RenameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameRequest.ProtoReflect.Descriptor instead.
+func (*RenameRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *RenameRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *RenameRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *RenameRequest) GetOverwrite() bool {
+	if x != nil {
+		return x.Overwrite
+	}
+	return false
+}
+
+type RenameResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Generation    int64                  `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"` // Generation of the renamed file at its new path
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RenameResponse) Reset() {
+	*x = RenameResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RenameResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenameResponse) ProtoMessage() {}
+
+func (x *RenameResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenameResponse.ProtoReflect.Descriptor instead.
+func (*RenameResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *RenameResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RenameResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RenameResponse) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+// Copy messages
+type CopyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Source        string                 `protobuf:"bytes,1,opt,name=source,proto3" json:"source,omitempty"`
+	Destination   string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"`
+	Overwrite     bool                   `protobuf:"varint,3,opt,name=overwrite,proto3" json:"overwrite,omitempty"` // Replace the destination if it is an existing file; otherwise fail
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyRequest) Reset() {
+	*x = CopyRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyRequest) ProtoMessage() {}
+
+func (x *CopyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyRequest.ProtoReflect.Descriptor instead.
+func (*CopyRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CopyRequest) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *CopyRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *CopyRequest) GetOverwrite() bool {
+	if x != nil {
+		return x.Overwrite
+	}
+	return false
+}
+
+type CopyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Generation    int64                  `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"` // Generation of the new file
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CopyResponse) Reset() {
+	*x = CopyResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CopyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CopyResponse) ProtoMessage() {}
+
+func (x *CopyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CopyResponse.ProtoReflect.Descriptor instead.
+func (*CopyResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CopyResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *CopyResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *CopyResponse) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+// Version messages
+type ListVersionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVersionsRequest) Reset() {
+	*x = ListVersionsRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsRequest) ProtoMessage() {}
+
+func (x *ListVersionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsRequest.ProtoReflect.Descriptor instead.
+func (*ListVersionsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ListVersionsRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+type ListVersionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Versions      []*FileInfo            `protobuf:"bytes,1,rep,name=versions,proto3" json:"versions,omitempty"` // Newest first; the first is the current version
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListVersionsResponse) Reset() {
+	*x = ListVersionsResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListVersionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListVersionsResponse) ProtoMessage() {}
+
+func (x *ListVersionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListVersionsResponse.ProtoReflect.Descriptor instead.
+func (*ListVersionsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *ListVersionsResponse) GetVersions() []*FileInfo {
+	if x != nil {
+		return x.Versions
+	}
+	return nil
+}
+
+type RestoreRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"` // Generation of the old version to restore
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreRequest) Reset() {
+	*x = RestoreRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreRequest) ProtoMessage() {}
+
+func (x *RestoreRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreRequest.ProtoReflect.Descriptor instead.
+func (*RestoreRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *RestoreRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *RestoreRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type RestoreResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Generation    int64                  `protobuf:"varint,3,opt,name=generation,proto3" json:"generation,omitempty"` // Generation the restored contents were written as
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreResponse) Reset() {
+	*x = RestoreResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreResponse) ProtoMessage() {}
+
+func (x *RestoreResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreResponse.ProtoReflect.Descriptor instead.
+func (*RestoreResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *RestoreResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *RestoreResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *RestoreResponse) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+// SetAttributes messages
+type SetAttributesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Set           map[string]string      `protobuf:"bytes,2,rep,name=set,proto3" json:"set,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"` // Attributes to add or change
+	Remove        []string               `protobuf:"bytes,3,rep,name=remove,proto3" json:"remove,omitempty"`                                                                     // Attribute keys to remove
+	AddTags       []string               `protobuf:"bytes,4,rep,name=add_tags,json=addTags,proto3" json:"add_tags,omitempty"`
+	RemoveTags    []string               `protobuf:"bytes,5,rep,name=remove_tags,json=removeTags,proto3" json:"remove_tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAttributesRequest) Reset() {
+	*x = SetAttributesRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAttributesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAttributesRequest) ProtoMessage() {}
+
+func (x *SetAttributesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAttributesRequest.ProtoReflect.Descriptor instead.
+func (*SetAttributesRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *SetAttributesRequest) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *SetAttributesRequest) GetSet() map[string]string {
+	if x != nil {
+		return x.Set
+	}
+	return nil
+}
+
+func (x *SetAttributesRequest) GetRemove() []string {
+	if x != nil {
+		return x.Remove
+	}
+	return nil
+}
+
+func (x *SetAttributesRequest) GetAddTags() []string {
+	if x != nil {
+		return x.AddTags
+	}
+	return nil
+}
+
+func (x *SetAttributesRequest) GetRemoveTags() []string {
+	if x != nil {
+		return x.RemoveTags
+	}
+	return nil
+}
+
+type SetAttributesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	File          *FileInfo              `protobuf:"bytes,3,opt,name=file,proto3" json:"file,omitempty"` // The file with its new attributes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetAttributesResponse) Reset() {
+	*x = SetAttributesResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetAttributesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetAttributesResponse) ProtoMessage() {}
+
+func (x *SetAttributesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetAttributesResponse.ProtoReflect.Descriptor instead.
+func (*SetAttributesResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *SetAttributesResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *SetAttributesResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *SetAttributesResponse) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+// Trash messages
+type ListTrashRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Prefix        string                 `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"` // Only entries whose original path starts with this
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashRequest) Reset() {
+	*x = ListTrashRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashRequest) ProtoMessage() {}
+
+func (x *ListTrashRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashRequest.ProtoReflect.Descriptor instead.
+func (*ListTrashRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ListTrashRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+type ListTrashResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Entries       []*TrashEntry          `protobuf:"bytes,1,rep,name=entries,proto3" json:"entries,omitempty"` // Most recently deleted first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListTrashResponse) Reset() {
+	*x = ListTrashResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListTrashResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListTrashResponse) ProtoMessage() {}
+
+func (x *ListTrashResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListTrashResponse.ProtoReflect.Descriptor instead.
+func (*ListTrashResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *ListTrashResponse) GetEntries() []*TrashEntry {
+	if x != nil {
+		return x.Entries
+	}
+	return nil
+}
+
+type TrashEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`                                 // Identifies the entry for Undelete
+	File          *FileInfo              `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`                             // The file as it was when deleted, at its original path
+	DeletedAt     int64                  `protobuf:"varint,3,opt,name=deleted_at,json=deletedAt,proto3" json:"deleted_at,omitempty"` // Unix timestamp
+	PurgeAt       int64                  `protobuf:"varint,4,opt,name=purge_at,json=purgeAt,proto3" json:"purge_at,omitempty"`       // Unix timestamp after which it is deleted for good
+	Versions      int32                  `protobuf:"varint,5,opt,name=versions,proto3" json:"versions,omitempty"`                    // Old versions kept with it
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *ListResponse) Reset() {
-	*x = ListResponse{}
-	mi := &file_proto_dfs_proto_msgTypes[6]
+func (x *TrashEntry) Reset() {
+	*x = TrashEntry{}
+	mi := &file_proto_dfs_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TrashEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TrashEntry) ProtoMessage() {}
+
+func (x *TrashEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TrashEntry.ProtoReflect.Descriptor instead.
+func (*TrashEntry) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *TrashEntry) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *TrashEntry) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *TrashEntry) GetDeletedAt() int64 {
+	if x != nil {
+		return x.DeletedAt
+	}
+	return 0
+}
+
+func (x *TrashEntry) GetPurgeAt() int64 {
+	if x != nil {
+		return x.PurgeAt
+	}
+	return 0
+}
+
+func (x *TrashEntry) GetVersions() int32 {
+	if x != nil {
+		return x.Versions
+	}
+	return 0
+}
+
+type UndeleteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Destination   string                 `protobuf:"bytes,2,opt,name=destination,proto3" json:"destination,omitempty"` // Where to put the file; empty for its original path
+	Parents       bool                   `protobuf:"varint,3,opt,name=parents,proto3" json:"parents,omitempty"`        // Create missing parent directories
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteRequest) Reset() {
+	*x = UndeleteRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteRequest) ProtoMessage() {}
+
+func (x *UndeleteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteRequest.ProtoReflect.Descriptor instead.
+func (*UndeleteRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *UndeleteRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UndeleteRequest) GetDestination() string {
+	if x != nil {
+		return x.Destination
+	}
+	return ""
+}
+
+func (x *UndeleteRequest) GetParents() bool {
+	if x != nil {
+		return x.Parents
+	}
+	return false
+}
+
+type UndeleteResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Filename      string                 `protobuf:"bytes,3,opt,name=filename,proto3" json:"filename,omitempty"` // Where the file is now
+	Generation    int64                  `protobuf:"varint,4,opt,name=generation,proto3" json:"generation,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UndeleteResponse) Reset() {
+	*x = UndeleteResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[37]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UndeleteResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UndeleteResponse) ProtoMessage() {}
+
+func (x *UndeleteResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[37]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UndeleteResponse.ProtoReflect.Descriptor instead.
+func (*UndeleteResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *UndeleteResponse) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *UndeleteResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *UndeleteResponse) GetFilename() string {
+	if x != nil {
+		return x.Filename
+	}
+	return ""
+}
+
+func (x *UndeleteResponse) GetGeneration() int64 {
+	if x != nil {
+		return x.Generation
+	}
+	return 0
+}
+
+// Watch messages
+type WatchRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Only events for paths starting with this, and the removal or rename
+	// of a directory above it, which takes everything under it along
+	Prefix        string `protobuf:"bytes,1,opt,name=prefix,proto3" json:"prefix,omitempty"`
+	AfterSequence uint64 `protobuf:"varint,2,opt,name=after_sequence,json=afterSequence,proto3" json:"after_sequence,omitempty"` // Resume after this event; 0 to start with the next change
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[38]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[38]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *WatchRequest) GetPrefix() string {
+	if x != nil {
+		return x.Prefix
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetAfterSequence() uint64 {
+	if x != nil {
+		return x.AfterSequence
+	}
+	return 0
+}
+
+type WatchEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Sequence      uint64                 `protobuf:"varint,1,opt,name=sequence,proto3" json:"sequence,omitempty"` // Increases with every event
+	Type          WatchEventType         `protobuf:"varint,2,opt,name=type,proto3,enum=dfs.WatchEventType" json:"type,omitempty"`
+	Path          string                 `protobuf:"bytes,3,opt,name=path,proto3" json:"path,omitempty"`
+	OldPath       string                 `protobuf:"bytes,4,opt,name=old_path,json=oldPath,proto3" json:"old_path,omitempty"` // For renames, where it was before
+	File          *FileInfo              `protobuf:"bytes,5,opt,name=file,proto3" json:"file,omitempty"`                      // The file as of the event; for deletes, as it was
+	Time          int64                  `protobuf:"varint,6,opt,name=time,proto3" json:"time,omitempty"`                     // Unix timestamp
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEvent) Reset() {
+	*x = WatchEvent{}
+	mi := &file_proto_dfs_proto_msgTypes[39]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEvent) ProtoMessage() {}
+
+func (x *WatchEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[39]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEvent.ProtoReflect.Descriptor instead.
+func (*WatchEvent) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *WatchEvent) GetSequence() uint64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *WatchEvent) GetType() WatchEventType {
+	if x != nil {
+		return x.Type
+	}
+	return WatchEventType_WATCH_EVENT_CREATE
+}
+
+func (x *WatchEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetOldPath() string {
+	if x != nil {
+		return x.OldPath
+	}
+	return ""
+}
+
+func (x *WatchEvent) GetFile() *FileInfo {
+	if x != nil {
+		return x.File
+	}
+	return nil
+}
+
+func (x *WatchEvent) GetTime() int64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+// RotateKeys messages
+type RotateKeysRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RotateKeysRequest) Reset() {
+	*x = RotateKeysRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[40]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *ListResponse) String() string {
+func (x *RotateKeysRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*ListResponse) ProtoMessage() {}
+func (*RotateKeysRequest) ProtoMessage() {}
 
-func (x *ListResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[6]
+func (x *RotateKeysRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[40]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -420,43 +2913,37 @@ func (x *ListResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use ListResponse.ProtoReflect.Descriptor instead.
-func (*ListResponse) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{6}
-}
-
-func (x *ListResponse) GetFiles() []*FileInfo {
-	if x != nil {
-		return x.Files
-	}
-	return nil
+// Deprecated: Use RotateKeysRequest.ProtoReflect.Descriptor instead.
+func (*RotateKeysRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{40}
 }
 
-type FileInfo struct {
+type RotateKeysResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
-	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
-	CreatedAt     int64                  `protobuf:"varint,3,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`    // Unix timestamp
-	ModifiedAt    int64                  `protobuf:"varint,4,opt,name=modified_at,json=modifiedAt,proto3" json:"modified_at,omitempty"` // Unix timestamp
+	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	ActiveKey     string                 `protobuf:"bytes,3,opt,name=active_key,json=activeKey,proto3" json:"active_key,omitempty"` // The master key everything is wrapped with now
+	Rewrapped     int64                  `protobuf:"varint,4,opt,name=rewrapped,proto3" json:"rewrapped,omitempty"`                 // Data keys rewrapped
+	Total         int64                  `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"`                         // Data keys in use
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *FileInfo) Reset() {
-	*x = FileInfo{}
-	mi := &file_proto_dfs_proto_msgTypes[7]
+func (x *RotateKeysResponse) Reset() {
+	*x = RotateKeysResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[41]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *FileInfo) String() string {
+func (x *RotateKeysResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*FileInfo) ProtoMessage() {}
+func (*RotateKeysResponse) ProtoMessage() {}
 
-func (x *FileInfo) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[7]
+func (x *RotateKeysResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[41]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -467,62 +2954,70 @@ func (x *FileInfo) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use FileInfo.ProtoReflect.Descriptor instead.
-func (*FileInfo) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{7}
+// Deprecated: Use RotateKeysResponse.ProtoReflect.Descriptor instead.
+func (*RotateKeysResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{41}
 }
 
-func (x *FileInfo) GetFilename() string {
+func (x *RotateKeysResponse) GetSuccess() bool {
 	if x != nil {
-		return x.Filename
+		return x.Success
+	}
+	return false
+}
+
+func (x *RotateKeysResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
 	}
 	return ""
 }
 
-func (x *FileInfo) GetSize() int64 {
+func (x *RotateKeysResponse) GetActiveKey() string {
 	if x != nil {
-		return x.Size
+		return x.ActiveKey
 	}
-	return 0
+	return ""
 }
 
-func (x *FileInfo) GetCreatedAt() int64 {
+func (x *RotateKeysResponse) GetRewrapped() int64 {
 	if x != nil {
-		return x.CreatedAt
+		return x.Rewrapped
 	}
 	return 0
 }
 
-func (x *FileInfo) GetModifiedAt() int64 {
+func (x *RotateKeysResponse) GetTotal() int64 {
 	if x != nil {
-		return x.ModifiedAt
+		return x.Total
 	}
 	return 0
 }
 
-// Delete messages
-type DeleteRequest struct {
+// Stat messages
+type StatRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"` // Generation of an old version to describe; 0 for the current one
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteRequest) Reset() {
-	*x = DeleteRequest{}
-	mi := &file_proto_dfs_proto_msgTypes[8]
+func (x *StatRequest) Reset() {
+	*x = StatRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[42]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteRequest) String() string {
+func (x *StatRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteRequest) ProtoMessage() {}
+func (*StatRequest) ProtoMessage() {}
 
-func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[8]
+func (x *StatRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[42]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -533,41 +3028,49 @@ func (x *DeleteRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteRequest.ProtoReflect.Descriptor instead.
-func (*DeleteRequest) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{8}
+// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
+func (*StatRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{42}
 }
 
-func (x *DeleteRequest) GetFilename() string {
+func (x *StatRequest) GetFilename() string {
 	if x != nil {
 		return x.Filename
 	}
 	return ""
 }
 
-type DeleteResponse struct {
+func (x *StatRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type StatResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
+	File          *FileInfo              `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	Chunks        []*ChunkLocation       `protobuf:"bytes,3,rep,name=chunks,proto3" json:"chunks,omitempty"` // In file order, with replica health
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *DeleteResponse) Reset() {
-	*x = DeleteResponse{}
-	mi := &file_proto_dfs_proto_msgTypes[9]
+func (x *StatResponse) Reset() {
+	*x = StatResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[43]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *DeleteResponse) String() string {
+func (x *StatResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*DeleteResponse) ProtoMessage() {}
+func (*StatResponse) ProtoMessage() {}
 
-func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[9]
+func (x *StatResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[43]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -578,48 +3081,56 @@ func (x *DeleteResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use DeleteResponse.ProtoReflect.Descriptor instead.
-func (*DeleteResponse) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{9}
+// Deprecated: Use StatResponse.ProtoReflect.Descriptor instead.
+func (*StatResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{43}
 }
 
-func (x *DeleteResponse) GetSuccess() bool {
+func (x *StatResponse) GetExists() bool {
 	if x != nil {
-		return x.Success
+		return x.Exists
 	}
 	return false
 }
 
-func (x *DeleteResponse) GetMessage() string {
+func (x *StatResponse) GetFile() *FileInfo {
 	if x != nil {
-		return x.Message
+		return x.File
 	}
-	return ""
+	return nil
 }
 
-// Stat messages
-type StatRequest struct {
+func (x *StatResponse) GetChunks() []*ChunkLocation {
+	if x != nil {
+		return x.Chunks
+	}
+	return nil
+}
+
+// GetChunkLocations messages
+type GetChunkLocationsRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Filename      string                 `protobuf:"bytes,1,opt,name=filename,proto3" json:"filename,omitempty"`
+	Version       int64                  `protobuf:"varint,2,opt,name=version,proto3" json:"version,omitempty"` // Generation of an old version to read; 0 for the current one
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatRequest) Reset() {
-	*x = StatRequest{}
-	mi := &file_proto_dfs_proto_msgTypes[10]
+func (x *GetChunkLocationsRequest) Reset() {
+	*x = GetChunkLocationsRequest{}
+	mi := &file_proto_dfs_proto_msgTypes[44]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatRequest) String() string {
+func (x *GetChunkLocationsRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatRequest) ProtoMessage() {}
+func (*GetChunkLocationsRequest) ProtoMessage() {}
 
-func (x *StatRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[10]
+func (x *GetChunkLocationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[44]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -630,41 +3141,49 @@ func (x *StatRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatRequest.ProtoReflect.Descriptor instead.
-func (*StatRequest) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{10}
+// Deprecated: Use GetChunkLocationsRequest.ProtoReflect.Descriptor instead.
+func (*GetChunkLocationsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{44}
 }
 
-func (x *StatRequest) GetFilename() string {
+func (x *GetChunkLocationsRequest) GetFilename() string {
 	if x != nil {
 		return x.Filename
 	}
 	return ""
 }
 
-type StatResponse struct {
+func (x *GetChunkLocationsRequest) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+type GetChunkLocationsResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Exists        bool                   `protobuf:"varint,1,opt,name=exists,proto3" json:"exists,omitempty"`
-	File          *FileInfo              `protobuf:"bytes,2,opt,name=file,proto3" json:"file,omitempty"`
+	File          *FileInfo              `protobuf:"bytes,1,opt,name=file,proto3" json:"file,omitempty"`
+	Chunks        []*ChunkLocation       `protobuf:"bytes,2,rep,name=chunks,proto3" json:"chunks,omitempty"`                  // In file order
+	DataKey       []byte                 `protobuf:"bytes,3,opt,name=data_key,json=dataKey,proto3" json:"data_key,omitempty"` // Key to decrypt the chunks with, if the file is encrypted
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *StatResponse) Reset() {
-	*x = StatResponse{}
-	mi := &file_proto_dfs_proto_msgTypes[11]
+func (x *GetChunkLocationsResponse) Reset() {
+	*x = GetChunkLocationsResponse{}
+	mi := &file_proto_dfs_proto_msgTypes[45]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *StatResponse) String() string {
+func (x *GetChunkLocationsResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*StatResponse) ProtoMessage() {}
+func (*GetChunkLocationsResponse) ProtoMessage() {}
 
-func (x *StatResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_proto_dfs_proto_msgTypes[11]
+func (x *GetChunkLocationsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[45]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -675,25 +3194,148 @@ func (x *StatResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use StatResponse.ProtoReflect.Descriptor instead.
-func (*StatResponse) Descriptor() ([]byte, []int) {
-	return file_proto_dfs_proto_rawDescGZIP(), []int{11}
+// Deprecated: Use GetChunkLocationsResponse.ProtoReflect.Descriptor instead.
+func (*GetChunkLocationsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{45}
 }
 
-func (x *StatResponse) GetExists() bool {
+func (x *GetChunkLocationsResponse) GetFile() *FileInfo {
 	if x != nil {
-		return x.Exists
+		return x.File
 	}
-	return false
+	return nil
 }
 
-func (x *StatResponse) GetFile() *FileInfo {
+func (x *GetChunkLocationsResponse) GetChunks() []*ChunkLocation {
 	if x != nil {
-		return x.File
+		return x.Chunks
+	}
+	return nil
+}
+
+func (x *GetChunkLocationsResponse) GetDataKey() []byte {
+	if x != nil {
+		return x.DataKey
+	}
+	return nil
+}
+
+type ChunkLocation struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	ChunkId         string                 `protobuf:"bytes,1,opt,name=chunk_id,json=chunkId,proto3" json:"chunk_id,omitempty"`
+	Offset          int64                  `protobuf:"varint,2,opt,name=offset,proto3" json:"offset,omitempty"` // Offset of this chunk within the file
+	Size            int64                  `protobuf:"varint,3,opt,name=size,proto3" json:"size,omitempty"`
+	Addresses       []string               `protobuf:"bytes,4,rep,name=addresses,proto3" json:"addresses,omitempty"`                                     // Chunkservers holding this chunk
+	HealthyReplicas int32                  `protobuf:"varint,5,opt,name=healthy_replicas,json=healthyReplicas,proto3" json:"healthy_replicas,omitempty"` // How many of addresses are currently healthy
+	Crc32C          uint32                 `protobuf:"varint,6,opt,name=crc32c,proto3" json:"crc32c,omitempty"`                                          // CRC-32C (Castagnoli) of the chunk data as stored
+	Hash            string                 `protobuf:"bytes,7,opt,name=hash,proto3" json:"hash,omitempty"`                                               // SHA-256 of the chunk data (HMAC with the data key if encrypted), if recorded
+	Refs            int32                  `protobuf:"varint,8,opt,name=refs,proto3" json:"refs,omitempty"`                                              // Files and versions sharing this chunk
+	Compression     Compression            `protobuf:"varint,9,opt,name=compression,proto3,enum=dfs.Compression" json:"compression,omitempty"`           // How the chunk is stored; readers decompress it
+	StoredSize      int64                  `protobuf:"varint,10,opt,name=stored_size,json=storedSize,proto3" json:"stored_size,omitempty"`               // Bytes stored, after compression; size is before
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ChunkLocation) Reset() {
+	*x = ChunkLocation{}
+	mi := &file_proto_dfs_proto_msgTypes[46]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChunkLocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChunkLocation) ProtoMessage() {}
+
+func (x *ChunkLocation) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_dfs_proto_msgTypes[46]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChunkLocation.ProtoReflect.Descriptor instead.
+func (*ChunkLocation) Descriptor() ([]byte, []int) {
+	return file_proto_dfs_proto_rawDescGZIP(), []int{46}
+}
+
+func (x *ChunkLocation) GetChunkId() string {
+	if x != nil {
+		return x.ChunkId
+	}
+	return ""
+}
+
+func (x *ChunkLocation) GetOffset() int64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *ChunkLocation) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *ChunkLocation) GetAddresses() []string {
+	if x != nil {
+		return x.Addresses
 	}
 	return nil
 }
 
+func (x *ChunkLocation) GetHealthyReplicas() int32 {
+	if x != nil {
+		return x.HealthyReplicas
+	}
+	return 0
+}
+
+func (x *ChunkLocation) GetCrc32C() uint32 {
+	if x != nil {
+		return x.Crc32C
+	}
+	return 0
+}
+
+func (x *ChunkLocation) GetHash() string {
+	if x != nil {
+		return x.Hash
+	}
+	return ""
+}
+
+func (x *ChunkLocation) GetRefs() int32 {
+	if x != nil {
+		return x.Refs
+	}
+	return 0
+}
+
+func (x *ChunkLocation) GetCompression() Compression {
+	if x != nil {
+		return x.Compression
+	}
+	return Compression_COMPRESSION_DEFAULT
+}
+
+func (x *ChunkLocation) GetStoredSize() int64 {
+	if x != nil {
+		return x.StoredSize
+	}
+	return 0
+}
+
 var File_proto_dfs_proto protoreflect.FileDescriptor
 
 const file_proto_dfs_proto_rawDesc = "" +
@@ -702,47 +3344,310 @@ const file_proto_dfs_proto_rawDesc = "" +
 	"\rUploadRequest\x12/\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x11.dfs.FileMetadataH\x00R\bmetadata\x12\x16\n" +
 	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
-	"\x04data\">\n" +
+	"\x04data\"\xd8\x03\n" +
 	"\fFileMetadata\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
-	"\x04size\x18\x02 \x01(\x03R\x04size\"]\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12 \n" +
+	"\vreplication\x18\x03 \x01(\x05R\vreplication\x12\x1a\n" +
+	"\bchecksum\x18\x04 \x01(\tR\bchecksum\x12\"\n" +
+	"\x04mode\x18\x05 \x01(\x0e2\x0e.dfs.WriteModeR\x04mode\x12#\n" +
+	"\rif_generation\x18\x06 \x01(\x03R\fifGeneration\x12\x18\n" +
+	"\aparents\x18\a \x01(\bR\aparents\x12A\n" +
+	"\n" +
+	"attributes\x18\b \x03(\v2!.dfs.FileMetadata.AttributesEntryR\n" +
+	"attributes\x12\x12\n" +
+	"\x04tags\x18\t \x03(\tR\x04tags\x12-\n" +
+	"\bchunking\x18\n" +
+	" \x01(\x0e2\x11.dfs.ChunkingModeR\bchunking\x122\n" +
+	"\vcompression\x18\v \x01(\x0e2\x10.dfs.CompressionR\vcompression\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xd9\x01\n" +
 	"\x0eUploadResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x17\n" +
-	"\afile_id\x18\x03 \x01(\tR\x06fileId\"-\n" +
+	"\afile_id\x18\x03 \x01(\tR\x06fileId\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\x03R\n" +
+	"generation\x12\x16\n" +
+	"\x06chunks\x18\x05 \x01(\x05R\x06chunks\x12!\n" +
+	"\fdedup_chunks\x18\x06 \x01(\x05R\vdedupChunks\x12\x1f\n" +
+	"\vdedup_bytes\x18\a \x01(\x03R\n" +
+	"dedupBytes\"C\n" +
+	"\x12StartUploadRequest\x12-\n" +
+	"\bmetadata\x18\x01 \x01(\v2\x11.dfs.FileMetadataR\bmetadata\"4\n" +
+	"\x13StartUploadResponse\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"_\n" +
+	"\x12WriteUploadRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data\"@\n" +
+	"\x13WriteUploadResponse\x12)\n" +
+	"\x10committed_offset\x18\x01 \x01(\x03R\x0fcommittedOffset\"3\n" +
+	"\x12QueryUploadRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"T\n" +
+	"\x13QueryUploadResponse\x12)\n" +
+	"\x10committed_offset\x18\x01 \x01(\x03R\x0fcommittedOffset\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\"4\n" +
+	"\x13FinishUploadRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"3\n" +
+	"\x12AbortUploadRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\"I\n" +
+	"\x13AbortUploadResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"w\n" +
 	"\x0fDownloadRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\"c\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x16\n" +
+	"\x06length\x18\x03 \x01(\x03R\x06length\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\x03R\aversion\"c\n" +
 	"\x10DownloadResponse\x12/\n" +
 	"\bmetadata\x18\x01 \x01(\v2\x11.dfs.FileMetadataH\x00R\bmetadata\x12\x16\n" +
 	"\x05chunk\x18\x02 \x01(\fH\x00R\x05chunkB\x06\n" +
-	"\x04data\"%\n" +
+	"\x04data\"\xa5\x04\n" +
 	"\vListRequest\x12\x16\n" +
-	"\x06prefix\x18\x01 \x01(\tR\x06prefix\"3\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12\x1c\n" +
+	"\tdirectory\x18\x02 \x01(\tR\tdirectory\x12\x1c\n" +
+	"\trecursive\x18\x03 \x01(\bR\trecursive\x12\x1b\n" +
+	"\tpage_size\x18\x04 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x05 \x01(\tR\tpageToken\x12!\n" +
+	"\x04sort\x18\x06 \x01(\x0e2\r.dfs.ListSortR\x04sort\x12\x1e\n" +
+	"\n" +
+	"descending\x18\a \x01(\bR\n" +
+	"descending\x12\x12\n" +
+	"\x04glob\x18\b \x01(\tR\x04glob\x12\x14\n" +
+	"\x05regex\x18\t \x01(\tR\x05regex\x12\x19\n" +
+	"\bmin_size\x18\n" +
+	" \x01(\x03R\aminSize\x12\x19\n" +
+	"\bmax_size\x18\v \x01(\x03R\amaxSize\x12%\n" +
+	"\x0emodified_after\x18\f \x01(\x03R\rmodifiedAfter\x12'\n" +
+	"\x0fmodified_before\x18\r \x01(\x03R\x0emodifiedBefore\x12@\n" +
+	"\n" +
+	"attributes\x18\x0e \x03(\v2 .dfs.ListRequest.AttributesEntryR\n" +
+	"attributes\x12\x12\n" +
+	"\x04tags\x18\x0f \x03(\tR\x04tags\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"[\n" +
 	"\fListResponse\x12#\n" +
-	"\x05files\x18\x01 \x03(\v2\r.dfs.FileInfoR\x05files\"z\n" +
+	"\x05files\x18\x01 \x03(\v2\r.dfs.FileInfoR\x05files\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"\xde\x03\n" +
 	"\bFileInfo\x12\x1a\n" +
 	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x12\n" +
 	"\x04size\x18\x02 \x01(\x03R\x04size\x12\x1d\n" +
 	"\n" +
 	"created_at\x18\x03 \x01(\x03R\tcreatedAt\x12\x1f\n" +
 	"\vmodified_at\x18\x04 \x01(\x03R\n" +
-	"modifiedAt\"+\n" +
+	"modifiedAt\x12 \n" +
+	"\vreplication\x18\x05 \x01(\x05R\vreplication\x12\x1a\n" +
+	"\bchecksum\x18\x06 \x01(\tR\bchecksum\x12\x1e\n" +
+	"\n" +
+	"generation\x18\a \x01(\x03R\n" +
+	"generation\x12\x15\n" +
+	"\x06is_dir\x18\b \x01(\bR\x05isDir\x12#\n" +
+	"\rsuperseded_at\x18\t \x01(\x03R\fsupersededAt\x12=\n" +
+	"\n" +
+	"attributes\x18\n" +
+	" \x03(\v2\x1d.dfs.FileInfo.AttributesEntryR\n" +
+	"attributes\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tags\x12\x1f\n" +
+	"\vstored_size\x18\f \x01(\x03R\n" +
+	"storedSize\x12\x15\n" +
+	"\x06key_id\x18\r \x01(\tR\x05keyId\x1a=\n" +
+	"\x0fAttributesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"I\n" +
 	"\rDeleteRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\"D\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x1c\n" +
+	"\tpermanent\x18\x02 \x01(\bR\tpermanent\"_\n" +
 	"\x0eDeleteResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\")\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x19\n" +
+	"\btrash_id\x18\x03 \x01(\tR\atrashId\"<\n" +
+	"\fMkdirRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x18\n" +
+	"\aparents\x18\x02 \x01(\bR\aparents\"C\n" +
+	"\rMkdirResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"^\n" +
+	"\fRmdirRequest\x12\x12\n" +
+	"\x04path\x18\x01 \x01(\tR\x04path\x12\x1c\n" +
+	"\trecursive\x18\x02 \x01(\bR\trecursive\x12\x1c\n" +
+	"\tpermanent\x18\x03 \x01(\bR\tpermanent\"h\n" +
+	"\rRmdirResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12#\n" +
+	"\rfiles_deleted\x18\x03 \x01(\x05R\ffilesDeleted\"g\n" +
+	"\rRenameRequest\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x1c\n" +
+	"\toverwrite\x18\x03 \x01(\bR\toverwrite\"d\n" +
+	"\x0eRenameResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x03R\n" +
+	"generation\"e\n" +
+	"\vCopyRequest\x12\x16\n" +
+	"\x06source\x18\x01 \x01(\tR\x06source\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x1c\n" +
+	"\toverwrite\x18\x03 \x01(\bR\toverwrite\"b\n" +
+	"\fCopyResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x03R\n" +
+	"generation\"1\n" +
+	"\x13ListVersionsRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\"A\n" +
+	"\x14ListVersionsResponse\x12)\n" +
+	"\bversions\x18\x01 \x03(\v2\r.dfs.FileInfoR\bversions\"F\n" +
+	"\x0eRestoreRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"e\n" +
+	"\x0fRestoreResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x03 \x01(\x03R\n" +
+	"generation\"\xf4\x01\n" +
+	"\x14SetAttributesRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x124\n" +
+	"\x03set\x18\x02 \x03(\v2\".dfs.SetAttributesRequest.SetEntryR\x03set\x12\x16\n" +
+	"\x06remove\x18\x03 \x03(\tR\x06remove\x12\x19\n" +
+	"\badd_tags\x18\x04 \x03(\tR\aaddTags\x12\x1f\n" +
+	"\vremove_tags\x18\x05 \x03(\tR\n" +
+	"removeTags\x1a6\n" +
+	"\bSetEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"n\n" +
+	"\x15SetAttributesResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\x04file\x18\x03 \x01(\v2\r.dfs.FileInfoR\x04file\"*\n" +
+	"\x10ListTrashRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\">\n" +
+	"\x11ListTrashResponse\x12)\n" +
+	"\aentries\x18\x01 \x03(\v2\x0f.dfs.TrashEntryR\aentries\"\x95\x01\n" +
+	"\n" +
+	"TrashEntry\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12!\n" +
+	"\x04file\x18\x02 \x01(\v2\r.dfs.FileInfoR\x04file\x12\x1d\n" +
+	"\n" +
+	"deleted_at\x18\x03 \x01(\x03R\tdeletedAt\x12\x19\n" +
+	"\bpurge_at\x18\x04 \x01(\x03R\apurgeAt\x12\x1a\n" +
+	"\bversions\x18\x05 \x01(\x05R\bversions\"]\n" +
+	"\x0fUndeleteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12 \n" +
+	"\vdestination\x18\x02 \x01(\tR\vdestination\x12\x18\n" +
+	"\aparents\x18\x03 \x01(\bR\aparents\"\x82\x01\n" +
+	"\x10UndeleteResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1a\n" +
+	"\bfilename\x18\x03 \x01(\tR\bfilename\x12\x1e\n" +
+	"\n" +
+	"generation\x18\x04 \x01(\x03R\n" +
+	"generation\"M\n" +
+	"\fWatchRequest\x12\x16\n" +
+	"\x06prefix\x18\x01 \x01(\tR\x06prefix\x12%\n" +
+	"\x0eafter_sequence\x18\x02 \x01(\x04R\rafterSequence\"\xb7\x01\n" +
+	"\n" +
+	"WatchEvent\x12\x1a\n" +
+	"\bsequence\x18\x01 \x01(\x04R\bsequence\x12'\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x13.dfs.WatchEventTypeR\x04type\x12\x12\n" +
+	"\x04path\x18\x03 \x01(\tR\x04path\x12\x19\n" +
+	"\bold_path\x18\x04 \x01(\tR\aoldPath\x12!\n" +
+	"\x04file\x18\x05 \x01(\v2\r.dfs.FileInfoR\x04file\x12\x12\n" +
+	"\x04time\x18\x06 \x01(\x03R\x04time\"\x13\n" +
+	"\x11RotateKeysRequest\"\x9b\x01\n" +
+	"\x12RotateKeysResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1d\n" +
+	"\n" +
+	"active_key\x18\x03 \x01(\tR\tactiveKey\x12\x1c\n" +
+	"\trewrapped\x18\x04 \x01(\x03R\trewrapped\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x03R\x05total\"C\n" +
 	"\vStatRequest\x12\x1a\n" +
-	"\bfilename\x18\x01 \x01(\tR\bfilename\"I\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"u\n" +
 	"\fStatResponse\x12\x16\n" +
 	"\x06exists\x18\x01 \x01(\bR\x06exists\x12!\n" +
-	"\x04file\x18\x02 \x01(\v2\r.dfs.FileInfoR\x04file2\x8a\x02\n" +
+	"\x04file\x18\x02 \x01(\v2\r.dfs.FileInfoR\x04file\x12*\n" +
+	"\x06chunks\x18\x03 \x03(\v2\x12.dfs.ChunkLocationR\x06chunks\"P\n" +
+	"\x18GetChunkLocationsRequest\x12\x1a\n" +
+	"\bfilename\x18\x01 \x01(\tR\bfilename\x12\x18\n" +
+	"\aversion\x18\x02 \x01(\x03R\aversion\"\x85\x01\n" +
+	"\x19GetChunkLocationsResponse\x12!\n" +
+	"\x04file\x18\x01 \x01(\v2\r.dfs.FileInfoR\x04file\x12*\n" +
+	"\x06chunks\x18\x02 \x03(\v2\x12.dfs.ChunkLocationR\x06chunks\x12\x19\n" +
+	"\bdata_key\x18\x03 \x01(\fR\adataKey\"\xb4\x02\n" +
+	"\rChunkLocation\x12\x19\n" +
+	"\bchunk_id\x18\x01 \x01(\tR\achunkId\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x03R\x06offset\x12\x12\n" +
+	"\x04size\x18\x03 \x01(\x03R\x04size\x12\x1c\n" +
+	"\taddresses\x18\x04 \x03(\tR\taddresses\x12)\n" +
+	"\x10healthy_replicas\x18\x05 \x01(\x05R\x0fhealthyReplicas\x12\x16\n" +
+	"\x06crc32c\x18\x06 \x01(\rR\x06crc32c\x12\x12\n" +
+	"\x04hash\x18\a \x01(\tR\x04hash\x12\x12\n" +
+	"\x04refs\x18\b \x01(\x05R\x04refs\x122\n" +
+	"\vcompression\x18\t \x01(\x0e2\x10.dfs.CompressionR\vcompression\x12\x1f\n" +
+	"\vstored_size\x18\n" +
+	" \x01(\x03R\n" +
+	"storedSize*`\n" +
+	"\tWriteMode\x12\x15\n" +
+	"\x11WRITE_MODE_CREATE\x10\x00\x12\x18\n" +
+	"\x14WRITE_MODE_OVERWRITE\x10\x01\x12\"\n" +
+	"\x1eWRITE_MODE_IF_GENERATION_MATCH\x10\x02*e\n" +
+	"\fChunkingMode\x12\x19\n" +
+	"\x15CHUNKING_MODE_DEFAULT\x10\x00\x12\x17\n" +
+	"\x13CHUNKING_MODE_FIXED\x10\x01\x12!\n" +
+	"\x1dCHUNKING_MODE_CONTENT_DEFINED\x10\x02*h\n" +
+	"\vCompression\x12\x17\n" +
+	"\x13COMPRESSION_DEFAULT\x10\x00\x12\x14\n" +
+	"\x10COMPRESSION_NONE\x10\x01\x12\x14\n" +
+	"\x10COMPRESSION_GZIP\x10\x02\x12\x14\n" +
+	"\x10COMPRESSION_ZSTD\x10\x03*J\n" +
+	"\bListSort\x12\x12\n" +
+	"\x0eLIST_SORT_NAME\x10\x00\x12\x12\n" +
+	"\x0eLIST_SORT_SIZE\x10\x01\x12\x16\n" +
+	"\x12LIST_SORT_MODIFIED\x10\x02*\x8c\x01\n" +
+	"\x0eWatchEventType\x12\x16\n" +
+	"\x12WATCH_EVENT_CREATE\x10\x00\x12\x16\n" +
+	"\x12WATCH_EVENT_UPDATE\x10\x01\x12\x16\n" +
+	"\x12WATCH_EVENT_DELETE\x10\x02\x12\x16\n" +
+	"\x12WATCH_EVENT_RENAME\x10\x03\x12\x1a\n" +
+	"\x16WATCH_EVENT_ATTRIBUTES\x10\x042\x8d\n" +
+	"\n" +
 	"\vFileService\x123\n" +
 	"\x06Upload\x12\x12.dfs.UploadRequest\x1a\x13.dfs.UploadResponse(\x01\x129\n" +
 	"\bDownload\x12\x14.dfs.DownloadRequest\x1a\x15.dfs.DownloadResponse0\x01\x12+\n" +
 	"\x04List\x12\x10.dfs.ListRequest\x1a\x11.dfs.ListResponse\x121\n" +
 	"\x06Delete\x12\x12.dfs.DeleteRequest\x1a\x13.dfs.DeleteResponse\x12+\n" +
-	"\x04Stat\x12\x10.dfs.StatRequest\x1a\x11.dfs.StatResponseB$Z\"github.com/darshanmadesh/godfs/apib\x06proto3"
+	"\x04Stat\x12\x10.dfs.StatRequest\x1a\x11.dfs.StatResponse\x12R\n" +
+	"\x11GetChunkLocations\x12\x1d.dfs.GetChunkLocationsRequest\x1a\x1e.dfs.GetChunkLocationsResponse\x12@\n" +
+	"\vStartUpload\x12\x17.dfs.StartUploadRequest\x1a\x18.dfs.StartUploadResponse\x12B\n" +
+	"\vWriteUpload\x12\x17.dfs.WriteUploadRequest\x1a\x18.dfs.WriteUploadResponse(\x01\x12@\n" +
+	"\vQueryUpload\x12\x17.dfs.QueryUploadRequest\x1a\x18.dfs.QueryUploadResponse\x12=\n" +
+	"\fFinishUpload\x12\x18.dfs.FinishUploadRequest\x1a\x13.dfs.UploadResponse\x12@\n" +
+	"\vAbortUpload\x12\x17.dfs.AbortUploadRequest\x1a\x18.dfs.AbortUploadResponse\x12.\n" +
+	"\x05Mkdir\x12\x11.dfs.MkdirRequest\x1a\x12.dfs.MkdirResponse\x12.\n" +
+	"\x05Rmdir\x12\x11.dfs.RmdirRequest\x1a\x12.dfs.RmdirResponse\x121\n" +
+	"\x06Rename\x12\x12.dfs.RenameRequest\x1a\x13.dfs.RenameResponse\x12+\n" +
+	"\x04Copy\x12\x10.dfs.CopyRequest\x1a\x11.dfs.CopyResponse\x12C\n" +
+	"\fListVersions\x12\x18.dfs.ListVersionsRequest\x1a\x19.dfs.ListVersionsResponse\x124\n" +
+	"\aRestore\x12\x13.dfs.RestoreRequest\x1a\x14.dfs.RestoreResponse\x12F\n" +
+	"\rSetAttributes\x12\x19.dfs.SetAttributesRequest\x1a\x1a.dfs.SetAttributesResponse\x12:\n" +
+	"\tListTrash\x12\x15.dfs.ListTrashRequest\x1a\x16.dfs.ListTrashResponse\x127\n" +
+	"\bUndelete\x12\x14.dfs.UndeleteRequest\x1a\x15.dfs.UndeleteResponse\x12-\n" +
+	"\x05Watch\x12\x11.dfs.WatchRequest\x1a\x0f.dfs.WatchEvent0\x01\x12=\n" +
+	"\n" +
+	"RotateKeys\x12\x16.dfs.RotateKeysRequest\x1a\x17.dfs.RotateKeysResponseB$Z\"github.com/darshanmadesh/godfs/apib\x06proto3"
 
 var (
 	file_proto_dfs_proto_rawDescOnce sync.Once
@@ -756,41 +3661,139 @@ func file_proto_dfs_proto_rawDescGZIP() []byte {
 	return file_proto_dfs_proto_rawDescData
 }
 
-var file_proto_dfs_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
+var file_proto_dfs_proto_enumTypes = make([]protoimpl.EnumInfo, 5)
+var file_proto_dfs_proto_msgTypes = make([]protoimpl.MessageInfo, 51)
 var file_proto_dfs_proto_goTypes = []any{
-	(*UploadRequest)(nil),    // 0: dfs.UploadRequest
-	(*FileMetadata)(nil),     // 1: dfs.FileMetadata
-	(*UploadResponse)(nil),   // 2: dfs.UploadResponse
-	(*DownloadRequest)(nil),  // 3: dfs.DownloadRequest
-	(*DownloadResponse)(nil), // 4: dfs.DownloadResponse
-	(*ListRequest)(nil),      // 5: dfs.ListRequest
-	(*ListResponse)(nil),     // 6: dfs.ListResponse
-	(*FileInfo)(nil),         // 7: dfs.FileInfo
-	(*DeleteRequest)(nil),    // 8: dfs.DeleteRequest
-	(*DeleteResponse)(nil),   // 9: dfs.DeleteResponse
-	(*StatRequest)(nil),      // 10: dfs.StatRequest
-	(*StatResponse)(nil),     // 11: dfs.StatResponse
+	(WriteMode)(0),                    // 0: dfs.WriteMode
+	(ChunkingMode)(0),                 // 1: dfs.ChunkingMode
+	(Compression)(0),                  // 2: dfs.Compression
+	(ListSort)(0),                     // 3: dfs.ListSort
+	(WatchEventType)(0),               // 4: dfs.WatchEventType
+	(*UploadRequest)(nil),             // 5: dfs.UploadRequest
+	(*FileMetadata)(nil),              // 6: dfs.FileMetadata
+	(*UploadResponse)(nil),            // 7: dfs.UploadResponse
+	(*StartUploadRequest)(nil),        // 8: dfs.StartUploadRequest
+	(*StartUploadResponse)(nil),       // 9: dfs.StartUploadResponse
+	(*WriteUploadRequest)(nil),        // 10: dfs.WriteUploadRequest
+	(*WriteUploadResponse)(nil),       // 11: dfs.WriteUploadResponse
+	(*QueryUploadRequest)(nil),        // 12: dfs.QueryUploadRequest
+	(*QueryUploadResponse)(nil),       // 13: dfs.QueryUploadResponse
+	(*FinishUploadRequest)(nil),       // 14: dfs.FinishUploadRequest
+	(*AbortUploadRequest)(nil),        // 15: dfs.AbortUploadRequest
+	(*AbortUploadResponse)(nil),       // 16: dfs.AbortUploadResponse
+	(*DownloadRequest)(nil),           // 17: dfs.DownloadRequest
+	(*DownloadResponse)(nil),          // 18: dfs.DownloadResponse
+	(*ListRequest)(nil),               // 19: dfs.ListRequest
+	(*ListResponse)(nil),              // 20: dfs.ListResponse
+	(*FileInfo)(nil),                  // 21: dfs.FileInfo
+	(*DeleteRequest)(nil),             // 22: dfs.DeleteRequest
+	(*DeleteResponse)(nil),            // 23: dfs.DeleteResponse
+	(*MkdirRequest)(nil),              // 24: dfs.MkdirRequest
+	(*MkdirResponse)(nil),             // 25: dfs.MkdirResponse
+	(*RmdirRequest)(nil),              // 26: dfs.RmdirRequest
+	(*RmdirResponse)(nil),             // 27: dfs.RmdirResponse
+	(*RenameRequest)(nil),             // 28: dfs.RenameRequest
+	(*RenameResponse)(nil),            // 29: dfs.RenameResponse
+	(*CopyRequest)(nil),               // 30: dfs.CopyRequest
+	(*CopyResponse)(nil),              // 31: dfs.CopyResponse
+	(*ListVersionsRequest)(nil),       // 32: dfs.ListVersionsRequest
+	(*ListVersionsResponse)(nil),      // 33: dfs.ListVersionsResponse
+	(*RestoreRequest)(nil),            // 34: dfs.RestoreRequest
+	(*RestoreResponse)(nil),           // 35: dfs.RestoreResponse
+	(*SetAttributesRequest)(nil),      // 36: dfs.SetAttributesRequest
+	(*SetAttributesResponse)(nil),     // 37: dfs.SetAttributesResponse
+	(*ListTrashRequest)(nil),          // 38: dfs.ListTrashRequest
+	(*ListTrashResponse)(nil),         // 39: dfs.ListTrashResponse
+	(*TrashEntry)(nil),                // 40: dfs.TrashEntry
+	(*UndeleteRequest)(nil),           // 41: dfs.UndeleteRequest
+	(*UndeleteResponse)(nil),          // 42: dfs.UndeleteResponse
+	(*WatchRequest)(nil),              // 43: dfs.WatchRequest
+	(*WatchEvent)(nil),                // 44: dfs.WatchEvent
+	(*RotateKeysRequest)(nil),         // 45: dfs.RotateKeysRequest
+	(*RotateKeysResponse)(nil),        // 46: dfs.RotateKeysResponse
+	(*StatRequest)(nil),               // 47: dfs.StatRequest
+	(*StatResponse)(nil),              // 48: dfs.StatResponse
+	(*GetChunkLocationsRequest)(nil),  // 49: dfs.GetChunkLocationsRequest
+	(*GetChunkLocationsResponse)(nil), // 50: dfs.GetChunkLocationsResponse
+	(*ChunkLocation)(nil),             // 51: dfs.ChunkLocation
+	nil,                               // 52: dfs.FileMetadata.AttributesEntry
+	nil,                               // 53: dfs.ListRequest.AttributesEntry
+	nil,                               // 54: dfs.FileInfo.AttributesEntry
+	nil,                               // 55: dfs.SetAttributesRequest.SetEntry
 }
 var file_proto_dfs_proto_depIdxs = []int32{
-	1,  // 0: dfs.UploadRequest.metadata:type_name -> dfs.FileMetadata
-	1,  // 1: dfs.DownloadResponse.metadata:type_name -> dfs.FileMetadata
-	7,  // 2: dfs.ListResponse.files:type_name -> dfs.FileInfo
-	7,  // 3: dfs.StatResponse.file:type_name -> dfs.FileInfo
-	0,  // 4: dfs.FileService.Upload:input_type -> dfs.UploadRequest
-	3,  // 5: dfs.FileService.Download:input_type -> dfs.DownloadRequest
-	5,  // 6: dfs.FileService.List:input_type -> dfs.ListRequest
-	8,  // 7: dfs.FileService.Delete:input_type -> dfs.DeleteRequest
-	10, // 8: dfs.FileService.Stat:input_type -> dfs.StatRequest
-	2,  // 9: dfs.FileService.Upload:output_type -> dfs.UploadResponse
-	4,  // 10: dfs.FileService.Download:output_type -> dfs.DownloadResponse
-	6,  // 11: dfs.FileService.List:output_type -> dfs.ListResponse
-	9,  // 12: dfs.FileService.Delete:output_type -> dfs.DeleteResponse
-	11, // 13: dfs.FileService.Stat:output_type -> dfs.StatResponse
-	9,  // [9:14] is the sub-list for method output_type
-	4,  // [4:9] is the sub-list for method input_type
-	4,  // [4:4] is the sub-list for extension type_name
-	4,  // [4:4] is the sub-list for extension extendee
-	0,  // [0:4] is the sub-list for field type_name
+	6,  // 0: dfs.UploadRequest.metadata:type_name -> dfs.FileMetadata
+	0,  // 1: dfs.FileMetadata.mode:type_name -> dfs.WriteMode
+	52, // 2: dfs.FileMetadata.attributes:type_name -> dfs.FileMetadata.AttributesEntry
+	1,  // 3: dfs.FileMetadata.chunking:type_name -> dfs.ChunkingMode
+	2,  // 4: dfs.FileMetadata.compression:type_name -> dfs.Compression
+	6,  // 5: dfs.StartUploadRequest.metadata:type_name -> dfs.FileMetadata
+	6,  // 6: dfs.DownloadResponse.metadata:type_name -> dfs.FileMetadata
+	3,  // 7: dfs.ListRequest.sort:type_name -> dfs.ListSort
+	53, // 8: dfs.ListRequest.attributes:type_name -> dfs.ListRequest.AttributesEntry
+	21, // 9: dfs.ListResponse.files:type_name -> dfs.FileInfo
+	54, // 10: dfs.FileInfo.attributes:type_name -> dfs.FileInfo.AttributesEntry
+	21, // 11: dfs.ListVersionsResponse.versions:type_name -> dfs.FileInfo
+	55, // 12: dfs.SetAttributesRequest.set:type_name -> dfs.SetAttributesRequest.SetEntry
+	21, // 13: dfs.SetAttributesResponse.file:type_name -> dfs.FileInfo
+	40, // 14: dfs.ListTrashResponse.entries:type_name -> dfs.TrashEntry
+	21, // 15: dfs.TrashEntry.file:type_name -> dfs.FileInfo
+	4,  // 16: dfs.WatchEvent.type:type_name -> dfs.WatchEventType
+	21, // 17: dfs.WatchEvent.file:type_name -> dfs.FileInfo
+	21, // 18: dfs.StatResponse.file:type_name -> dfs.FileInfo
+	51, // 19: dfs.StatResponse.chunks:type_name -> dfs.ChunkLocation
+	21, // 20: dfs.GetChunkLocationsResponse.file:type_name -> dfs.FileInfo
+	51, // 21: dfs.GetChunkLocationsResponse.chunks:type_name -> dfs.ChunkLocation
+	2,  // 22: dfs.ChunkLocation.compression:type_name -> dfs.Compression
+	5,  // 23: dfs.FileService.Upload:input_type -> dfs.UploadRequest
+	17, // 24: dfs.FileService.Download:input_type -> dfs.DownloadRequest
+	19, // 25: dfs.FileService.List:input_type -> dfs.ListRequest
+	22, // 26: dfs.FileService.Delete:input_type -> dfs.DeleteRequest
+	47, // 27: dfs.FileService.Stat:input_type -> dfs.StatRequest
+	49, // 28: dfs.FileService.GetChunkLocations:input_type -> dfs.GetChunkLocationsRequest
+	8,  // 29: dfs.FileService.StartUpload:input_type -> dfs.StartUploadRequest
+	10, // 30: dfs.FileService.WriteUpload:input_type -> dfs.WriteUploadRequest
+	12, // 31: dfs.FileService.QueryUpload:input_type -> dfs.QueryUploadRequest
+	14, // 32: dfs.FileService.FinishUpload:input_type -> dfs.FinishUploadRequest
+	15, // 33: dfs.FileService.AbortUpload:input_type -> dfs.AbortUploadRequest
+	24, // 34: dfs.FileService.Mkdir:input_type -> dfs.MkdirRequest
+	26, // 35: dfs.FileService.Rmdir:input_type -> dfs.RmdirRequest
+	28, // 36: dfs.FileService.Rename:input_type -> dfs.RenameRequest
+	30, // 37: dfs.FileService.Copy:input_type -> dfs.CopyRequest
+	32, // 38: dfs.FileService.ListVersions:input_type -> dfs.ListVersionsRequest
+	34, // 39: dfs.FileService.Restore:input_type -> dfs.RestoreRequest
+	36, // 40: dfs.FileService.SetAttributes:input_type -> dfs.SetAttributesRequest
+	38, // 41: dfs.FileService.ListTrash:input_type -> dfs.ListTrashRequest
+	41, // 42: dfs.FileService.Undelete:input_type -> dfs.UndeleteRequest
+	43, // 43: dfs.FileService.Watch:input_type -> dfs.WatchRequest
+	45, // 44: dfs.FileService.RotateKeys:input_type -> dfs.RotateKeysRequest
+	7,  // 45: dfs.FileService.Upload:output_type -> dfs.UploadResponse
+	18, // 46: dfs.FileService.Download:output_type -> dfs.DownloadResponse
+	20, // 47: dfs.FileService.List:output_type -> dfs.ListResponse
+	23, // 48: dfs.FileService.Delete:output_type -> dfs.DeleteResponse
+	48, // 49: dfs.FileService.Stat:output_type -> dfs.StatResponse
+	50, // 50: dfs.FileService.GetChunkLocations:output_type -> dfs.GetChunkLocationsResponse
+	9,  // 51: dfs.FileService.StartUpload:output_type -> dfs.StartUploadResponse
+	11, // 52: dfs.FileService.WriteUpload:output_type -> dfs.WriteUploadResponse
+	13, // 53: dfs.FileService.QueryUpload:output_type -> dfs.QueryUploadResponse
+	7,  // 54: dfs.FileService.FinishUpload:output_type -> dfs.UploadResponse
+	16, // 55: dfs.FileService.AbortUpload:output_type -> dfs.AbortUploadResponse
+	25, // 56: dfs.FileService.Mkdir:output_type -> dfs.MkdirResponse
+	27, // 57: dfs.FileService.Rmdir:output_type -> dfs.RmdirResponse
+	29, // 58: dfs.FileService.Rename:output_type -> dfs.RenameResponse
+	31, // 59: dfs.FileService.Copy:output_type -> dfs.CopyResponse
+	33, // 60: dfs.FileService.ListVersions:output_type -> dfs.ListVersionsResponse
+	35, // 61: dfs.FileService.Restore:output_type -> dfs.RestoreResponse
+	37, // 62: dfs.FileService.SetAttributes:output_type -> dfs.SetAttributesResponse
+	39, // 63: dfs.FileService.ListTrash:output_type -> dfs.ListTrashResponse
+	42, // 64: dfs.FileService.Undelete:output_type -> dfs.UndeleteResponse
+	44, // 65: dfs.FileService.Watch:output_type -> dfs.WatchEvent
+	46, // 66: dfs.FileService.RotateKeys:output_type -> dfs.RotateKeysResponse
+	45, // [45:67] is the sub-list for method output_type
+	23, // [23:45] is the sub-list for method input_type
+	23, // [23:23] is the sub-list for extension type_name
+	23, // [23:23] is the sub-list for extension extendee
+	0,  // [0:23] is the sub-list for field type_name
 }
 
 func init() { file_proto_dfs_proto_init() }
@@ -802,7 +3805,7 @@ func file_proto_dfs_proto_init() {
 		(*UploadRequest_Metadata)(nil),
 		(*UploadRequest_Chunk)(nil),
 	}
-	file_proto_dfs_proto_msgTypes[4].OneofWrappers = []any{
+	file_proto_dfs_proto_msgTypes[13].OneofWrappers = []any{
 		(*DownloadResponse_Metadata)(nil),
 		(*DownloadResponse_Chunk)(nil),
 	}
@@ -811,13 +3814,14 @@ func file_proto_dfs_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_dfs_proto_rawDesc), len(file_proto_dfs_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   12,
+			NumEnums:      5,
+			NumMessages:   51,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_proto_dfs_proto_goTypes,
 		DependencyIndexes: file_proto_dfs_proto_depIdxs,
+		EnumInfos:         file_proto_dfs_proto_enumTypes,
 		MessageInfos:      file_proto_dfs_proto_msgTypes,
 	}.Build()
 	File_proto_dfs_proto = out.File
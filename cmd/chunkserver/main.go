@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"google.golang.org/grpc"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkserver"
+)
+
+func main() {
+	// Parse command-line flags.
+	port := flag.Int("port", 50052, "Port to listen on")
+	dataDir := flag.String("data-dir", "./chunkdata", "Directory to store chunk data")
+	masterAddr := flag.String("master", "localhost:50051", "Master address (host:port)")
+	advertise := flag.String("advertise", "", "Address the master and clients use to reach this chunkserver (default: localhost:<port>)")
+	heartbeatInterval := flag.Duration("heartbeat-interval", chunkserver.DefaultHeartbeatInterval, "How often to report to the master")
+	capacity := flag.Int64("capacity", 0, "Bytes of storage to offer (0 = what the data disk has free, plus what chunks already use)")
+	flag.Parse()
+
+	if *advertise == "" {
+		*advertise = fmt.Sprintf("localhost:%d", *port)
+	}
+
+	// Create the chunk server
+	chunkServer, err := chunkserver.NewServer(*dataDir)
+	if err != nil {
+		log.Fatalf("Failed to create chunkserver: %v", err)
+	}
+	defer chunkServer.Close()
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Fatalf("Failed to listen on port %d: %v", *port, err)
+	}
+
+	// Register the ChunkService with a gRPC server.
+	grpcServer := grpc.NewServer()
+	api.RegisterChunkServiceServer(grpcServer, chunkServer)
+
+	// Report to the master in the background until we shut down.
+	// The master only places chunks on chunkservers it hears from.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		err := chunkServer.RunHeartbeats(ctx, chunkserver.HeartbeatConfig{
+			Master:   *masterAddr,
+			Address:  *advertise,
+			Interval: *heartbeatInterval,
+			Capacity: *capacity,
+		})
+		if err != nil {
+			log.Fatalf("Failed to start heartbeats: %v", err)
+		}
+	}()
+
+	// Graceful shutdown on SIGINT/SIGTERM, same as the master.
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-stop
+		log.Println("Shutting down chunkserver...")
+		cancel() // Stop heartbeating first so the master stops sending work
+		grpcServer.GracefulStop()
+	}()
+
+	log.Printf("GoDFS Chunkserver starting...")
+	log.Printf("  Port:     %d", *port)
+	log.Printf("  Data dir: %s", *dataDir)
+	log.Printf("  Master:   %s", *masterAddr)
+	log.Printf("  Address:  %s", *advertise)
+	log.Println("Press Ctrl+C to stop")
+
+	if err := grpcServer.Serve(listener); err != nil {
+		log.Fatalf("Failed to serve: %v", err)
+	}
+
+	log.Println("Chunkserver stopped")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// attributeFlag defines a repeatable KEY=VALUE flag on fs, collecting the
+// pairs into a map. With optionalValue, a bare KEY is allowed too, and
+// maps to an empty value.
+func attributeFlag(fs *flag.FlagSet, name, usage string, optionalValue bool) map[string]string {
+	attributes := make(map[string]string)
+	fs.Func(name, usage, func(value string) error {
+		key, v, ok := strings.Cut(value, "=")
+		if key == "" || !ok && !optionalValue {
+			return fmt.Errorf("want KEY=VALUE, got %q", value)
+		}
+		attributes[key] = v
+		return nil
+	})
+	return attributes
+}
+
+// listFlag defines a repeatable flag on fs, collecting its values.
+func listFlag(fs *flag.FlagSet, name, usage string) *[]string {
+	var values []string
+	fs.Func(name, usage, func(value string) error {
+		values = append(values, value)
+		return nil
+	})
+	return &values
+}
+
+// handleSetAttributes changes a file's user metadata without uploading it
+// again.
+func handleSetAttributes(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("setattr", flag.ContinueOnError)
+	set := attributeFlag(fs, "set", "Set an attribute, as KEY=VALUE (repeatable)", false)
+	unset := listFlag(fs, "unset", "Remove an attribute (repeatable)")
+	tag := listFlag(fs, "tag", "Add a tag (repeatable)")
+	untag := listFlag(fs, "untag", "Remove a tag (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: setattr [--set KEY=VALUE]... [--unset KEY]... [--tag T]... [--untag T]... <path>")
+	}
+
+	resp, err := client.SetAttributes(ctx, &api.SetAttributesRequest{
+		Filename:   args[0],
+		Set:        set,
+		Remove:     *unset,
+		AddTags:    *tag,
+		RemoveTags: *untag,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set attributes: %w", err)
+	}
+
+	fmt.Println(resp.Message)
+	printAttributes(resp.File)
+	return nil
+}
+
+// printAttributes prints a file's user metadata, if it has any.
+func printAttributes(f *api.FileInfo) {
+	for _, key := range slices.Sorted(maps.Keys(f.Attributes)) {
+		fmt.Printf("Attr:     %s=%s\n", key, f.Attributes[key])
+	}
+	if len(f.Tags) > 0 {
+		fmt.Printf("Tags:     %s\n", strings.Join(f.Tags, ", "))
+	}
+}
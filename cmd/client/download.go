@@ -0,0 +1,560 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkclient"
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+// castagnoli is the CRC-32C table chunkservers use for chunk checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// readOptions selects which part of a remote file to read, and how.
+type readOptions struct {
+	// offset and length select a byte range, as in DownloadRequest:
+	// a negative offset counts back from the end, length 0 reads to the end.
+	offset int64
+	length int64
+
+	// version selects an old version of the file by generation;
+	// 0 reads the current one.
+	version int64
+
+	// proxy reads through the master instead of from chunkservers.
+	proxy bool
+
+	// progress prints a progress line to stdout.
+	progress bool
+}
+
+// Suffixes of the files a download in progress keeps next to its target.
+const (
+	partialSuffix       = ".partial"        // The data received so far
+	downloadStateSuffix = ".godfs-download" // Which remote file version it is
+)
+
+// downloadState identifies the remote file a partial download belongs to.
+// If the file has been replaced since, the partial data is from another
+// version and must be thrown away rather than resumed.
+type downloadState struct {
+	Filename   string `json:"filename"`
+	Size       int64  `json:"size"`
+	Checksum   string `json:"checksum"`
+	Generation int64  `json:"generation"`
+}
+
+// handleDownload downloads a file from the DFS to local filesystem.
+//
+// By default chunks are read straight from the chunkservers, using the
+// locations the master hands out. With --proxy the master streams the data
+// instead, for when chunkservers aren't reachable from the client.
+//
+// Whole-file downloads are resumable. Data goes to "<local>.partial", with
+// "<local>.godfs-download" recording which version of the remote file it
+// is. If the transfer fails it is retried from where it stopped, up to
+// --retries times; if the client itself dies, running the same command
+// again picks up from the partial file. Each attempt reads the version
+// the download started with, and if that is gone the download fails
+// rather than mix in another. The finished file is checked against the
+// checksum recorded at upload time before it is moved into place.
+//
+// --offset and --length download just part of the file (not resumable).
+// --version downloads an old version of the file.
+func handleDownload(ctx context.Context, client api.FileServiceClient, args []string) error {
+	// Each command parses its own flags from the arguments after its name.
+	fs := flag.NewFlagSet("download", flag.ContinueOnError)
+	proxy := fs.Bool("proxy", false, "Download through the master instead of directly from chunkservers")
+	offset := fs.Int64("offset", 0, "Start reading at this byte (negative: counts back from the end)")
+	length := fs.Int64("length", 0, "Read at most this many bytes (0: to the end)")
+	retries := fs.Int("retries", 5, "How many times to resume after a failure before giving up")
+	version := fs.Int64("version", 0, "Download this old version (a generation) instead of the current one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: download [--proxy] [--offset N] [--length N] [--retries N] [--version G] <remote-file> [local-path]")
+	}
+	if *length < 0 {
+		return fmt.Errorf("invalid length %d", *length)
+	}
+
+	remoteFile := args[0]
+	// Default: the remote file's name, in the current directory. Only the
+	// last element, so a remote path can't place files elsewhere on disk.
+	localPath := path.Base(remoteFile)
+	if len(args) > 1 {
+		localPath = args[1]
+	}
+
+	opts := readOptions{
+		offset:   *offset,
+		length:   *length,
+		version:  *version,
+		proxy:    *proxy,
+		progress: true,
+	}
+
+	var totalReceived int64
+	var err error
+	if *offset != 0 || *length != 0 {
+		totalReceived, err = downloadRange(ctx, client, remoteFile, localPath, opts)
+	} else {
+		totalReceived, err = downloadResumable(ctx, client, remoteFile, localPath, opts, *retries)
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\r") // Clear progress line
+	fmt.Printf("Downloaded '%s' to '%s' (%d bytes)\n", remoteFile, localPath, totalReceived)
+	wire.report(false)
+
+	return nil
+}
+
+// downloadRange downloads part of a file straight to localPath.
+func downloadRange(ctx context.Context, client api.FileServiceClient, remoteFile, localPath string, opts readOptions) (int64, error) {
+	// Create local file
+	file, err := os.Create(localPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create local file: %w", err)
+	}
+	defer file.Close()
+
+	n, err := readFile(ctx, client, remoteFile, file, opts)
+	if err != nil {
+		// Clean up partial file on error
+		file.Close()
+		os.Remove(localPath)
+		return n, err
+	}
+	return n, nil
+}
+
+// downloadResumable downloads a whole file via a partial file that
+// survives failures, and returns the file's size.
+func downloadResumable(ctx context.Context, client api.FileServiceClient, remoteFile, localPath string, opts readOptions, retries int) (int64, error) {
+	partialPath := localPath + partialSuffix
+	statePath := localPath + downloadStateSuffix
+
+	stat, err := client.Stat(ctx, &api.StatRequest{
+		Filename: remoteFile,
+		Version:  opts.version,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get file info: %w", err)
+	}
+	if !stat.Exists {
+		return 0, fmt.Errorf("file not found: %s", remoteFile)
+	}
+	if stat.File.IsDir {
+		return 0, fmt.Errorf("%s is a directory", remoteFile)
+	}
+	want := downloadState{
+		Filename:   stat.File.Filename,
+		Size:       stat.File.Size,
+		Checksum:   stat.File.Checksum,
+		Generation: stat.File.Generation,
+	}
+
+	// Every attempt reads this version, even if the file is replaced
+	// meanwhile: resuming on top of another version's data would only
+	// fail the checksum at the very end.
+	opts.version = want.Generation
+
+	// Keep what an earlier attempt fetched, if it was fetching this
+	// same version of the file; otherwise start afresh.
+	flags := os.O_RDWR | os.O_CREATE
+	if !matchesDownloadState(statePath, want) {
+		flags |= os.O_TRUNC
+		data, err := json.Marshal(want)
+		if err != nil {
+			return 0, err
+		}
+		if err := os.WriteFile(statePath, data, 0644); err != nil {
+			return 0, fmt.Errorf("failed to save download state: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(partialPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create local file: %w", err)
+	}
+	defer file.Close()
+
+	// discard throws the partial data away: it is wrong, not just short
+	discard := func(err error) (int64, error) {
+		file.Close()
+		os.Remove(partialPath)
+		os.Remove(statePath)
+		return 0, err
+	}
+
+	// Everything already on disk counts towards the checksum
+	hash := sha256.New()
+	offset, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read partial download: %w", err)
+	}
+	if offset > want.Size {
+		return discard(fmt.Errorf("partial download is larger than the file; removed it, please retry"))
+	}
+	if offset > 0 {
+		fmt.Printf("Resuming download at %s of %s\n", formatSize(offset), formatSize(want.Size))
+	}
+
+	out := io.MultiWriter(file, hash)
+	for attempt := 1; offset < want.Size; attempt++ {
+		opts.offset = offset
+		n, _, err := fetch(ctx, client, remoteFile, out, opts)
+		offset += n
+		if err == nil {
+			break
+		}
+		if status.Code(err) == codes.NotFound {
+			// Deleted, or replaced and its old version since dropped:
+			// what we have can't be finished
+			return discard(fmt.Errorf("%s changed during the download (version %d is gone); removed the partial download, please retry: %w", remoteFile, want.Generation, err))
+		}
+		if attempt > retries || !retryable(ctx, err) {
+			// Keep the partial file so the next run can resume it
+			return offset, fmt.Errorf("download failed at %s of %s: %w", formatSize(offset), formatSize(want.Size), err)
+		}
+
+		// Back off a little more each time, so a server that is
+		// struggling isn't hammered.
+		fmt.Printf("\nDownload interrupted (%v), retrying in %ds...\n", err, attempt)
+		select {
+		case <-ctx.Done():
+			return offset, fmt.Errorf("download failed: %w", ctx.Err())
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+	}
+
+	// The data may span several attempts, and even runs; only the
+	// checksum can tell us it's right.
+	if want.Checksum != "" {
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != want.Checksum {
+			return discard(fmt.Errorf("checksum mismatch: expected %s, got %s", want.Checksum, sum))
+		}
+	}
+
+	if err := file.Close(); err != nil {
+		return offset, fmt.Errorf("failed to write local file: %w", err)
+	}
+	if err := os.Rename(partialPath, localPath); err != nil {
+		return offset, fmt.Errorf("failed to move download into place: %w", err)
+	}
+	os.Remove(statePath)
+	return offset, nil
+}
+
+// matchesDownloadState reports whether the saved state at path describes
+// want, meaning a partial download next to it can be resumed.
+func matchesDownloadState(path string, want downloadState) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return false
+	}
+	return state == want
+}
+
+// handleCat writes a file, or a byte range of it, to stdout.
+func handleCat(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	proxy := fs.Bool("proxy", false, "Read through the master instead of directly from chunkservers")
+	rangeSpec := fs.String("range", "", "Bytes to read: START-END (inclusive), START- (to the end) or -N (the last N)")
+	version := fs.Int64("version", 0, "Read this old version (a generation) instead of the current one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: cat [--proxy] [--range START-END] [--version G] <remote-file>")
+	}
+
+	opts := readOptions{proxy: *proxy, version: *version}
+	if *rangeSpec != "" {
+		var err error
+		opts.offset, opts.length, err = parseRange(*rangeSpec)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := readFile(ctx, client, args[0], os.Stdout, opts)
+	return err
+}
+
+// parseRange parses an HTTP-style byte range into an offset and length
+// as used by DownloadRequest.
+func parseRange(spec string) (offset, length int64, err error) {
+	startStr, endStr, ok := strings.Cut(spec, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid range %q: want START-END, START- or -N", spec)
+	}
+
+	// "-N": the last N bytes
+	if startStr == "" {
+		n, err := strconv.ParseInt(endStr, 10, 64)
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid range %q: bad suffix length", spec)
+		}
+		return -n, 0, nil
+	}
+
+	start, err := strconv.ParseInt(startStr, 10, 64)
+	if err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("invalid range %q: bad start", spec)
+	}
+
+	// "START-": everything from START on
+	if endStr == "" {
+		return start, 0, nil
+	}
+
+	end, err := strconv.ParseInt(endStr, 10, 64)
+	if err != nil || end < start {
+		return 0, 0, fmt.Errorf("invalid range %q: bad end", spec)
+	}
+	return start, end - start + 1, nil
+}
+
+// resolveRange turns an offset and length into the half-open interval
+// [start, end) of a file of the given size, the same way the master does.
+func resolveRange(size, offset, length int64) (start, end int64, err error) {
+	start = offset
+	if offset < 0 {
+		start = max(size+offset, 0)
+	}
+	if start > size {
+		return 0, 0, fmt.Errorf("offset %d is past the end of the file (%d bytes)", offset, size)
+	}
+
+	end = size
+	if length > 0 {
+		end = min(start+length, size)
+	}
+	return start, end, nil
+}
+
+// readFile reads a remote file, or part of it, into w and returns the
+// number of bytes written. A whole-file read is checked against the
+// SHA-256 the master recorded at upload time.
+func readFile(ctx context.Context, client api.FileServiceClient, remoteFile string, w io.Writer, opts readOptions) (int64, error) {
+	hash := sha256.New()
+	n, checksum, err := fetch(ctx, client, remoteFile, io.MultiWriter(w, hash), opts)
+	if err != nil {
+		return n, err
+	}
+
+	if checksum != "" {
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != checksum {
+			return n, fmt.Errorf("checksum mismatch: expected %s, got %s", checksum, sum)
+		}
+	}
+	return n, nil
+}
+
+// fetch reads a remote file, or part of it, into w through the master or
+// directly from chunkservers. It returns the number of bytes written and,
+// if the whole file was read, its recorded checksum.
+func fetch(ctx context.Context, client api.FileServiceClient, remoteFile string, w io.Writer, opts readOptions) (int64, string, error) {
+	if opts.proxy {
+		return downloadViaMaster(ctx, client, remoteFile, w, opts)
+	}
+	return downloadDirect(ctx, client, remoteFile, w, opts)
+}
+
+// printProgress shows how much of a read is done.
+func printProgress(opts readOptions, done, total int64) {
+	if !opts.progress {
+		return
+	}
+	progress := float64(done) / float64(max(total, 1)) * 100
+	fmt.Printf("\rDownloading... %.1f%%", progress)
+}
+
+// downloadViaMaster streams a file through the master's Download RPC.
+// It returns the number of bytes written and, if the whole file was read,
+// its recorded checksum.
+func downloadViaMaster(ctx context.Context, client api.FileServiceClient, remoteFile string, file io.Writer, opts readOptions) (int64, string, error) {
+	// Start the download stream
+	stream, err := client.Download(ctx, &api.DownloadRequest{
+		Filename: remoteFile,
+		Offset:   opts.offset,
+		Length:   opts.length,
+		Version:  opts.version,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to start download: %w", err)
+	}
+
+	// Receive the first message (should be metadata)
+	resp, err := stream.Recv()
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to receive metadata: %w", err)
+	}
+
+	metadata, ok := resp.Data.(*api.DownloadResponse_Metadata)
+	if !ok {
+		return 0, "", fmt.Errorf("expected metadata, got chunk")
+	}
+
+	// The master already resolved the range; work it out again so we know
+	// how much to expect
+	fileSize := metadata.Metadata.Size
+	start, end, err := resolveRange(fileSize, opts.offset, opts.length)
+	if err != nil {
+		return 0, "", err
+	}
+
+	// Receive and write chunks
+	var totalReceived int64
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return totalReceived, "", fmt.Errorf("download failed: %w", err)
+		}
+
+		chunk, ok := resp.Data.(*api.DownloadResponse_Chunk)
+		if !ok {
+			continue // Skip non-chunk messages
+		}
+
+		n, err := file.Write(chunk.Chunk)
+		if err != nil {
+			return totalReceived, "", fmt.Errorf("failed to write to file: %w", err)
+		}
+
+		totalReceived += int64(n)
+		printProgress(opts, totalReceived, end-start)
+	}
+
+	if start != 0 || end != fileSize {
+		return totalReceived, "", nil
+	}
+	return totalReceived, metadata.Metadata.Checksum, nil
+}
+
+// downloadDirect asks the master where a file's chunks live and reads
+// each one straight from a chunkserver. Every whole chunk is checked
+// against the CRC-32C the master recorded for it; compressed chunks are
+// decompressed, which checks them against the codec's own checksum, and
+// encrypted chunks are decrypted with the key the master hands out, which
+// authenticates them.
+// It returns the number of bytes written and, if the whole file was read,
+// its recorded checksum.
+func downloadDirect(ctx context.Context, client api.FileServiceClient, remoteFile string, file io.Writer, opts readOptions) (int64, string, error) {
+	locs, err := client.GetChunkLocations(ctx, &api.GetChunkLocationsRequest{
+		Filename: remoteFile,
+		Version:  opts.version,
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to get chunk locations: %w", err)
+	}
+
+	fileSize := locs.File.Size
+	start, end, err := resolveRange(fileSize, opts.offset, opts.length)
+	if err != nil {
+		return 0, "", err
+	}
+
+	conns := chunkclient.NewPool(dialOptions...)
+	defer conns.Close()
+
+	var totalReceived int64
+
+	// Only read the chunks that overlap the range
+	for _, chunk := range locs.Chunks {
+		chunkEnd := chunk.Offset + chunk.Size
+		if chunkEnd <= start || chunk.Offset >= end {
+			continue
+		}
+		offset := max(start-chunk.Offset, 0)
+		length := min(end, chunkEnd) - chunk.Offset - offset
+
+		// Reading to the end of a chunk (length 0) lets the codec check it
+		codec := chunkCodec(chunk.Compression)
+		readLength := length
+		if offset+length == chunk.Size {
+			readLength = 0
+		}
+
+		var crc uint32
+		err := conns.ReadStoredRange(ctx, chunkclient.StoredChunk{
+			ID:         chunk.ChunkId,
+			Addresses:  chunk.Addresses,
+			Codec:      codec,
+			Key:        locs.DataKey,
+			StoredSize: chunk.StoredSize,
+		}, offset, readLength, func(data []byte) error {
+			crc = crc32.Update(crc, castagnoli, data)
+			n, err := file.Write(data)
+			if err != nil {
+				return fmt.Errorf("failed to write to file: %w", err)
+			}
+
+			totalReceived += int64(n)
+			printProgress(opts, totalReceived, end-start)
+			return nil
+		})
+		if err != nil {
+			return totalReceived, "", err
+		}
+
+		// Chunks written before checksums existed have no CRC recorded.
+		// Part of a chunk can't be checked; the chunkserver verified it.
+		// The CRC is of the stored bytes, so only covers chunks stored as
+		// they are.
+		whole := offset == 0 && length == chunk.Size
+		stored := codec == compression.None && locs.DataKey == nil
+		if whole && stored && chunk.Crc32C != 0 && crc != chunk.Crc32C {
+			return totalReceived, "", fmt.Errorf("chunk %s is corrupt: checksum %08x, expected %08x", chunk.ChunkId, crc, chunk.Crc32C)
+		}
+	}
+
+	if start != 0 || end != fileSize {
+		return totalReceived, "", nil
+	}
+	return totalReceived, locs.File.Checksum, nil
+}
+
+// chunkCodec returns the compression codec a chunk is stored with.
+func chunkCodec(c api.Compression) string {
+	switch c {
+	case api.Compression_COMPRESSION_GZIP:
+		return compression.Gzip
+	case api.Compression_COMPRESSION_ZSTD:
+		return compression.Zstd
+	default:
+		return compression.None
+	}
+}
@@ -2,11 +2,15 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
 	"fmt"
 	"io"
 	"os"
-	"path/filepath"
+	"path"
+	"strconv"
+	"strings"
 	"time"
 
 	"google.golang.org/grpc"
@@ -21,6 +25,8 @@ const chunkSize = 1024 * 1024
 func main() {
 	// Define flags that apply to all commands
 	serverAddr := flag.String("server", "localhost:50051", "Server address (host:port)")
+	timeout := flag.Duration("timeout", 5*time.Minute, "Give up on the command after this long (0 for no limit)")
+	wireCompression := flag.String("wire-compression", "none", "Compress RPC messages on the wire with 'gzip' or 'zstd' (for slow links), or 'none'")
 
 	// Custom usage message
 	flag.Usage = func() {
@@ -28,11 +34,38 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Usage:\n")
 		fmt.Fprintf(os.Stderr, "  %s [flags] <command> [arguments]\n\n", os.Args[0])
 		fmt.Fprintf(os.Stderr, "Commands:\n")
-		fmt.Fprintf(os.Stderr, "  upload <local-file>              Upload a file to DFS\n")
-		fmt.Fprintf(os.Stderr, "  download <remote-file> [local]   Download a file from DFS\n")
-		fmt.Fprintf(os.Stderr, "  list [prefix]                    List files in DFS\n")
-		fmt.Fprintf(os.Stderr, "  delete <filename>                Delete a file from DFS\n")
-		fmt.Fprintf(os.Stderr, "  stat <filename>                  Get file information\n\n")
+		fmt.Fprintf(os.Stderr, "  upload [--replication N] [--overwrite | --if-generation G] [--retries N] [--parents]\n")
+		fmt.Fprintf(os.Stderr, "         [--chunking fixed|cdc] [--compress zstd|gzip|none] [--attr KEY=VALUE]... [--tag T]...\n")
+		fmt.Fprintf(os.Stderr, "         <local-file> [remote-path]\n")
+		fmt.Fprintf(os.Stderr, "                                   Upload a file to DFS\n")
+		fmt.Fprintf(os.Stderr, "  download [--proxy] [--offset N] [--length N] [--retries N] [--version G]\n")
+		fmt.Fprintf(os.Stderr, "           <remote-file> [local]\n")
+		fmt.Fprintf(os.Stderr, "                                   Download a file (or part of it) from DFS\n")
+		fmt.Fprintf(os.Stderr, "  cat [--proxy] [--range START-END] [--version G] <remote-file>\n")
+		fmt.Fprintf(os.Stderr, "                                   Write a file (or a byte range) to stdout\n")
+		fmt.Fprintf(os.Stderr, "  list [--recursive] [--prefix P] [--sort name|size|modified] [--desc] [--page-size N]\n")
+		fmt.Fprintf(os.Stderr, "       [--glob G] [--regex R] [--min-size S] [--max-size S] [--since T] [--before T]\n")
+		fmt.Fprintf(os.Stderr, "       [--attr KEY[=VALUE]]... [--tag T]...\n")
+		fmt.Fprintf(os.Stderr, "       [directory]\n")
+		fmt.Fprintf(os.Stderr, "                                   List a directory (default /), or all paths with a prefix\n")
+		fmt.Fprintf(os.Stderr, "  delete [--permanent] <filename>  Move a file to the trash (or with --permanent, delete it)\n")
+		fmt.Fprintf(os.Stderr, "  rename [--overwrite] <source> <destination>\n")
+		fmt.Fprintf(os.Stderr, "                                   Move a file or directory to a new path\n")
+		fmt.Fprintf(os.Stderr, "  copy [--overwrite] <source> <destination>\n")
+		fmt.Fprintf(os.Stderr, "                                   Copy a file on the server, without transferring data\n")
+		fmt.Fprintf(os.Stderr, "  mkdir [--parents] <directory>    Create a directory\n")
+		fmt.Fprintf(os.Stderr, "  rmdir [--recursive] [--permanent] <directory>\n")
+		fmt.Fprintf(os.Stderr, "                                   Remove a directory (and with --recursive, its contents)\n")
+		fmt.Fprintf(os.Stderr, "  trash [--prefix P]               List deleted files waiting in the trash\n")
+		fmt.Fprintf(os.Stderr, "  undelete [--to PATH] [--parents] <trash-id | path>\n")
+		fmt.Fprintf(os.Stderr, "                                   Restore a file from the trash\n")
+		fmt.Fprintf(os.Stderr, "  watch [--prefix P] [--after SEQ] Print changes to files as they happen\n")
+		fmt.Fprintf(os.Stderr, "  versions <filename>              List a file's versions\n")
+		fmt.Fprintf(os.Stderr, "  restore <filename> <generation>  Make an old version of a file current again\n")
+		fmt.Fprintf(os.Stderr, "  setattr [--set KEY=VALUE]... [--unset KEY]... [--tag T]... [--untag T]... <path>\n")
+		fmt.Fprintf(os.Stderr, "                                   Change a file's attributes and tags\n")
+		fmt.Fprintf(os.Stderr, "  rotate-keys                      Rewrap every file's data key with the newest master key\n")
+		fmt.Fprintf(os.Stderr, "  stat [--version G] <path>        Get file or directory information\n\n")
 		fmt.Fprintf(os.Stderr, "Flags:\n")
 		flag.PrintDefaults()
 	}
@@ -52,9 +85,15 @@ func main() {
 	// grpc.Dial establishes a connection to the server.
 	// WithTransportCredentials(insecure.NewCredentials()) disables TLS.
 	// In production, you'd use proper TLS credentials!
+	var err error
+	dialOptions, err = wireDialOptions(*wireCompression)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid --wire-compression: %v\n", err)
+		os.Exit(1)
+	}
 	conn, err := grpc.NewClient(
 		*serverAddr,
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, dialOptions...)...,
 	)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Failed to connect to server: %v\n", err)
@@ -69,8 +108,17 @@ func main() {
 
 	// Create a context with timeout for all operations.
 	// Context carries deadlines and cancellation signals across API boundaries.
-	// 5 minutes is generous for large file transfers.
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	// The 5 minute default is generous for most transfers; really large
+	// ones can lift it with --timeout 0. A watch runs until interrupted,
+	// unless given a timeout explicitly.
+	timeoutSet := false
+	flag.Visit(func(f *flag.Flag) {
+		timeoutSet = timeoutSet || f.Name == "timeout"
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	if *timeout > 0 && (command != "watch" || timeoutSet) {
+		ctx, cancel = context.WithTimeout(context.Background(), *timeout)
+	}
 	defer cancel() // Release resources associated with context
 
 	// Route to the appropriate command handler
@@ -80,12 +128,36 @@ func main() {
 		cmdErr = handleUpload(ctx, client, cmdArgs)
 	case "download":
 		cmdErr = handleDownload(ctx, client, cmdArgs)
+	case "cat":
+		cmdErr = handleCat(ctx, client, cmdArgs)
 	case "list":
 		cmdErr = handleList(ctx, client, cmdArgs)
 	case "delete":
 		cmdErr = handleDelete(ctx, client, cmdArgs)
+	case "rename":
+		cmdErr = handleRename(ctx, client, cmdArgs)
+	case "copy":
+		cmdErr = handleCopy(ctx, client, cmdArgs)
+	case "mkdir":
+		cmdErr = handleMkdir(ctx, client, cmdArgs)
+	case "rmdir":
+		cmdErr = handleRmdir(ctx, client, cmdArgs)
 	case "stat":
 		cmdErr = handleStat(ctx, client, cmdArgs)
+	case "setattr":
+		cmdErr = handleSetAttributes(ctx, client, cmdArgs)
+	case "versions":
+		cmdErr = handleVersions(ctx, client, cmdArgs)
+	case "restore":
+		cmdErr = handleRestore(ctx, client, cmdArgs)
+	case "trash":
+		cmdErr = handleTrash(ctx, client, cmdArgs)
+	case "undelete":
+		cmdErr = handleUndelete(ctx, client, cmdArgs)
+	case "watch":
+		cmdErr = handleWatch(ctx, client, cmdArgs)
+	case "rotate-keys":
+		cmdErr = handleRotateKeys(ctx, client, cmdArgs)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n", command)
 		flag.Usage()
@@ -98,243 +170,322 @@ func main() {
 	}
 }
 
-// handleUpload uploads a local file to the DFS.
-func handleUpload(ctx context.Context, client api.FileServiceClient, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: upload <local-file>")
+// handleList lists files in the DFS.
+//
+// By default it lists a directory's entries, like ls; --recursive
+// includes everything below it. --prefix instead matches every path
+// starting with the prefix, whichever directory it is in. Big listings
+// are fetched from the server a page at a time.
+//
+// The filters are applied by the server, so only matching entries are
+// sent: --glob "*.gz" matches names, --glob "logs/2026-*/*.gz" paths
+// below the directory listed (which takes --recursive). Sizes take K, M
+// and G suffixes; times are dates, RFC 3339 timestamps, or durations ago
+// like 24h or 7d.
+func handleList(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	recursive := fs.Bool("recursive", false, "List everything below the directory, not just its entries")
+	prefix := fs.String("prefix", "", "List all paths starting with this prefix instead of a directory")
+	sortBy := fs.String("sort", "name", "Sort by 'name', 'size' or 'modified'")
+	desc := fs.Bool("desc", false, "Sort in descending order")
+	pageSize := fs.Int("page-size", 0, "Entries to fetch per request (0 for the server default)")
+	glob := fs.String("glob", "", "Only entries whose name (or with a '/', path) matches this pattern")
+	regex := fs.String("regex", "", "Only entries whose path matches this regular expression")
+	minSize := fs.String("min-size", "", "Only files at least this big (e.g. 10M)")
+	maxSize := fs.String("max-size", "", "Only files at most this big")
+	since := fs.String("since", "", "Only entries modified since this time (e.g. 2026-01-02 or 24h)")
+	before := fs.String("before", "", "Only entries modified before this time")
+	attributes := attributeFlag(fs, "attr", "Only entries with this attribute, as KEY=VALUE, or KEY for any value (repeatable)", true)
+	tags := listFlag(fs, "tag", "Only entries with this tag (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	sortOrders := map[string]api.ListSort{
+		"name":     api.ListSort_LIST_SORT_NAME,
+		"size":     api.ListSort_LIST_SORT_SIZE,
+		"modified": api.ListSort_LIST_SORT_MODIFIED,
+	}
+	sort, ok := sortOrders[*sortBy]
+	if !ok {
+		return fmt.Errorf("unknown sort order %q (want name, size or modified)", *sortBy)
 	}
 
-	localPath := args[0]
-
-	// Open the local file
-	file, err := os.Open(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+	req := &api.ListRequest{
+		Prefix:     *prefix,
+		PageSize:   int32(*pageSize),
+		Sort:       sort,
+		Descending: *desc,
+		Glob:       *glob,
+		Regex:      *regex,
+		Attributes: attributes,
+		Tags:       *tags,
 	}
-	defer file.Close()
-
-	// Get file info for metadata
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+	var err error
+	if req.MinSize, err = parseSize(*minSize); err != nil {
+		return err
 	}
-
-	// Start the upload stream.
-	// Upload returns a stream we can send messages on.
-	stream, err := client.Upload(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to start upload: %w", err)
+	if req.MaxSize, err = parseSize(*maxSize); err != nil {
+		return err
 	}
-
-	// Send metadata as first message
-	if err := stream.Send(&api.UploadRequest{
-		Data: &api.UploadRequest_Metadata{
-			Metadata: &api.FileMetadata{
-				Filename: filepath.Base(localPath), // Use just the filename, not full path
-				Size:     fileInfo.Size(),
-			},
-		},
-	}); err != nil {
-		return fmt.Errorf("failed to send metadata: %w", err)
+	if req.ModifiedAfter, err = parseTime(*since); err != nil {
+		return err
+	}
+	if req.ModifiedBefore, err = parseTime(*before); err != nil {
+		return err
+	}
+	if *prefix == "" {
+		req.Directory = "/"
+		if len(args) > 0 {
+			req.Directory = args[0]
+		}
+		req.Recursive = *recursive
 	}
 
-	// Stream file data in chunks
-	buf := make([]byte, chunkSize)
-	var totalSent int64
-
+	// Fetch page after page, printing each as it arrives, so even a huge
+	// listing starts showing up straight away
+	total := 0
 	for {
-		n, err := file.Read(buf)
-		if err == io.EOF {
-			break
-		}
+		resp, err := client.List(ctx, req)
 		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
+			return fmt.Errorf("failed to list files: %w", err)
 		}
 
-		// Send chunk
-		if err := stream.Send(&api.UploadRequest{
-			Data: &api.UploadRequest_Chunk{
-				Chunk: buf[:n],
-			},
-		}); err != nil {
-			return fmt.Errorf("failed to send chunk: %w", err)
+		if total == 0 && len(resp.Files) > 0 {
+			// Print header
+			fmt.Printf("%-40s %15s %20s\n", "FILENAME", "SIZE", "MODIFIED")
+			fmt.Println(repeat("-", 77))
 		}
 
-		totalSent += int64(n)
-
-		// Print progress (simple progress indicator)
-		progress := float64(totalSent) / float64(fileInfo.Size()) * 100
-		fmt.Printf("\rUploading... %.1f%%", progress)
-	}
+		// Print each file; directories get a trailing slash and no size
+		for _, f := range resp.Files {
+			modTime := time.Unix(f.ModifiedAt, 0).Format("2006-01-02 15:04:05")
+			name, size := f.Filename, formatSize(f.Size)
+			if f.IsDir {
+				name, size = name+"/", "-"
+			}
+			fmt.Printf("%-40s %15s %20s\n", name, size, modTime)
+		}
+		total += len(resp.Files)
 
-	// Close the stream and get the response.
-	// CloseAndRecv() signals we're done sending and waits for server response.
-	resp, err := stream.CloseAndRecv()
-	if err != nil {
-		return fmt.Errorf("upload failed: %w", err)
+		if resp.NextPageToken == "" {
+			break
+		}
+		req.PageToken = resp.NextPageToken
 	}
 
-	fmt.Printf("\r") // Clear progress line
-	if resp.Success {
-		fmt.Printf("Uploaded '%s' successfully (%d bytes)\n", filepath.Base(localPath), totalSent)
-	} else {
-		return fmt.Errorf("server error: %s", resp.Message)
+	if total == 0 {
+		fmt.Println("No files found")
+		return nil
 	}
+	fmt.Printf("\nTotal: %d file(s)\n", total)
 
 	return nil
 }
 
-// handleDownload downloads a file from the DFS to local filesystem.
-func handleDownload(ctx context.Context, client api.FileServiceClient, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: download <remote-file> [local-path]")
+// handleDelete deletes a file from the DFS.
+func handleDelete(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	permanent := fs.Bool("permanent", false, "Delete the file for good instead of moving it to the trash")
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
+	args = fs.Args()
 
-	remoteFile := args[0]
-	localPath := remoteFile // Default: same name as remote
-	if len(args) > 1 {
-		localPath = args[1]
+	if len(args) < 1 {
+		return fmt.Errorf("usage: delete [--permanent] <filename>")
 	}
 
-	// Start the download stream
-	stream, err := client.Download(ctx, &api.DownloadRequest{
-		Filename: remoteFile,
+	filename := args[0]
+
+	resp, err := client.Delete(ctx, &api.DeleteRequest{
+		Filename:  filename,
+		Permanent: *permanent,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to start download: %w", err)
+		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
-	// Receive the first message (should be metadata)
-	resp, err := stream.Recv()
-	if err != nil {
-		return fmt.Errorf("failed to receive metadata: %w", err)
+	switch {
+	case resp.Success && resp.TrashId != "":
+		fmt.Printf("Moved '%s' to the trash (id %s)\n", filename, resp.TrashId)
+	case resp.Success:
+		fmt.Printf("Deleted '%s'\n", filename)
+	default:
+		fmt.Printf("Failed to delete: %s\n", resp.Message)
 	}
 
-	metadata, ok := resp.Data.(*api.DownloadResponse_Metadata)
-	if !ok {
-		return fmt.Errorf("expected metadata, got chunk")
+	return nil
+}
+
+// handleRename moves a file or directory to a new path. A destination
+// ending in a slash means "into this directory, keeping the name".
+func handleRename(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("rename", flag.ContinueOnError)
+	overwrite := fs.Bool("overwrite", false, "Replace the destination if it is an existing file")
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
+	args = fs.Args()
 
-	fileSize := metadata.Metadata.Size
+	if len(args) < 2 {
+		return fmt.Errorf("usage: rename [--overwrite] <source> <destination>")
+	}
 
-	// Create local file
-	file, err := os.Create(localPath)
-	if err != nil {
-		return fmt.Errorf("failed to create local file: %w", err)
+	source, destination := args[0], args[1]
+	if strings.HasSuffix(destination, "/") {
+		destination += path.Base(source)
 	}
-	defer file.Close()
 
-	// Receive and write chunks
-	var totalReceived int64
-	for {
-		resp, err := stream.Recv()
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			// Clean up partial file on error
-			os.Remove(localPath)
-			return fmt.Errorf("download failed: %w", err)
-		}
+	resp, err := client.Rename(ctx, &api.RenameRequest{
+		Source:      source,
+		Destination: destination,
+		Overwrite:   *overwrite,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to rename: %w", err)
+	}
 
-		chunk, ok := resp.Data.(*api.DownloadResponse_Chunk)
-		if !ok {
-			continue // Skip non-chunk messages
-		}
+	fmt.Println(resp.Message)
+	return nil
+}
 
-		n, err := file.Write(chunk.Chunk)
-		if err != nil {
-			os.Remove(localPath)
-			return fmt.Errorf("failed to write to file: %w", err)
-		}
+// handleCopy copies a file on the server. As with rename, a destination
+// ending in a slash means "into this directory, keeping the name".
+func handleCopy(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("copy", flag.ContinueOnError)
+	overwrite := fs.Bool("overwrite", false, "Replace the destination if it is an existing file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
 
-		totalReceived += int64(n)
+	if len(args) < 2 {
+		return fmt.Errorf("usage: copy [--overwrite] <source> <destination>")
+	}
 
-		// Print progress
-		progress := float64(totalReceived) / float64(fileSize) * 100
-		fmt.Printf("\rDownloading... %.1f%%", progress)
+	source, destination := args[0], args[1]
+	if strings.HasSuffix(destination, "/") {
+		destination += path.Base(source)
 	}
 
-	fmt.Printf("\r") // Clear progress line
-	fmt.Printf("Downloaded '%s' to '%s' (%d bytes)\n", remoteFile, localPath, totalReceived)
+	resp, err := client.Copy(ctx, &api.CopyRequest{
+		Source:      source,
+		Destination: destination,
+		Overwrite:   *overwrite,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to copy: %w", err)
+	}
 
+	fmt.Println(resp.Message)
 	return nil
 }
 
-// handleList lists files in the DFS.
-func handleList(ctx context.Context, client api.FileServiceClient, args []string) error {
-	prefix := ""
-	if len(args) > 0 {
-		prefix = args[0]
+// handleMkdir creates a directory in the DFS.
+func handleMkdir(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("mkdir", flag.ContinueOnError)
+	parents := fs.Bool("parents", false, "Create missing parent directories, and don't fail if it exists")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: mkdir [--parents] <directory>")
 	}
 
-	resp, err := client.List(ctx, &api.ListRequest{
-		Prefix: prefix,
+	resp, err := client.Mkdir(ctx, &api.MkdirRequest{
+		Path:    args[0],
+		Parents: *parents,
 	})
 	if err != nil {
-		return fmt.Errorf("failed to list files: %w", err)
+		return fmt.Errorf("failed to create directory: %w", err)
 	}
 
-	if len(resp.Files) == 0 {
-		fmt.Println("No files found")
-		return nil
-	}
+	fmt.Println(resp.Message)
+	return nil
+}
 
-	// Print header
-	fmt.Printf("%-40s %15s %20s\n", "FILENAME", "SIZE", "MODIFIED")
-	fmt.Println(repeat("-", 77))
+// handleRmdir removes a directory from the DFS.
+func handleRmdir(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("rmdir", flag.ContinueOnError)
+	recursive := fs.Bool("recursive", false, "Also delete everything in the directory")
+	permanent := fs.Bool("permanent", false, "Delete the files for good instead of moving them to the trash")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
 
-	// Print each file
-	for _, f := range resp.Files {
-		modTime := time.Unix(f.ModifiedAt, 0).Format("2006-01-02 15:04:05")
-		fmt.Printf("%-40s %15s %20s\n", f.Filename, formatSize(f.Size), modTime)
+	if len(args) < 1 {
+		return fmt.Errorf("usage: rmdir [--recursive] [--permanent] <directory>")
 	}
 
-	fmt.Printf("\nTotal: %d file(s)\n", len(resp.Files))
+	resp, err := client.Rmdir(ctx, &api.RmdirRequest{
+		Path:      args[0],
+		Recursive: *recursive,
+		Permanent: *permanent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove directory: %w", err)
+	}
 
+	fmt.Println(resp.Message)
+	switch {
+	case resp.FilesDeleted > 0 && *permanent:
+		fmt.Printf("Deleted %d file(s)\n", resp.FilesDeleted)
+	case resp.FilesDeleted > 0:
+		fmt.Printf("Moved %d file(s) to the trash\n", resp.FilesDeleted)
+	}
 	return nil
 }
 
-// handleDelete deletes a file from the DFS.
-func handleDelete(ctx context.Context, client api.FileServiceClient, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: delete <filename>")
+// handleRotateKeys has the master rewrap every file's data key with its
+// active master key. Run it after adding a new master key; once it reports
+// everything rewrapped, the old master key can be removed.
+func handleRotateKeys(ctx context.Context, client api.FileServiceClient, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: rotate-keys")
 	}
 
-	filename := args[0]
-
-	resp, err := client.Delete(ctx, &api.DeleteRequest{
-		Filename: filename,
-	})
+	resp, err := client.RotateKeys(ctx, &api.RotateKeysRequest{})
 	if err != nil {
-		return fmt.Errorf("failed to delete file: %w", err)
-	}
-
-	if resp.Success {
-		fmt.Printf("Deleted '%s'\n", filename)
-	} else {
-		fmt.Printf("Failed to delete: %s\n", resp.Message)
+		return fmt.Errorf("failed to rotate keys: %w", err)
 	}
 
+	fmt.Println(resp.Message)
 	return nil
 }
 
 // handleStat gets information about a file.
 func handleStat(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("stat", flag.ContinueOnError)
+	version := fs.Int64("version", 0, "Describe this old version (a generation) instead of the current one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
 	if len(args) < 1 {
-		return fmt.Errorf("usage: stat <filename>")
+		return fmt.Errorf("usage: stat [--version G] <path>")
 	}
 
 	filename := args[0]
 
 	resp, err := client.Stat(ctx, &api.StatRequest{
 		Filename: filename,
+		Version:  *version,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to get file info: %w", err)
 	}
 
 	if !resp.Exists {
+		if *version != 0 {
+			fmt.Printf("Version %d of '%s' does not exist\n", *version, filename)
+			return nil
+		}
 		fmt.Printf("File '%s' does not exist\n", filename)
 		return nil
 	}
@@ -344,13 +495,59 @@ func handleStat(ctx context.Context, client api.FileServiceClient, args []string
 	modified := time.Unix(f.ModifiedAt, 0).Format("2006-01-02 15:04:05")
 
 	fmt.Printf("Filename: %s\n", f.Filename)
+	if f.IsDir {
+		fmt.Printf("Type:     directory\n")
+		if f.CreatedAt > 0 { // The root has no creation time
+			fmt.Printf("Created:  %s\n", created)
+		}
+		printAttributes(f)
+		return nil
+	}
 	fmt.Printf("Size:     %s (%d bytes)\n", formatSize(f.Size), f.Size)
+	switch {
+	case f.StoredSize < f.Size:
+		fmt.Printf("Stored:   %s (%d bytes, %.1fx compressed)\n", formatSize(f.StoredSize), f.StoredSize, float64(f.Size)/float64(max(f.StoredSize, 1)))
+	case f.StoredSize > f.Size: // Encryption adds a little
+		fmt.Printf("Stored:   %s (%d bytes)\n", formatSize(f.StoredSize), f.StoredSize)
+	}
+	if f.KeyId != "" {
+		fmt.Printf("Key:      %s (encrypted at rest)\n", f.KeyId)
+	}
 	fmt.Printf("Created:  %s\n", created)
 	fmt.Printf("Modified: %s\n", modified)
+	fmt.Printf("Replicas: %d\n", f.Replication)
+	fmt.Printf("Gen:      %d\n", f.Generation)
+	if f.SupersededAt > 0 {
+		fmt.Printf("Replaced: %s\n", time.Unix(f.SupersededAt, 0).Format("2006-01-02 15:04:05"))
+	}
+	if f.Checksum != "" {
+		fmt.Printf("SHA-256:  %s\n", f.Checksum)
+	}
+	printAttributes(f)
+
+	if len(resp.Chunks) > 0 {
+		fmt.Printf("\n%-34s %12s %8s %5s  %s\n", "CHUNK", "SIZE", "HEALTHY", "REFS", "LOCATIONS")
+		for _, c := range resp.Chunks {
+			health := fmt.Sprintf("%d/%d", c.HealthyReplicas, f.Replication)
+			fmt.Printf("%-34s %12s %8s %5d  %s\n", c.ChunkId, formatSize(c.Size), health, c.Refs, strings.Join(c.Addresses, ","))
+		}
+	}
 
 	return nil
 }
 
+// sha256File returns the hex SHA-256 of a file's contents and rewinds it.
+func sha256File(file *os.File) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
 // formatSize formats bytes into human-readable format.
 func formatSize(bytes int64) string {
 	const (
@@ -371,6 +568,56 @@ func formatSize(bytes int64) string {
 	}
 }
 
+// parseSize parses a size in bytes, with an optional K, M or G suffix
+// (powers of 1024, as formatSize prints them): "512", "10M", "1.5GB".
+// An empty string is 0.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+
+	number := strings.TrimSuffix(strings.ToUpper(s), "B")
+	multiplier := int64(1)
+	for suffix, m := range map[string]int64{"K": 1 << 10, "M": 1 << 20, "G": 1 << 30} {
+		if strings.HasSuffix(number, suffix) {
+			number, multiplier = strings.TrimSuffix(number, suffix), m
+			break
+		}
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(multiplier)), nil
+}
+
+// parseTime parses a point in time as a Unix timestamp: a date
+// ("2026-01-02", midnight local time), an RFC 3339 timestamp, or a
+// duration before now ("90m", "24h", "7d"). An empty string is 0.
+func parseTime(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t.Unix(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.Unix(), nil
+	}
+
+	// time.ParseDuration stops at hours; days are handy here
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return time.Now().AddDate(0, 0, -n).Unix(), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return time.Now().Add(-d).Unix(), nil
+	}
+	return 0, fmt.Errorf("invalid time %q: want a date, an RFC 3339 timestamp or a duration like 24h", s)
+}
+
 // repeat returns a string with char repeated n times.
 func repeat(char string, n int) string {
 	result := ""
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// handleTrash lists the deleted files the server is still keeping, most
+// recently deleted first, with when each will be purged.
+func handleTrash(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("trash", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "Only list files whose original path starts with this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	resp, err := client.ListTrash(ctx, &api.ListTrashRequest{
+		Prefix: *prefix,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	if len(resp.Entries) == 0 {
+		fmt.Println("The trash is empty")
+		return nil
+	}
+
+	fmt.Printf("%-16s %15s %20s %20s  %s\n", "ID", "SIZE", "DELETED", "PURGED AFTER", "PATH")
+	fmt.Println(repeat("-", 100))
+
+	for _, entry := range resp.Entries {
+		deleted := time.Unix(entry.DeletedAt, 0).Format("2006-01-02 15:04:05")
+		purge := time.Unix(entry.PurgeAt, 0).Format("2006-01-02 15:04:05")
+		name := entry.File.Filename
+		if entry.Versions > 0 {
+			name = fmt.Sprintf("%s (+%d versions)", name, entry.Versions)
+		}
+		fmt.Printf("%-16s %15s %20s %20s  %s\n", entry.Id, formatSize(entry.File.Size), deleted, purge, name)
+	}
+
+	fmt.Printf("\nTotal: %d file(s)\n", len(resp.Entries))
+
+	return nil
+}
+
+// handleUndelete restores a file from the trash. It takes a trash ID, or
+// a path, which means the most recently deleted file at that path.
+func handleUndelete(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("undelete", flag.ContinueOnError)
+	to := fs.String("to", "", "Restore the file to this path instead of its original one")
+	parents := fs.Bool("parents", false, "Create missing parent directories")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: undelete [--to PATH] [--parents] <trash-id | path>")
+	}
+
+	id := args[0]
+	if strings.Contains(id, "/") {
+		var err error
+		if id, err = findInTrash(ctx, client, id); err != nil {
+			return err
+		}
+	}
+
+	resp, err := client.Undelete(ctx, &api.UndeleteRequest{
+		Id:          id,
+		Destination: *to,
+		Parents:     *parents,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to undelete: %w", err)
+	}
+
+	fmt.Println(resp.Message)
+	return nil
+}
+
+// findInTrash returns the ID of the most recently deleted file at a path.
+func findInTrash(ctx context.Context, client api.FileServiceClient, filename string) (string, error) {
+	resp, err := client.ListTrash(ctx, &api.ListTrashRequest{
+		Prefix: filename,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	// Entries come most recently deleted first
+	for _, entry := range resp.Entries {
+		if entry.File.Filename == filename {
+			return entry.Id, nil
+		}
+	}
+	return "", fmt.Errorf("%s is not in the trash", filename)
+}
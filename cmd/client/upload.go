@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// uploadStateSuffix names the file, next to the local file, where an
+// upload in progress records its session.
+const uploadStateSuffix = ".godfs-upload"
+
+// uploadState is what we remember about an upload in progress, so that
+// running the same upload again resumes it. The size, modification time
+// and checksum identify the local file's contents: if any of them changed,
+// the old session is for different data and must not be resumed.
+type uploadState struct {
+	SessionID string    `json:"session_id"`
+	Filename  string    `json:"filename"`
+	Size      int64     `json:"size"`
+	ModTime   time.Time `json:"mod_time"`
+	Checksum  string    `json:"checksum"`
+}
+
+// handleUpload uploads a local file to the DFS.
+//
+// The file is stored under the remote path if one is given - ending it in
+// a slash keeps the local name, in that directory - and at the top level
+// under its local name otherwise. The directory must exist unless
+// --parents is set.
+//
+// Uploads go through a resumable session. If the connection drops, the
+// upload asks the master how much it has and carries on from there, up to
+// --retries times. The session is also saved next to the local file, so
+// if the client itself dies, running the same command again resumes it.
+func handleUpload(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ContinueOnError)
+	replication := fs.Int("replication", 0, "Number of replicas to store (0 uses the cluster default)")
+	overwrite := fs.Bool("overwrite", false, "Replace the file if it already exists")
+	ifGeneration := fs.Int64("if-generation", -1, "Only write if the file is at this generation (0: only if it doesn't exist)")
+	retries := fs.Int("retries", 5, "How many times to resume after a failure before giving up")
+	parents := fs.Bool("parents", false, "Create missing directories on the remote path")
+	attributes := attributeFlag(fs, "attr", "Attach an attribute, as KEY=VALUE (repeatable)", false)
+	tags := listFlag(fs, "tag", "Attach a tag (repeatable)")
+	chunkingName := fs.String("chunking", "", "Cut the file into chunks at 'fixed' or content-defined ('cdc') boundaries (default: the server's)")
+	compressName := fs.String("compress", "", "Compress the file at rest with 'zstd', 'gzip' or 'none' (default: the server's rules)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	args = fs.Args()
+
+	if len(args) < 1 {
+		return fmt.Errorf("usage: upload [--replication N] [--overwrite | --if-generation G] [--retries N] [--parents] [--chunking fixed|cdc] [--compress zstd|gzip|none] [--attr KEY=VALUE]... [--tag T]... <local-file> [remote-path]")
+	}
+
+	chunkingModes := map[string]api.ChunkingMode{
+		"":      api.ChunkingMode_CHUNKING_MODE_DEFAULT,
+		"fixed": api.ChunkingMode_CHUNKING_MODE_FIXED,
+		"cdc":   api.ChunkingMode_CHUNKING_MODE_CONTENT_DEFINED,
+	}
+	chunking, ok := chunkingModes[*chunkingName]
+	if !ok {
+		return fmt.Errorf("unknown chunking mode %q (want 'fixed' or 'cdc')", *chunkingName)
+	}
+	compressions := map[string]api.Compression{
+		"":     api.Compression_COMPRESSION_DEFAULT,
+		"none": api.Compression_COMPRESSION_NONE,
+		"gzip": api.Compression_COMPRESSION_GZIP,
+		"zstd": api.Compression_COMPRESSION_ZSTD,
+	}
+	compress, ok := compressions[*compressName]
+	if !ok {
+		return fmt.Errorf("unknown compression %q (want 'zstd', 'gzip' or 'none')", *compressName)
+	}
+
+	mode := api.WriteMode_WRITE_MODE_CREATE
+	switch {
+	case *overwrite && *ifGeneration >= 0:
+		return fmt.Errorf("--overwrite and --if-generation are mutually exclusive")
+	case *overwrite:
+		mode = api.WriteMode_WRITE_MODE_OVERWRITE
+	case *ifGeneration >= 0:
+		mode = api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH
+	}
+
+	localPath := args[0]
+	remotePath := filepath.Base(localPath) // Use just the filename, not full path
+	if len(args) > 1 {
+		remotePath = args[1]
+		if strings.HasSuffix(remotePath, "/") {
+			remotePath += filepath.Base(localPath)
+		}
+	}
+
+	// Open the local file
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	// Get file info for metadata
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	// Hash the file up front so the server can verify it received exactly
+	// what we meant to send. This reads the file twice, but the second read
+	// usually comes from the page cache.
+	checksum, err := sha256File(file)
+	if err != nil {
+		return err
+	}
+
+	metadata := &api.FileMetadata{
+		Filename:     remotePath,
+		Size:         fileInfo.Size(),
+		Replication:  int32(*replication),
+		Checksum:     checksum,
+		Mode:         mode,
+		IfGeneration: max(*ifGeneration, 0),
+		Parents:      *parents,
+		Attributes:   attributes,
+		Tags:         *tags,
+		Chunking:     chunking,
+		Compression:  compress,
+	}
+	want := uploadState{
+		Filename: metadata.Filename,
+		Size:     fileInfo.Size(),
+		ModTime:  fileInfo.ModTime(),
+		Checksum: checksum,
+	}
+
+	// Resume the previous attempt's session if there is one for this data
+	statePath := localPath + uploadStateSuffix
+	sessionID, offset, err := resumeUpload(ctx, client, statePath, want)
+	if err != nil {
+		return err
+	}
+	if sessionID == "" {
+		resp, err := client.StartUpload(ctx, &api.StartUploadRequest{Metadata: metadata})
+		if err != nil {
+			return fmt.Errorf("failed to start upload: %w", err)
+		}
+		sessionID = resp.SessionId
+
+		want.SessionID = sessionID
+		if err := saveUploadState(statePath, want); err != nil {
+			// Not fatal: we can still upload, just not resume across runs
+			fmt.Fprintf(os.Stderr, "Warning: failed to save upload state: %v\n", err)
+		}
+	} else {
+		fmt.Printf("Resuming upload at %s of %s\n", formatSize(offset), formatSize(fileInfo.Size()))
+	}
+
+	for attempt := 1; ; attempt++ {
+		offset, err = sendUpload(ctx, client, sessionID, file, offset, fileInfo.Size())
+		if err == nil {
+			break
+		}
+		if attempt > *retries || !retryable(ctx, err) {
+			return fmt.Errorf("upload failed: %w", err)
+		}
+
+		// Back off a little more each time, so a server that is
+		// struggling isn't hammered.
+		fmt.Printf("\nUpload interrupted (%v), retrying in %ds...\n", err, attempt)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("upload failed: %w", ctx.Err())
+		case <-time.After(time.Duration(attempt) * time.Second):
+		}
+
+		// Whatever was in flight may or may not have been committed
+		q, err := client.QueryUpload(ctx, &api.QueryUploadRequest{SessionId: sessionID})
+		if err != nil {
+			if retryable(ctx, err) {
+				continue // Resend from our last known offset; the server skips what it has
+			}
+			return fmt.Errorf("failed to query upload: %w", err)
+		}
+		offset = q.CommittedOffset
+	}
+
+	resp, err := client.FinishUpload(ctx, &api.FinishUploadRequest{SessionId: sessionID})
+	if err != nil {
+		// A rejected commit (say, the generation changed) ends the session
+		if !retryable(ctx, err) {
+			os.Remove(statePath)
+		}
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	os.Remove(statePath)
+
+	fmt.Printf("\r") // Clear progress line
+	if resp.Success {
+		fmt.Printf("Uploaded '%s' successfully (%d bytes, generation %d)\n", resp.FileId, fileInfo.Size(), resp.Generation)
+		if resp.DedupChunks > 0 {
+			fmt.Printf("Deduplicated %d of %d chunks (%s already stored)\n", resp.DedupChunks, resp.Chunks, formatSize(resp.DedupBytes))
+		}
+		wire.report(true)
+	} else {
+		return fmt.Errorf("server error: %s", resp.Message)
+	}
+
+	return nil
+}
+
+// resumeUpload looks for a saved session for the same local data and asks
+// the master how far it got. It returns an empty session ID if there is
+// nothing to resume.
+func resumeUpload(ctx context.Context, client api.FileServiceClient, statePath string, want uploadState) (string, int64, error) {
+	data, err := os.ReadFile(statePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read upload state: %w", err)
+	}
+
+	var state uploadState
+	if err := json.Unmarshal(data, &state); err != nil || state.SessionID == "" {
+		// Unreadable state is as good as none
+		return "", 0, nil
+	}
+	if state.Filename != want.Filename || state.Size != want.Size ||
+		!state.ModTime.Equal(want.ModTime) || state.Checksum != want.Checksum {
+		// The local file changed since: that session has the wrong data
+		return "", 0, nil
+	}
+
+	resp, err := client.QueryUpload(ctx, &api.QueryUploadRequest{SessionId: state.SessionID})
+	if status.Code(err) == codes.NotFound {
+		// Expired, finished, or the master restarted: start over
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to query upload: %w", err)
+	}
+	return state.SessionID, resp.CommittedOffset, nil
+}
+
+// saveUploadState records an upload's session for a later resume.
+func saveUploadState(path string, state uploadState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// sendUpload streams the file from offset to the end into an upload
+// session and returns the offset the server has committed.
+func sendUpload(ctx context.Context, client api.FileServiceClient, sessionID string, file *os.File, offset, size int64) (int64, error) {
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return offset, fmt.Errorf("failed to seek: %w", err)
+	}
+
+	stream, err := client.WriteUpload(ctx)
+	if err != nil {
+		return offset, err
+	}
+
+	// The first message says which session and where the data starts
+	if err := stream.Send(&api.WriteUploadRequest{
+		SessionId: sessionID,
+		Offset:    offset,
+	}); err != nil {
+		return offset, err
+	}
+
+	// Stream file data in chunks
+	buf := make([]byte, chunkSize)
+	totalSent := offset
+
+	for {
+		n, err := file.Read(buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return offset, fmt.Errorf("failed to read file: %w", err)
+		}
+
+		if err := stream.Send(&api.WriteUploadRequest{
+			Data: buf[:n],
+		}); err != nil {
+			// Send returns io.EOF when the server ended the stream early;
+			// the real reason is only available from CloseAndRecv.
+			if err == io.EOF {
+				_, err = stream.CloseAndRecv()
+			}
+			return offset, err
+		}
+
+		totalSent += int64(n)
+
+		// Print progress (simple progress indicator)
+		progress := float64(totalSent) / float64(max(size, 1)) * 100
+		fmt.Printf("\rUploading... %.1f%%", progress)
+	}
+
+	// Close the stream and get the response.
+	// CloseAndRecv() signals we're done sending and waits for server response.
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return offset, err
+	}
+	return resp.CommittedOffset, nil
+}
+
+// retryable reports whether an upload error is worth resuming after.
+// Network trouble and server-side failures are; the server rejecting the
+// request itself, or running out of time, are not.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.FailedPrecondition, codes.OutOfRange, codes.DataLoss,
+		codes.PermissionDenied, codes.Unimplemented:
+		return false
+	}
+	return true
+}
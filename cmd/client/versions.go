@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// handleVersions lists the versions of a file the server keeps, newest
+// first. Any of them can be read with --version, or restored.
+func handleVersions(ctx context.Context, client api.FileServiceClient, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: versions <filename>")
+	}
+
+	resp, err := client.ListVersions(ctx, &api.ListVersionsRequest{
+		Filename: args[0],
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list versions: %w", err)
+	}
+
+	fmt.Printf("%6s %15s %20s %20s  %s\n", "GEN", "SIZE", "WRITTEN", "REPLACED", "SHA-256")
+	fmt.Println(repeat("-", 77))
+
+	for _, v := range resp.Versions {
+		written := time.Unix(v.ModifiedAt, 0).Format("2006-01-02 15:04:05")
+		replaced := "(current)"
+		if v.SupersededAt > 0 {
+			replaced = time.Unix(v.SupersededAt, 0).Format("2006-01-02 15:04:05")
+		}
+		checksum := v.Checksum
+		if len(checksum) > 12 {
+			checksum = checksum[:12]
+		}
+		fmt.Printf("%6d %15s %20s %20s  %s\n", v.Generation, formatSize(v.Size), written, replaced, checksum)
+	}
+
+	fmt.Printf("\nTotal: %d version(s)\n", len(resp.Versions))
+
+	return nil
+}
+
+// handleRestore makes an old version of a file current again.
+func handleRestore(ctx context.Context, client api.FileServiceClient, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: restore <filename> <generation>")
+	}
+
+	version, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || version <= 0 {
+		return fmt.Errorf("invalid generation %q", args[1])
+	}
+
+	resp, err := client.Restore(ctx, &api.RestoreRequest{
+		Filename: args[0],
+		Version:  version,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore: %w", err)
+	}
+
+	fmt.Println(resp.Message)
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// maxWatchBackoff caps the wait between attempts to reconnect a watch.
+const maxWatchBackoff = 30 * time.Second
+
+// watchEventNames are how events are printed. One a newer master sends
+// that isn't here is printed by its proto name.
+var watchEventNames = map[api.WatchEventType]string{
+	api.WatchEventType_WATCH_EVENT_CREATE:     "create",
+	api.WatchEventType_WATCH_EVENT_UPDATE:     "update",
+	api.WatchEventType_WATCH_EVENT_DELETE:     "delete",
+	api.WatchEventType_WATCH_EVENT_RENAME:     "rename",
+	api.WatchEventType_WATCH_EVENT_ATTRIBUTES: "attributes",
+}
+
+// handleWatch prints changes to the namespace as they happen, one line per
+// event, until interrupted. The first column is the event's sequence
+// number: passing the last one printed to --after picks up where an
+// earlier watch left off.
+//
+// If the connection drops, it reconnects and resumes from the last event
+// it printed, so nothing is missed. If the server no longer has the events
+// needed to resume - it restarted, or we were away too long - it stops
+// with an error, and the caller should re-list before watching again.
+func handleWatch(ctx context.Context, client api.FileServiceClient, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ContinueOnError)
+	prefix := fs.String("prefix", "", "Only show events for paths starting with this")
+	after := fs.Uint64("after", 0, "Resume after this event's sequence number (default: start with the next change)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	req := &api.WatchRequest{
+		Prefix:        *prefix,
+		AfterSequence: *after,
+	}
+	for attempt := 1; ; attempt++ {
+		received, err := watch(ctx, client, req)
+		if received {
+			attempt = 1 // The connection worked for a while; start backing off afresh
+		}
+		if err != io.EOF && !retryable(ctx, err) {
+			return fmt.Errorf("watch failed: %w", err)
+		}
+
+		backoff := min(time.Duration(attempt)*time.Second, maxWatchBackoff)
+		fmt.Fprintf(os.Stderr, "Watch interrupted (%v), reconnecting in %v...\n", err, backoff)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("watch failed: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// watch streams events until the stream breaks, advancing
+// req.AfterSequence past each event printed. It reports whether any
+// events arrived.
+func watch(ctx context.Context, client api.FileServiceClient, req *api.WatchRequest) (bool, error) {
+	stream, err := client.Watch(ctx, req)
+	if err != nil {
+		return false, err
+	}
+
+	received := false
+	for {
+		e, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+		req.AfterSequence = e.Sequence
+
+		when := time.Unix(e.Time, 0).Format("2006-01-02 15:04:05")
+		name := e.Path
+		if e.File != nil && e.File.IsDir {
+			name += "/"
+		}
+		if e.OldPath != "" {
+			name = fmt.Sprintf("%s -> %s", e.OldPath, name)
+		}
+		eventName, ok := watchEventNames[e.Type]
+		if !ok {
+			eventName = e.Type.String()
+		}
+		fmt.Printf("%d %s %-10s %s\n", e.Sequence, when, eventName, name)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/stats"
+
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+// Wire compression: with --wire-compression, every RPC message the client
+// sends is compressed, and servers compress their replies to match. It
+// costs CPU at both ends, so it pays where the network is the bottleneck -
+// across regions, say - and on data that compresses, like text.
+
+// wireStats counts the bytes of RPC messages: their size, and what they
+// took up on the wire, compressed. It is a gRPC stats handler.
+type wireStats struct {
+	sent, sentWire         atomic.Int64
+	received, receivedWire atomic.Int64
+
+	// codec is the compression asked for, if any.
+	codec string
+}
+
+// wire counts for every connection the command makes, so transfers can
+// report the compression they got.
+var wire = &wireStats{}
+
+// dialOptions are the options for every connection the command makes,
+// to the master and to chunkservers. main fills them in from the flags.
+var dialOptions []grpc.DialOption
+
+// wireDialOptions returns the dial options that compress messages with
+// the named codec ("none" for no compression) and count them in wire.
+func wireDialOptions(name string) ([]grpc.DialOption, error) {
+	codec, err := compression.Parse(name)
+	if err != nil {
+		return nil, err
+	}
+
+	wire.codec = codec
+	opts := []grpc.DialOption{grpc.WithStatsHandler(wire)}
+	if codec != compression.None {
+		opts = append(opts, grpc.WithDefaultCallOptions(grpc.UseCompressor(codec)))
+	}
+	return opts, nil
+}
+
+// HandleRPC counts message sizes.
+func (w *wireStats) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	switch p := s.(type) {
+	case *stats.OutPayload:
+		w.sent.Add(int64(p.Length))
+		w.sentWire.Add(int64(p.WireLength))
+	case *stats.InPayload:
+		w.received.Add(int64(p.Length))
+		w.receivedWire.Add(int64(p.WireLength))
+	}
+}
+
+func (w *wireStats) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireStats) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireStats) HandleConn(context.Context, stats.ConnStats) {}
+
+// report prints how much a transfer took on the wire, against how much
+// data it moved: the data sent for an upload, received for a download.
+// Without wire compression there is nothing to say.
+func (w *wireStats) report(upload bool) {
+	if w.codec == compression.None {
+		return
+	}
+
+	data, onWire, verb := w.received.Load(), w.receivedWire.Load(), "received"
+	if upload {
+		data, onWire, verb = w.sent.Load(), w.sentWire.Load(), "sent"
+	}
+	if onWire == 0 {
+		return
+	}
+	fmt.Printf("Wire: %s %s for %s of messages (%.1fx with %s)\n", formatSize(onWire), verb, formatSize(data), float64(data)/float64(onWire), w.codec)
+}
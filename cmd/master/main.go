@@ -7,7 +7,9 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -16,24 +18,127 @@ import (
 )
 
 func main() {
+	// Everything happens in run, so its deferred cleanup - closing the
+	// metadata store above all - runs before the process exits on an
+	// error. log.Fatal would skip it.
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the master and serves until it is stopped.
+func run() error {
 	// Parse command-line flags.
 	// flag package provides simple CLI argument parsing.
 	// Format: flag.Type(name, default, description)
 	port := flag.Int("port", 50051, "Port to listen on")
-	dataDir := flag.String("data-dir", "./data", "Directory to store file data")
+	dataDir := flag.String("data-dir", "./data", "Directory for master state (metadata)")
+	chunkSize := flag.Int64("chunk-size", master.DefaultChunkSize, "Size of stored chunks in bytes")
+	chunkingName := flag.String("chunking", "fixed", "How to cut uploads into chunks: 'fixed' (every chunk-size bytes) or 'cdc' (content-defined, for deduplicating edited files)")
+	cdcAverageSize := flag.Int64("cdc-avg-size", master.DefaultCDCAverageSize, "Average chunk size in bytes for content-defined chunking (at most chunk-size/4)")
+	replication := flag.Int("replication", master.DefaultReplication, "Default number of replicas per chunk (files may override)")
+	heartbeatTimeout := flag.Duration("heartbeat-timeout", master.DefaultHeartbeatTimeout, "Consider a chunkserver dead after this long without a heartbeat")
+	repairInterval := flag.Duration("repair-interval", master.DefaultRepairInterval, "How often to re-replicate under-replicated chunks")
+	gcGrace := flag.Duration("gc-grace", master.DefaultGCGrace, "How long an unreferenced chunk survives before chunkservers delete it")
+	sessionTimeout := flag.Duration("upload-session-timeout", master.DefaultUploadSessionTimeout, "Abandon resumable uploads idle for this long")
+	trashGrace := flag.Duration("trash-grace", master.DefaultTrashGrace, "How long deleted files stay in the trash before they are purged")
+	metadataStore := flag.String("metadata-store", "wal", "Metadata store: 'wal' (durable) or 'memory' (lost on restart)")
+	metadataDir := flag.String("metadata-dir", "", "Directory for the metadata WAL and snapshots (default: <data-dir>/metadata)")
+	snapshotEvery := flag.Int("snapshot-every", master.DefaultSnapshotEvery, "Compact the metadata WAL into a snapshot after this many writes")
+	snapshotInterval := flag.Duration("snapshot-interval", 5*time.Minute, "Also compact the metadata WAL at this interval (0 to disable)")
+	masterKeyFile := flag.String("master-key-file", "", "Encrypt files at rest, with data keys wrapped by the master keys in this file (lines of 'ID base64-key'; the last is active). This protects chunkserver disks and metadata, not the API: the master has no authentication, and anyone who can reach it can read files and get their data keys from GetChunkLocations")
+
+	// --retention may be given several times, one rule per prefix
+	var retention []master.RetentionRule
+	flag.Func("retention", fmt.Sprintf("Old versions to keep under a path, as PREFIX=keep:N,days:D (repeatable; default keeps %d versions)", master.DefaultKeepVersions), func(value string) error {
+		rule, err := master.ParseRetentionRule(value)
+		if err != nil {
+			return err
+		}
+		retention = append(retention, rule)
+		return nil
+	})
+
+	// --compression may be given several times too
+	var compressionRules []master.CompressionRule
+	flag.Func("compression", "Compress files under a path at rest, as PREFIX=zstd|gzip|none (repeatable; default: none)", func(value string) error {
+		rule, err := master.ParseCompressionRule(value)
+		if err != nil {
+			return err
+		}
+		compressionRules = append(compressionRules, rule)
+		return nil
+	})
 	flag.Parse() // Actually parse os.Args
 
+	chunking, err := master.ParseChunking(*chunkingName)
+	if err != nil {
+		return fmt.Errorf("invalid --chunking: %w", err)
+	}
+
+	// Without a master key, files are stored unencrypted
+	var kms master.KMS
+	if *masterKeyFile != "" {
+		keyFile, err := master.NewKeyFile(*masterKeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load master keys: %w", err)
+		}
+		kms = keyFile
+	}
+
+	if *metadataDir == "" {
+		*metadataDir = filepath.Join(*dataDir, "metadata")
+	}
+
+	// Open the metadata store.
+	// With the WAL store, this replays the snapshot and log so the
+	// namespace survives restarts.
+	var metadata master.MetadataStore
+	switch *metadataStore {
+	case "wal":
+		walStore, err := master.NewWALMetadataStore(*metadataDir, *snapshotEvery, *snapshotInterval)
+		if err != nil {
+			return fmt.Errorf("failed to open metadata store: %w", err)
+		}
+		// Close takes a final snapshot, so the next startup is fast.
+		defer func() {
+			if err := walStore.Close(); err != nil {
+				log.Printf("Failed to close metadata store: %v", err)
+			}
+		}()
+		metadata = walStore
+	case "memory":
+		metadata = master.NewInMemoryMetadataStore()
+	default:
+		return fmt.Errorf("unknown metadata store %q (want 'wal' or 'memory')", *metadataStore)
+	}
+
 	// Create the DFS server
-	dfsServer, err := master.NewServer(*dataDir)
+	dfsServer, err := master.NewServer(master.Config{
+		Metadata:             metadata,
+		ChunkSize:            *chunkSize,
+		Chunking:             chunking,
+		CDCAverageSize:       *cdcAverageSize,
+		DefaultReplication:   *replication,
+		HeartbeatTimeout:     *heartbeatTimeout,
+		RepairInterval:       *repairInterval,
+		GCGrace:              *gcGrace,
+		UploadSessionTimeout: *sessionTimeout,
+		Retention:            retention,
+		TrashGrace:           *trashGrace,
+		Compression:          compressionRules,
+		KMS:                  kms,
+	})
 	if err != nil {
-		log.Fatalf("Failed to create server: %v", err)
+		return fmt.Errorf("failed to create server: %w", err)
 	}
+	defer dfsServer.Close()
 
 	// Create a TCP listener on the specified port.
 	// net.Listen returns a Listener interface that accepts connections.
 	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
-		log.Fatalf("Failed to listen on port %d: %v", *port, err)
+		return fmt.Errorf("failed to listen on port %d: %w", *port, err)
 	}
 
 	// Create the gRPC server.
@@ -45,6 +150,9 @@ func main() {
 	// This tells gRPC to route FileService RPCs to our dfsServer.
 	api.RegisterFileServiceServer(grpcServer, dfsServer)
 
+	// Chunkservers send their heartbeats to the same server.
+	api.RegisterMasterServiceServer(grpcServer, dfsServer)
+
 	// Set up graceful shutdown.
 	// This is a production best practice - handle SIGINT (Ctrl+C) and SIGTERM
 	// gracefully to finish in-flight requests before shutting down.
@@ -65,6 +173,10 @@ func main() {
 		<-stop // Block until signal received (channel receive)
 		log.Println("Shutting down server...")
 
+		// Watch streams never end by themselves; end them so the
+		// graceful stop doesn't wait forever.
+		dfsServer.StopWatching()
+
 		// GracefulStop stops accepting new connections and waits
 		// for existing RPCs to complete before stopping.
 		grpcServer.GracefulStop()
@@ -74,14 +186,20 @@ func main() {
 	log.Printf("GoDFS Master Server starting...")
 	log.Printf("  Port:     %d", *port)
 	log.Printf("  Data dir: %s", *dataDir)
+	log.Printf("  Replication: %d", *replication)
+	log.Printf("  Metadata: %s", *metadataStore)
+	if *metadataStore == "wal" {
+		log.Printf("  Metadata dir: %s", *metadataDir)
+	}
 	log.Println("Press Ctrl+C to stop")
 
 	// Start serving requests.
 	// Serve() blocks until the server stops.
 	// This is why we handle shutdown in a separate goroutine.
 	if err := grpcServer.Serve(listener); err != nil {
-		log.Fatalf("Failed to serve: %v", err)
+		return fmt.Errorf("failed to serve: %w", err)
 	}
 
 	log.Println("Server stopped")
+	return nil
 }
@@ -0,0 +1,395 @@
+// Package chunkclient contains helpers for talking to chunkservers.
+// It is shared by the master (writing and reading chunks), chunkservers
+// (forwarding writes down a replication pipeline) and the CLI client
+// (reading chunks directly).
+package chunkclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sync"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/compression"
+	"github.com/darshanmadesh/godfs/internal/encryption"
+)
+
+// castagnoli is the CRC-32C table used for chunk checksums.
+// CRC-32C has hardware support on modern CPUs, which is why storage
+// systems (ext4, iSCSI, GCS...) favour it over the IEEE polynomial.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Pool keeps one gRPC connection per chunkserver.
+// gRPC connections are expensive to set up but safe for concurrent use,
+// so we create them lazily and share them across all requests.
+type Pool struct {
+	mu    sync.Mutex
+	conns map[string]*grpc.ClientConn
+
+	// opts are extra options for every connection.
+	opts []grpc.DialOption
+}
+
+// NewPool creates an empty connection pool. Connections are dialed with
+// opts in addition to the defaults.
+func NewPool(opts ...grpc.DialOption) *Pool {
+	return &Pool{
+		conns: make(map[string]*grpc.ClientConn),
+		opts:  opts,
+	}
+}
+
+// conn returns the pooled connection for addr, dialing it if needed.
+func (p *Pool) conn(addr string) (*grpc.ClientConn, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	conn, ok := p.conns[addr]
+	if !ok {
+		var err error
+		opts := append([]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}, p.opts...)
+		conn, err = grpc.NewClient(addr, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to chunkserver %s: %w", addr, err)
+		}
+		p.conns[addr] = conn
+	}
+	return conn, nil
+}
+
+// Client returns a ChunkService client for the chunkserver at addr.
+func (p *Pool) Client(addr string) (api.ChunkServiceClient, error) {
+	conn, err := p.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+	return api.NewChunkServiceClient(conn), nil
+}
+
+// Close closes every pooled connection.
+func (p *Pool) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for addr, conn := range p.conns {
+		conn.Close()
+		delete(p.conns, addr)
+	}
+}
+
+// ReadChunk streams a chunk's data to fn, trying each address in turn.
+// If a replica fails partway through - a chunkserver finding a corrupt
+// block stops there - the next one picks up where it left off, so fn
+// never sees a byte twice.
+// An error from fn itself ends the read.
+func (p *Pool) ReadChunk(ctx context.Context, chunkID string, addrs []string, fn func([]byte) error) error {
+	return p.ReadChunkRange(ctx, chunkID, addrs, 0, 0, fn)
+}
+
+// ReadChunkRange is like ReadChunk but reads only length bytes starting at
+// offset within the chunk. A length of 0 reads to the end of the chunk.
+func (p *Pool) ReadChunkRange(ctx context.Context, chunkID string, addrs []string, offset, length int64, fn func([]byte) error) error {
+	if len(addrs) == 0 {
+		return fmt.Errorf("chunk %s has no locations", chunkID)
+	}
+
+	// Count what reaches fn, to know where to resume
+	var delivered int64
+	var fnErr error
+	deliver := func(data []byte) error {
+		if err := fn(data); err != nil {
+			fnErr = err
+			return err
+		}
+		delivered += int64(len(data))
+		return nil
+	}
+
+	var lastErr error
+	for _, addr := range addrs {
+		req := &api.ReadChunkRequest{
+			ChunkId: chunkID,
+			Offset:  offset + delivered,
+		}
+		if length > 0 {
+			req.Length = length - delivered
+		}
+
+		err := p.readChunkFrom(ctx, addr, req, deliver)
+		if err == nil {
+			return nil
+		}
+		if fnErr != nil || ctx.Err() != nil {
+			return err
+		}
+		if length > 0 && delivered == length {
+			return nil // Everything arrived before the replica failed
+		}
+		lastErr = err
+	}
+	return fmt.Errorf("chunk %s unavailable: %w", chunkID, lastErr)
+}
+
+// StoredChunk describes how a chunk is stored, for ReadStoredRange.
+type StoredChunk struct {
+	ID        string
+	Addresses []string
+
+	// Codec is the compression codec the chunk is stored with (see
+	// package compression), if any.
+	Codec string
+
+	// Key is the data key the chunk is encrypted with (see package
+	// encryption), if any, and StoredSize its size as stored.
+	Key        []byte
+	StoredSize int64
+}
+
+// ReadStoredRange is ReadChunkRange for a chunk that may be stored
+// compressed, encrypted, or both: offset and length are positions in the
+// original data, and that is what fn gets.
+//
+// An encrypted chunk is read a segment at a time, so only the segments
+// the range covers are fetched. A compressed stream, though, can't be
+// entered in the middle, so a compressed chunk is read from the start and
+// what comes before offset thrown away.
+func (p *Pool) ReadStoredRange(ctx context.Context, chunk StoredChunk, offset, length int64, fn func([]byte) error) error {
+	if chunk.Codec == compression.None && chunk.Key == nil {
+		return p.ReadChunkRange(ctx, chunk.ID, chunk.Addresses, offset, length, fn)
+	}
+
+	// Work out what to read of the chunk as stored
+	var readOffset, readLength, firstSegment int64
+	if chunk.Codec == compression.None {
+		const sealed = encryption.SegmentSize + encryption.Overhead
+		firstSegment = offset / encryption.SegmentSize
+		readOffset = firstSegment * sealed
+		offset -= firstSegment * encryption.SegmentSize
+		if length > 0 {
+			lastSegment := (firstSegment*encryption.SegmentSize + offset + length - 1) / encryption.SegmentSize
+			if lastSegment < encryption.Segments(chunk.StoredSize)-1 {
+				readLength = (lastSegment - firstSegment + 1) * sealed
+			}
+		}
+	}
+
+	// ReadChunk pushes data at us while the readers below pull it: a
+	// pipe turns one into the other
+	pr, pw := io.Pipe()
+	defer pr.Close() // Stops the reader if we return early
+	go func() {
+		pw.CloseWithError(p.ReadChunkRange(ctx, chunk.ID, chunk.Addresses, readOffset, readLength, func(data []byte) error {
+			_, err := pw.Write(data)
+			return err
+		}))
+	}()
+
+	var r io.Reader = pr
+	if chunk.Key != nil {
+		var err error
+		r, err = encryption.NewReader(chunk.Key, chunk.ID, r, firstSegment, chunk.StoredSize)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt chunk %s: %w", chunk.ID, err)
+		}
+	}
+	if chunk.Codec != compression.None {
+		zr, err := compression.NewReader(chunk.Codec, r)
+		if err != nil {
+			return fmt.Errorf("failed to decompress chunk %s: %w", chunk.ID, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	if _, err := io.CopyN(io.Discard, r, offset); err != nil {
+		return fmt.Errorf("failed to read chunk %s: %w", chunk.ID, err)
+	}
+	if length > 0 {
+		r = io.LimitReader(r, length)
+	}
+
+	buf := make([]byte, readBufferSize)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %s: %w", chunk.ID, err)
+		}
+	}
+}
+
+// readBufferSize is how much data ReadStoredRange hands to
+// its callback at a time.
+const readBufferSize = 1024 * 1024
+
+// readChunkFrom reads a chunk from one chunkserver.
+func (p *Pool) readChunkFrom(ctx context.Context, addr string, req *api.ReadChunkRequest, fn func([]byte) error) error {
+	client, err := p.Client(addr)
+	if err != nil {
+		return err
+	}
+
+	stream, err := client.ReadChunk(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to read chunk from %s: %w", addr, err)
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read chunk from %s: %w", addr, err)
+		}
+
+		if err := fn(resp.Chunk); err != nil {
+			return err
+		}
+	}
+}
+
+// DeleteChunk deletes one chunk from one chunkserver.
+func (p *Pool) DeleteChunk(ctx context.Context, addr, chunkID string) error {
+	client, err := p.Client(addr)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.DeleteChunk(ctx, &api.DeleteChunkRequest{ChunkId: chunkID})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Message)
+	}
+	return nil
+}
+
+// Writer streams one chunk down a replication pipeline.
+//
+// Data is sent only to the first chunkserver in the pipeline; it stores
+// the data and forwards it to the next one, and so on. This way each
+// replica's network link carries the data once, instead of the writer
+// having to send N copies itself.
+type Writer struct {
+	chunkID  string
+	pipeline []string
+	size     int64
+	crc      uint32 // CRC-32C of everything written so far
+	stream   api.ChunkService_WriteChunkClient
+
+	// cancel aborts the WriteChunk stream; every chunkserver in the
+	// pipeline then discards whatever it received.
+	cancel context.CancelFunc
+}
+
+// NewWriter opens a WriteChunk stream to pipeline[0], asking it to forward
+// the data to the rest of the pipeline.
+func (p *Pool) NewWriter(ctx context.Context, chunkID string, pipeline []string) (*Writer, error) {
+	if len(pipeline) == 0 {
+		return nil, errors.New("empty replication pipeline")
+	}
+	head := pipeline[0]
+
+	client, err := p.Client(head)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	stream, err := client.WriteChunk(ctx)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to open chunk stream to %s: %w", head, err)
+	}
+
+	if err := stream.Send(&api.WriteChunkRequest{
+		Data: &api.WriteChunkRequest_Header{
+			Header: &api.ChunkHeader{
+				ChunkId:  chunkID,
+				Pipeline: pipeline[1:],
+			},
+		},
+	}); err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to send chunk header to %s: %w", head, err)
+	}
+
+	return &Writer{
+		chunkID:  chunkID,
+		pipeline: pipeline,
+		stream:   stream,
+		cancel:   cancel,
+	}, nil
+}
+
+// Size returns the number of bytes written so far.
+func (w *Writer) Size() int64 {
+	return w.size
+}
+
+// Checksum returns the CRC-32C of the data written so far.
+func (w *Writer) Checksum() uint32 {
+	return w.crc
+}
+
+// Write sends data down the pipeline.
+func (w *Writer) Write(data []byte) error {
+	if err := w.stream.Send(&api.WriteChunkRequest{
+		Data: &api.WriteChunkRequest_Chunk{Chunk: data},
+	}); err != nil {
+		// Send returns io.EOF when the chunkserver ended the stream early;
+		// the real reason is only available from CloseAndRecv.
+		if err == io.EOF {
+			_, err = w.stream.CloseAndRecv()
+		}
+		return fmt.Errorf("failed to send chunk data to %s: %w", w.pipeline[0], err)
+	}
+	w.size += int64(len(data))
+	w.crc = crc32.Update(w.crc, castagnoli, data)
+	return nil
+}
+
+// Close finishes the chunk and waits until every chunkserver in the
+// pipeline has acknowledged it. The chunkserver's checksum of what it
+// stored must match ours, which catches corruption in transit.
+func (w *Writer) Close() error {
+	defer w.cancel()
+
+	resp, err := w.stream.CloseAndRecv()
+	if err != nil {
+		return fmt.Errorf("failed to store chunk via %s: %w", w.pipeline[0], err)
+	}
+	if !resp.Success {
+		return fmt.Errorf("chunkserver %s rejected chunk: %s", w.pipeline[0], resp.Message)
+	}
+	if resp.Size != w.size {
+		return fmt.Errorf("chunkserver %s stored %d bytes, sent %d", w.pipeline[0], resp.Size, w.size)
+	}
+	if resp.Crc32C != w.crc {
+		return fmt.Errorf("chunkserver %s stored chunk with checksum %08x, sent %08x", w.pipeline[0], resp.Crc32C, w.crc)
+	}
+	if int(resp.Replicas) != len(w.pipeline) {
+		return fmt.Errorf("chunk %s stored on %d of %d replicas", w.chunkID, resp.Replicas, len(w.pipeline))
+	}
+	return nil
+}
+
+// Abort cancels the stream without committing the chunk.
+func (w *Writer) Abort() {
+	w.cancel()
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package chunkserver
+
+import "math"
+
+// diskCapacity can't find out how much space is free here, so it reports
+// the capacity as unknown: no limit of its own. HeartbeatConfig.Capacity
+// (--capacity) is then the only bound, and should be set.
+func diskCapacity(dir string, used int64) (int64, error) {
+	return math.MaxInt64, nil
+}
@@ -0,0 +1,22 @@
+//go:build unix
+
+package chunkserver
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// diskCapacity returns the space chunks can have on the filesystem holding
+// dir: the space they already use, plus what is free. The filesystem's
+// total size would overstate it - logs, the OS, other data share the disk
+// - and the master would keep placing chunks on a disk that is full.
+// Free space is what an unprivileged process may use, leaving out the
+// blocks reserved for root.
+func diskCapacity(dir string, used int64) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(dir, &st); err != nil {
+		return 0, fmt.Errorf("failed to stat filesystem: %w", err)
+	}
+	return used + int64(st.Bavail)*int64(st.Bsize), nil
+}
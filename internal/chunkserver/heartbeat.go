@@ -0,0 +1,117 @@
+package chunkserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// DefaultHeartbeatInterval is how often a chunkserver reports to the master.
+// The master's dead-node timeout should be several times this, so a couple
+// of lost heartbeats don't trigger re-replication.
+const DefaultHeartbeatInterval = 5 * time.Second
+
+// HeartbeatConfig controls how a chunkserver reports to the master.
+type HeartbeatConfig struct {
+	// Master is the master's address (host:port).
+	Master string
+
+	// Address is how the master and clients reach this chunkserver.
+	// It must be routable from them, so "localhost" only works when
+	// everything runs on one machine.
+	Address string
+
+	// Interval between heartbeats. Zero means DefaultHeartbeatInterval.
+	Interval time.Duration
+
+	// Capacity is the space (in bytes) this chunkserver offers for chunks.
+	// Zero means all the disk holding the data directory can give them,
+	// on platforms that can say how much that is (see diskCapacity).
+	// Either way, what is reported never exceeds the chunks already
+	// stored plus the disk's free space, since other things share it.
+	Capacity int64
+}
+
+// RunHeartbeats reports to the master every cfg.Interval until ctx is done.
+// Each heartbeat carries the chunkserver's capacity, used space and the
+// IDs of every chunk it holds; the master replies with chunks it no longer
+// references, which we delete.
+func (s *Server) RunHeartbeats(ctx context.Context, cfg HeartbeatConfig) error {
+	if cfg.Master == "" || cfg.Address == "" {
+		return errors.New("heartbeat needs both a master and an advertised address")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultHeartbeatInterval
+	}
+
+	conn, err := grpc.NewClient(cfg.Master, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return fmt.Errorf("failed to connect to master: %w", err)
+	}
+	defer conn.Close()
+	master := api.NewMasterServiceClient(conn)
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		// Heartbeat right away so the master learns about us on startup,
+		// then once per tick.
+		if err := s.heartbeat(ctx, master, cfg); err != nil {
+			log.Printf("chunkserver: heartbeat failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat sends one report and applies the master's reply.
+func (s *Server) heartbeat(ctx context.Context, master api.MasterServiceClient, cfg HeartbeatConfig) error {
+	ids, used, err := s.store.List()
+	if err != nil {
+		return err
+	}
+
+	capacity, err := diskCapacity(s.store.dir, used)
+	if err != nil {
+		return err
+	}
+	if cfg.Capacity > 0 {
+		capacity = min(capacity, cfg.Capacity)
+	}
+
+	// Don't let a slow master stall us past the next heartbeat
+	ctx, cancel := context.WithTimeout(ctx, cfg.Interval)
+	defer cancel()
+
+	resp, err := master.Heartbeat(ctx, &api.HeartbeatRequest{
+		Address:       cfg.Address,
+		CapacityBytes: capacity,
+		UsedBytes:     used,
+		ChunkIds:      ids,
+	})
+	if err != nil {
+		return err
+	}
+
+	// Garbage collection: the master no longer references these chunks
+	// (their file was deleted, or we were presumed dead and they were
+	// re-replicated elsewhere).
+	for _, id := range resp.DeleteChunkIds {
+		if err := s.store.Remove(id); err != nil && !errors.Is(err, ErrChunkNotFound) {
+			log.Printf("chunkserver: failed to delete chunk %s: %v", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package chunkserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkclient"
+)
+
+// Buffer size for streaming chunk data to readers (1MB).
+// Same as the master's streaming size; chunks themselves are much larger.
+const streamBufferSize = 1024 * 1024
+
+// Server implements the gRPC ChunkService interface.
+// It is deliberately dumb: it stores and serves bytes by chunk ID and
+// knows nothing about files. All namespace decisions live on the master.
+type Server struct {
+	// Embed the unimplemented server for forward compatibility.
+	api.UnimplementedChunkServiceServer
+
+	// store holds the chunk files on local disk.
+	store *Store
+
+	// peers holds connections to other chunkservers, used to forward
+	// writes down a replication pipeline.
+	peers *chunkclient.Pool
+}
+
+// NewServer creates a chunkserver storing chunks under dataDir.
+func NewServer(dataDir string) (*Server, error) {
+	store, err := NewStore(dataDir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Server{
+		store: store,
+		peers: chunkclient.NewPool(),
+	}, nil
+}
+
+// Close releases connections to peer chunkservers.
+func (s *Server) Close() {
+	s.peers.Close()
+}
+
+// WriteChunk stores a chunk and forwards it down the replication pipeline.
+// The sender sends: 1) a header naming the chunk, then 2) data messages.
+//
+// If the header lists more chunkservers, every data message is written
+// locally and also passed to the next one. We only report success once
+// the whole downstream pipeline has acknowledged, so a success at the
+// head of the pipeline means every replica has the chunk.
+//
+// Data goes to a staging file first; the chunk only appears under its ID
+// once it is complete and the downstream replicas have confirmed it.
+func (s *Server) WriteChunk(stream api.ChunkService_WriteChunkServer) error {
+	// The first message must be the header
+	req, err := stream.Recv()
+	if err != nil {
+		return fmt.Errorf("failed to receive header: %w", err)
+	}
+	header, ok := req.Data.(*api.WriteChunkRequest_Header)
+	if !ok {
+		return errors.New("expected header as first message")
+	}
+	chunkID := header.Header.ChunkId
+
+	staged, err := s.store.Create(chunkID)
+	if err != nil {
+		return fmt.Errorf("failed to create chunk: %w", err)
+	}
+
+	// Open the downstream leg of the pipeline, if any.
+	// Cancelling our stream's context also aborts the downstream write.
+	var next *chunkclient.Writer
+	if pipeline := header.Header.Pipeline; len(pipeline) > 0 {
+		next, err = s.peers.NewWriter(stream.Context(), chunkID, pipeline)
+		if err != nil {
+			staged.Abort()
+			return err
+		}
+	}
+
+	// fail discards the staged chunk and aborts downstream
+	fail := func(err error) error {
+		staged.Abort()
+		if next != nil {
+			next.Abort()
+		}
+		return err
+	}
+
+	// Stream the data to disk (and downstream), checksumming as we go
+	var size int64
+	var crc uint32
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(fmt.Errorf("failed to receive chunk data: %w", err))
+		}
+
+		data, ok := req.Data.(*api.WriteChunkRequest_Chunk)
+		if !ok {
+			return fail(errors.New("unexpected header after chunk data"))
+		}
+
+		n, err := staged.Write(data.Chunk)
+		if err != nil {
+			return fail(fmt.Errorf("failed to write chunk: %w", err))
+		}
+		size += int64(n)
+		crc = crc32.Update(crc, castagnoli, data.Chunk)
+
+		if next != nil {
+			if err := next.Write(data.Chunk); err != nil {
+				return fail(err)
+			}
+		}
+	}
+
+	// Wait for the rest of the pipeline, then publish our own copy.
+	// If we fail after downstream committed, their copies are
+	// unreferenced and heartbeat GC removes them.
+	replicas := int32(1)
+	if next != nil {
+		if err := next.Close(); err != nil {
+			staged.Abort()
+			return fmt.Errorf("replication pipeline failed: %w", err)
+		}
+		replicas += int32(len(header.Header.Pipeline))
+	}
+
+	if err := staged.Commit(crc); err != nil {
+		return fmt.Errorf("failed to commit chunk: %w", err)
+	}
+
+	return stream.SendAndClose(&api.WriteChunkResponse{
+		Success:  true,
+		Message:  fmt.Sprintf("Chunk '%s' stored", chunkID),
+		Size:     size,
+		Replicas: replicas,
+		Crc32C:   crc,
+	})
+}
+
+// ReadChunk streams a chunk's data (or a byte range of it) back to the
+// caller. Only the blocks the range covers are read, and each is checked
+// against its checksum before any of it is sent, so corrupt data never
+// goes out. If a block turns out corrupt partway through, the stream
+// fails, and the reader carries on from another replica.
+func (s *Server) ReadChunk(req *api.ReadChunkRequest, stream api.ChunkService_ReadChunkServer) error {
+	file, err := s.store.Open(req.ChunkId)
+	if err != nil {
+		return fmt.Errorf("failed to open chunk %s: %w", req.ChunkId, err)
+	}
+	defer file.Close()
+
+	// Serve just the requested range, if any
+	if req.Offset < 0 || req.Length < 0 {
+		return status.Errorf(codes.InvalidArgument, "invalid range: offset %d, length %d", req.Offset, req.Length)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat chunk: %w", err)
+	}
+	if req.Offset > info.Size() {
+		return status.Errorf(codes.OutOfRange, "offset %d is past the end of chunk %s (%d bytes)", req.Offset, req.ChunkId, info.Size())
+	}
+	r, err := s.store.RangeReader(req.ChunkId, file, info.Size(), req.Offset, req.Length)
+	if err != nil {
+		return fmt.Errorf("failed to verify chunk %s: %w", req.ChunkId, err)
+	}
+
+	buf := make([]byte, streamBufferSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return fmt.Errorf("failed to read chunk %s: %w", req.ChunkId, err)
+		}
+
+		if err := stream.Send(&api.ReadChunkResponse{
+			Chunk: buf[:n],
+		}); err != nil {
+			return fmt.Errorf("failed to send chunk data: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// DeleteChunk removes a chunk from local storage.
+func (s *Server) DeleteChunk(ctx context.Context, req *api.DeleteChunkRequest) (*api.DeleteChunkResponse, error) {
+	if err := s.store.Remove(req.ChunkId); err != nil {
+		if errors.Is(err, ErrChunkNotFound) {
+			return &api.DeleteChunkResponse{
+				Success: false,
+				Message: fmt.Sprintf("chunk not found: %s", req.ChunkId),
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to delete chunk: %w", err)
+	}
+
+	return &api.DeleteChunkResponse{
+		Success: true,
+		Message: fmt.Sprintf("Chunk '%s' deleted", req.ChunkId),
+	}, nil
+}
+
+// ReplicateChunk copies a local chunk to other chunkservers.
+// The master calls this to restore a chunk's replica count after a
+// chunkserver dies; the data flows directly between chunkservers.
+func (s *Server) ReplicateChunk(ctx context.Context, req *api.ReplicateChunkRequest) (*api.ReplicateChunkResponse, error) {
+	file, err := s.store.Open(req.ChunkId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open chunk %s: %w", req.ChunkId, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat chunk: %w", err)
+	}
+
+	// Never spread a corrupt copy; the master will try another source.
+	// Blocks are checked as they are sent, and a bad one aborts the copy
+	// before the targets commit anything.
+	r, err := s.store.RangeReader(req.ChunkId, file, info.Size(), 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify chunk %s: %w", req.ChunkId, err)
+	}
+
+	w, err := s.peers.NewWriter(ctx, req.ChunkId, req.Targets)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, streamBufferSize)
+	for {
+		n, err := io.ReadFull(r, buf)
+		if err == io.EOF {
+			break
+		}
+		if err != nil && err != io.ErrUnexpectedEOF {
+			w.Abort()
+			return nil, fmt.Errorf("failed to read chunk %s: %w", req.ChunkId, err)
+		}
+
+		if err := w.Write(buf[:n]); err != nil {
+			w.Abort()
+			return nil, err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return &api.ReplicateChunkResponse{
+		Success: true,
+		Message: fmt.Sprintf("Chunk '%s' copied to %d chunkserver(s)", req.ChunkId, len(req.Targets)),
+	}, nil
+}
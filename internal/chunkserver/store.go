@@ -0,0 +1,516 @@
+package chunkserver
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Common errors returned by the chunk store.
+var (
+	ErrChunkNotFound  = errors.New("chunk not found")
+	ErrInvalidChunkID = errors.New("invalid chunk ID")
+	ErrChunkCorrupt   = errors.New("chunk data does not match its checksum")
+)
+
+// Suffixes of the files kept next to each chunk.
+const (
+	checksumSuffix      = ".crc"     // CRC-32C of the chunk, 8 hex digits
+	blockChecksumSuffix = ".blocks"  // CRC-32C of each block, 4 bytes big-endian apiece
+	corruptSuffix       = ".corrupt" // Quarantined chunk that failed verification
+)
+
+// checksumBlockSize is the size of the blocks chunks are checksummed in,
+// besides as a whole. A read only has to check the blocks it covers, so
+// reading 4KB of a 64MB chunk doesn't mean reading all 64MB.
+const checksumBlockSize = 64 * 1024
+
+// castagnoli is the CRC-32C table used for chunk checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// maxChunkIDLength bounds chunk IDs so they always make sane filenames.
+const maxChunkIDLength = 128
+
+// Store keeps chunks as plain files in a directory, one file per chunk,
+// named by chunk ID. Chunks are immutable once written, which keeps the
+// store simple: no partial updates, no locking around reads.
+//
+// Next to each chunk, a small "<id>.crc" file holds the CRC-32C computed
+// when the chunk was written, and "<id>.blocks" the CRC-32C of each of its
+// checksumBlockSize blocks, so silent disk corruption can be detected
+// before the data is served.
+//
+// New chunks are written under a staging subdirectory and renamed into
+// place only once complete, so a chunk file under its real name is always
+// whole: readers, heartbeat reports and a restart after a crash never see
+// a half-written chunk.
+type Store struct {
+	dir     string
+	staging string
+}
+
+// stagingDirName is the subdirectory holding chunks still being written.
+const stagingDirName = "staging"
+
+// NewStore creates a chunk store rooted at dir, creating it if needed.
+// Anything left in the staging area belongs to writes interrupted by a
+// crash; those chunks were never acknowledged, so they are discarded.
+func NewStore(dir string) (*Store, error) {
+	staging := filepath.Join(dir, stagingDirName)
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create chunk directory: %w", err)
+	}
+	return &Store{dir: dir, staging: staging}, nil
+}
+
+// StagedChunk is a chunk being written. It becomes visible under its ID
+// only when Commit succeeds.
+type StagedChunk struct {
+	*os.File
+
+	store *Store
+	id    string
+
+	// blocks checksums the data block by block as it is written.
+	blocks blockSums
+}
+
+// Write writes data to the chunk.
+func (c *StagedChunk) Write(data []byte) (int, error) {
+	n, err := c.File.Write(data)
+	c.blocks.add(data[:n])
+	return n, err
+}
+
+// blockSums computes the CRC-32C of each block of a stream of data.
+type blockSums struct {
+	sums []uint32
+	crc  uint32 // Of the block being filled
+	n    int    // Bytes in the block being filled
+}
+
+func (b *blockSums) add(data []byte) {
+	for len(data) > 0 {
+		m := min(len(data), checksumBlockSize-b.n)
+		b.crc = crc32.Update(b.crc, castagnoli, data[:m])
+		b.n += m
+		data = data[m:]
+		if b.n == checksumBlockSize {
+			b.sums = append(b.sums, b.crc)
+			b.crc, b.n = 0, 0
+		}
+	}
+}
+
+// finish returns the checksums of every block, the last one partial.
+func (b *blockSums) finish() []uint32 {
+	if b.n > 0 {
+		b.sums = append(b.sums, b.crc)
+		b.crc, b.n = 0, 0
+	}
+	return b.sums
+}
+
+// Create starts writing a new chunk in the staging area.
+// The caller must Commit it, or Abort it if the write fails.
+func (s *Store) Create(id string) (*StagedChunk, error) {
+	if _, err := s.path(id); err != nil {
+		return nil, err
+	}
+
+	// A unique name, so two writes of the same ID can't interleave
+	file, err := os.CreateTemp(s.staging, id+".*")
+	if err != nil {
+		return nil, err
+	}
+	return &StagedChunk{File: file, store: s, id: id}, nil
+}
+
+// Commit flushes the chunk to disk, records its checksums and atomically
+// moves it to its final name.
+func (c *StagedChunk) Commit(crc uint32) error {
+	if err := c.Sync(); err != nil {
+		c.Abort()
+		return err
+	}
+	if err := c.Close(); err != nil {
+		c.Abort()
+		return err
+	}
+
+	// The checksums are written next to the staged data, so a failed
+	// commit can't leave them by a chunk they don't describe, and then
+	// moved into place before it: a chunk must never be visible without
+	// them, or it would be served unverified.
+	staged := c.Name()
+	if err := writeBlockChecksums(staged+blockChecksumSuffix, c.blocks.finish()); err != nil {
+		c.Abort()
+		return err
+	}
+	if err := writeChecksum(staged+checksumSuffix, crc); err != nil {
+		c.Abort()
+		return err
+	}
+
+	path, _ := c.store.path(c.id)
+	for _, suffix := range []string{blockChecksumSuffix, checksumSuffix} {
+		if err := os.Rename(staged+suffix, path+suffix); err != nil {
+			c.unpublish(path)
+			c.Abort()
+			return err
+		}
+	}
+
+	// The data goes last; from here on the chunk is served
+	if err := os.Rename(staged, path); err != nil {
+		c.unpublish(path)
+		c.Abort()
+		return err
+	}
+	return syncDir(c.store.dir)
+}
+
+// unpublish removes checksums a failed Commit moved into place at path,
+// unless a chunk is already there for them to describe: another write of
+// the same ID, which has the same data.
+func (c *StagedChunk) unpublish(path string) {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		removeChecksums(path)
+	}
+}
+
+// Abort discards the staged chunk.
+func (c *StagedChunk) Abort() {
+	c.Close()
+	os.Remove(c.Name())
+	removeChecksums(c.Name())
+}
+
+// Open opens a chunk for reading. Returns ErrChunkNotFound if absent.
+func (s *Store) Open(id string) (*os.File, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrChunkNotFound
+	}
+	return file, err
+}
+
+// Remove deletes a chunk. Returns ErrChunkNotFound if absent.
+func (s *Store) Remove(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrChunkNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// The checksums are meaningless without their chunk
+	return removeChecksums(path)
+}
+
+// removeChecksums deletes the checksum files of the chunk at path.
+func removeChecksums(path string) error {
+	for _, suffix := range []string{checksumSuffix, blockChecksumSuffix} {
+		if err := os.Remove(path + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeChecksum writes the CRC-32C of a chunk to a checksum file at path.
+func writeChecksum(path string, crc uint32) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%08x", crc); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeBlockChecksums writes the CRC-32C of each block of a chunk to a
+// block checksum file at path.
+func writeBlockChecksums(path string, sums []uint32) error {
+	data := make([]byte, 0, 4*len(sums))
+	for _, sum := range sums {
+		data = binary.BigEndian.AppendUint32(data, sum)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// blockChecksums returns the recorded CRC-32C of each block of a chunk.
+// ok is false for chunks stored before block checksums were recorded.
+func (s *Store) blockChecksums(id string) (sums []uint32, ok bool, err error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	data, err := os.ReadFile(path + blockChecksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data)%4 != 0 {
+		return nil, false, fmt.Errorf("malformed block checksum file for chunk %s", id)
+	}
+
+	sums = make([]uint32, len(data)/4)
+	for i := range sums {
+		sums[i] = binary.BigEndian.Uint32(data[4*i:])
+	}
+	return sums, true, nil
+}
+
+// Checksum returns the recorded CRC-32C of a chunk.
+// ok is false for chunks stored before checksums were recorded.
+func (s *Store) Checksum(id string) (crc uint32, ok bool, err error) {
+	path, err := s.path(id)
+	if err != nil {
+		return 0, false, err
+	}
+
+	data, err := os.ReadFile(path + checksumSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+
+	v, err := strconv.ParseUint(string(data), 16, 32)
+	if err != nil {
+		return 0, false, fmt.Errorf("malformed checksum file for chunk %s: %w", id, err)
+	}
+	return uint32(v), true, nil
+}
+
+// RangeReader returns a reader for length bytes of a chunk, opened as
+// file and size bytes long, starting at offset; a length of 0 reads to
+// the end. Each block is checked against its checksum as it is read, and
+// none of its data is returned unless it matches: a corrupt block is
+// quarantined, like a chunk failing Verify, and the read fails with
+// ErrChunkCorrupt.
+//
+// Chunks stored before block checksums can only be checked whole, so for
+// those the whole chunk is verified up front.
+func (s *Store) RangeReader(id string, file *os.File, size, offset, length int64) (io.Reader, error) {
+	end := size
+	if length > 0 {
+		end = min(offset+length, size)
+	}
+
+	sums, ok, err := s.blockChecksums(id)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if err := s.Verify(id); err != nil {
+			return nil, err
+		}
+		return io.NewSectionReader(file, offset, end-offset), nil
+	}
+
+	// A chunk cut short (or grown) can't match its checksums
+	if blocks := (size + checksumBlockSize - 1) / checksumBlockSize; int64(len(sums)) != blocks {
+		return nil, s.corrupt(id, fmt.Sprintf("%d bytes, but %d block checksums", size, len(sums)))
+	}
+
+	first := offset / checksumBlockSize
+	return &verifiedReader{
+		store:  s,
+		id:     id,
+		file:   file,
+		size:   size,
+		sums:   sums,
+		block:  first,
+		skip:   offset - first*checksumBlockSize,
+		remain: end - offset,
+		buf:    make([]byte, checksumBlockSize),
+	}, nil
+}
+
+// verifiedReader reads a chunk a block at a time, checking each block.
+type verifiedReader struct {
+	store *Store
+	id    string
+	file  *os.File
+	size  int64
+	sums  []uint32
+
+	block  int64  // Next block to read
+	skip   int64  // Bytes of the next block before the range
+	remain int64  // Bytes of the range not yet returned
+	buf    []byte // The last block read
+	plain  []byte // What is left of it to return
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	if r.remain == 0 {
+		return 0, io.EOF
+	}
+	if len(r.plain) == 0 {
+		start := r.block * checksumBlockSize
+		n := min(checksumBlockSize, r.size-start)
+		if _, err := r.file.ReadAt(r.buf[:n], start); err != nil {
+			return 0, fmt.Errorf("failed to read chunk: %w", err)
+		}
+		if got := crc32.Checksum(r.buf[:n], castagnoli); got != r.sums[r.block] {
+			return 0, r.store.corrupt(r.id, fmt.Sprintf("block %d has %08x, expected %08x", r.block, got, r.sums[r.block]))
+		}
+		r.plain = r.buf[r.skip:n]
+		r.block++
+		r.skip = 0
+	}
+
+	n := copy(p[:min(int64(len(p)), r.remain)], r.plain)
+	r.plain = r.plain[n:]
+	r.remain -= int64(n)
+	return n, nil
+}
+
+// corrupt quarantines a chunk that failed verification, and returns the
+// ErrChunkCorrupt error describing why.
+func (s *Store) corrupt(id, why string) error {
+	if err := s.quarantine(id); err != nil {
+		log.Printf("chunkserver: failed to quarantine chunk %s: %v", id, err)
+	}
+	return fmt.Errorf("%w: %s %s", ErrChunkCorrupt, id, why)
+}
+
+// Verify reads a whole chunk and checks it against its recorded checksum.
+// A corrupt chunk is quarantined: renamed out of the way so it is neither
+// served nor reported to the master, which then re-replicates it from a
+// good copy. Returns ErrChunkCorrupt in that case.
+func (s *Store) Verify(id string) error {
+	want, ok, err := s.Checksum(id)
+	if err != nil || !ok {
+		return err
+	}
+
+	file, err := s.Open(id)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h := crc32.New(castagnoli)
+	if _, err := io.Copy(h, file); err != nil {
+		return fmt.Errorf("failed to read chunk: %w", err)
+	}
+	if got := h.Sum32(); got != want {
+		return s.corrupt(id, fmt.Sprintf("has %08x, expected %08x", got, want))
+	}
+	return nil
+}
+
+// quarantine moves a corrupt chunk aside, keeping it for inspection.
+func (s *Store) quarantine(id string) error {
+	path, err := s.path(id)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(path, path+corruptSuffix); err != nil {
+		return err
+	}
+	log.Printf("chunkserver: chunk %s failed verification, quarantined", id)
+	return removeChecksums(path)
+}
+
+// syncDir fsyncs a directory so that a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// List returns the IDs of all stored chunks and their total size in bytes.
+func (s *Store) List() (ids []string, used int64, err error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	for _, entry := range entries {
+		// Skip anything that isn't a chunk (directories, stray files)
+		if !entry.Type().IsRegular() || !validChunkID(entry.Name()) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			// Deleted between ReadDir and Info - just skip it
+			continue
+		}
+		ids = append(ids, entry.Name())
+		used += info.Size()
+	}
+	return ids, used, nil
+}
+
+// path maps a chunk ID to its file, rejecting anything that isn't a
+// plain hex ID. Chunk IDs arrive over the network, so this is what stops
+// a request for "../../etc/passwd" from escaping the data directory.
+func (s *Store) path(id string) (string, error) {
+	if !validChunkID(id) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidChunkID, id)
+	}
+	return filepath.Join(s.dir, id), nil
+}
+
+// validChunkID reports whether id is a non-empty lowercase hex string.
+func validChunkID(id string) bool {
+	if id == "" || len(id) > maxChunkIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
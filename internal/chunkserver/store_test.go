@@ -0,0 +1,225 @@
+package chunkserver
+
+import (
+	"bytes"
+	"errors"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// storeChunk writes data as a chunk through the staging area.
+func storeChunk(t *testing.T, store *Store, id string, data []byte) {
+	t.Helper()
+	staged, err := store.Create(id)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// In uneven pieces, so blocks straddle writes
+	for rest := data; len(rest) > 0; {
+		n := min(len(rest), 10007)
+		if _, err := staged.Write(rest[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := staged.Commit(crc32.Checksum(data, castagnoli)); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+}
+
+// readRange reads a range of a chunk through RangeReader.
+func readRange(store *Store, id string, offset, length int64) ([]byte, error) {
+	file, err := store.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	r, err := store.RangeReader(id, file, info.Size(), offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRangeReaderReturnsRanges(t *testing.T) {
+	store, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5*checksumBlockSize+123)
+	rand.New(rand.NewSource(1)).Read(data)
+	storeChunk(t, store, "ab", data)
+
+	size := int64(len(data))
+	for _, r := range []struct{ offset, length int64 }{
+		{0, 0},                          // Whole chunk
+		{0, 10},                         // Start of the first block
+		{checksumBlockSize - 5, 10},     // Across a block boundary
+		{2 * checksumBlockSize, 0},      // From a block boundary to the end
+		{size - 100, 0},                 // Inside the short last block
+		{size - 100, 1000},              // Past the end: cut short
+		{3*checksumBlockSize + 1, 4096}, // Inside one block
+		{size, 0},                       // Nothing
+	} {
+		got, err := readRange(store, "ab", r.offset, r.length)
+		if err != nil {
+			t.Fatalf("range %d+%d: %v", r.offset, r.length, err)
+		}
+		end := size
+		if r.length > 0 {
+			end = min(r.offset+r.length, size)
+		}
+		if !bytes.Equal(got, data[r.offset:end]) {
+			t.Errorf("range %d+%d: got %d bytes, not the chunk's", r.offset, r.length, len(got))
+		}
+	}
+}
+
+func TestRangeReaderChecksOnlyBlocksRead(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 4*checksumBlockSize)
+	rand.New(rand.NewSource(2)).Read(data)
+	storeChunk(t, store, "cd", data)
+
+	// Flip a byte in the third block
+	path := filepath.Join(dir, "cd")
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteAt([]byte{data[2*checksumBlockSize+7] ^ 1}, 2*checksumBlockSize+7)
+	f.Close()
+
+	// A range clear of it reads fine
+	if _, err := readRange(store, "cd", 0, 2*checksumBlockSize); err != nil {
+		t.Fatalf("read of good blocks failed: %v", err)
+	}
+
+	// One reaching it fails, and the chunk is quarantined
+	if _, err := readRange(store, "cd", checksumBlockSize, 2*checksumBlockSize); !errors.Is(err, ErrChunkCorrupt) {
+		t.Fatalf("read of corrupt block: err = %v, want ErrChunkCorrupt", err)
+	}
+	if _, err := store.Open("cd"); !errors.Is(err, ErrChunkNotFound) {
+		t.Errorf("corrupt chunk still served: Open err = %v", err)
+	}
+	if ids, _, _ := store.List(); len(ids) != 0 {
+		t.Errorf("corrupt chunk still listed: %v", ids)
+	}
+}
+
+func TestRangeReaderVerifiesOldChunksWhole(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("godfs"), 50000)
+	storeChunk(t, store, "ef", data)
+
+	// A chunk from before block checksums has only its whole-chunk CRC
+	os.Remove(filepath.Join(dir, "ef"+blockChecksumSuffix))
+	got, err := readRange(store, "ef", 100, 50)
+	if err != nil || !bytes.Equal(got, data[100:150]) {
+		t.Fatalf("read of old chunk: %q, %v", got, err)
+	}
+
+	os.WriteFile(filepath.Join(dir, "ef"), data[:1000], 0644)
+	if _, err := readRange(store, "ef", 0, 10); !errors.Is(err, ErrChunkCorrupt) {
+		t.Fatalf("read of damaged old chunk: err = %v, want ErrChunkCorrupt", err)
+	}
+}
+
+func TestVerifyQuarantinesCorruptChunks(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("godfs"), 1000)
+	storeChunk(t, store, "ab", data)
+
+	if crc, ok, err := store.Checksum("ab"); err != nil || !ok || crc != crc32.Checksum(data, castagnoli) {
+		t.Fatalf("Checksum = %08x, %v, %v; want the data's CRC-32C", crc, ok, err)
+	}
+	if err := store.Verify("ab"); err != nil {
+		t.Fatalf("Verify of a good chunk: %v", err)
+	}
+
+	// Damage on disk is caught, and the chunk set aside with its
+	// checksums gone
+	path := filepath.Join(dir, "ab")
+	data[10] ^= 1
+	os.WriteFile(path, data, 0644)
+	if err := store.Verify("ab"); !errors.Is(err, ErrChunkCorrupt) {
+		t.Fatalf("Verify of a damaged chunk: err = %v, want ErrChunkCorrupt", err)
+	}
+	for _, name := range []string{"ab", "ab" + checksumSuffix, "ab" + blockChecksumSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left after quarantine: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(path + corruptSuffix); err != nil {
+		t.Errorf("quarantined chunk not kept: %v", err)
+	}
+}
+
+func TestFailedCommitLeavesNoChecksums(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := bytes.Repeat([]byte("godfs"), 30000)
+
+	// commitLost stages data as chunk id, and loses the staged file just
+	// before committing it, so the commit fails at the last step
+	commitLost := func(id string) {
+		t.Helper()
+		staged, err := store.Create(id)
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		if _, err := staged.Write(data); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		os.Remove(staged.Name())
+		if err := staged.Commit(crc32.Checksum(data, castagnoli)); err == nil {
+			t.Fatal("Commit of a lost staged file succeeded")
+		}
+	}
+
+	// Nothing of a failed commit shows under the chunk's name, nor is
+	// anything left in staging
+	commitLost("ab")
+	for _, name := range []string{"ab", "ab" + checksumSuffix, "ab" + blockChecksumSuffix} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s left by a failed commit: %v", name, err)
+		}
+	}
+	if left, _ := os.ReadDir(filepath.Join(dir, stagingDirName)); len(left) != 0 {
+		t.Errorf("failed commit left %d files in staging", len(left))
+	}
+
+	// A chunk already stored is still whole after another write of it
+	// fails
+	storeChunk(t, store, "cd", data)
+	commitLost("cd")
+	if err := store.Verify("cd"); err != nil {
+		t.Errorf("Verify after a failed second commit: %v", err)
+	}
+	if got, err := readRange(store, "cd", 0, 0); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("read after a failed second commit: %d bytes, %v", len(got), err)
+	}
+}
@@ -0,0 +1,135 @@
+// Package compression implements the codecs chunks can be stored with,
+// and that RPC messages can be sent with (see grpc.go). It is shared by
+// the master, which compresses chunks as it writes them, and by everything
+// that reads them back: the master and the CLI client.
+//
+// A codec is named by a string, and stored with each chunk's metadata:
+// "" (None) for data stored as is, "gzip" or "zstd".
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// The codecs. None is the empty string so that chunks written before
+// compression existed, which record no codec, read as uncompressed.
+const (
+	None = ""
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Parse checks a codec name as users write it, with "none" for None.
+func Parse(name string) (string, error) {
+	switch name {
+	case "none":
+		return None, nil
+	case Gzip, Zstd:
+		return name, nil
+	default:
+		return "", fmt.Errorf("unknown compression %q (want 'zstd', 'gzip' or 'none')", name)
+	}
+}
+
+// Name returns the name Parse accepts for a codec.
+func Name(codec string) string {
+	if codec == None {
+		return "none"
+	}
+	return codec
+}
+
+// Encoders are expensive to set up - zstd's allocates its tables and
+// windows - and a busy master compresses many chunks at once, so they are
+// pooled rather than made for each chunk.
+var (
+	gzipWriters = sync.Pool{New: func() any { return gzip.NewWriter(nil) }}
+	zstdWriters = sync.Pool{New: func() any {
+		// Only fails on bad options
+		w, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		return w
+	}}
+)
+
+// encoder is what gzip.Writer and zstd.Encoder have in common.
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// pooledWriter returns its encoder to the pool once closed.
+type pooledWriter struct {
+	encoder
+	pool *sync.Pool
+}
+
+// Close flushes what is left of the compressed stream to the underlying
+// writer. The writer can't be used afterwards.
+func (w *pooledWriter) Close() error {
+	err := w.encoder.Close()
+	w.encoder.Reset(nil)
+	w.pool.Put(w.encoder)
+	w.encoder = nil
+	return err
+}
+
+// NewWriter returns a writer that compresses what is written to it with
+// codec, and writes the result to w. It must be closed to finish the
+// compressed stream; closing it doesn't close w.
+func NewWriter(codec string, w io.Writer) (io.WriteCloser, error) {
+	var pool *sync.Pool
+	switch codec {
+	case Gzip:
+		pool = &gzipWriters
+	case Zstd:
+		pool = &zstdWriters
+	default:
+		return nil, fmt.Errorf("can't compress with codec %q", codec)
+	}
+	enc := pool.Get().(encoder)
+	enc.Reset(w)
+	return &pooledWriter{encoder: enc, pool: pool}, nil
+}
+
+// NewReader returns a reader that decompresses data read from r, which
+// was compressed with codec. Both codecs check their own checksum of the
+// data at the end of the stream, so corruption shows up as an error.
+func NewReader(codec string, r io.Reader) (io.ReadCloser, error) {
+	switch codec {
+	case Gzip:
+		return gzip.NewReader(r)
+	case Zstd:
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	default:
+		return nil, fmt.Errorf("can't decompress codec %q", codec)
+	}
+}
+
+// Worthwhile reports whether compressing data like sample with codec
+// pays: whether it saves at least an eighth of the space. Data that is
+// already compressed (archives, media, encrypted files) typically grows a
+// little instead, and isn't worth the CPU it takes to read it back.
+func Worthwhile(codec string, sample []byte) bool {
+	if codec == None || len(sample) == 0 {
+		return false
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(codec, &buf)
+	if err != nil {
+		return false
+	}
+	w.Write(sample) // Writes to a bytes.Buffer don't fail
+	w.Close()
+	return buf.Len() <= len(sample)-len(sample)/8
+}
@@ -0,0 +1,156 @@
+package compression
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// compress returns data compressed with codec.
+func compress(t *testing.T, codec string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(codec, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter(%s): %v", codec, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// decompress returns data compressed with codec, decompressed.
+func decompress(codec string, data []byte) ([]byte, error) {
+	r, err := NewReader(codec, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 300*1024)
+	rand.New(rand.NewSource(1)).Read(random)
+	text := bytes.Repeat([]byte("GET /index.html 200\n"), 20000)
+
+	for _, codec := range []string{Gzip, Zstd} {
+		for name, data := range map[string][]byte{"empty": nil, "random": random, "text": text} {
+			// Twice, so the second time round uses a pooled encoder
+			for range 2 {
+				stored := compress(t, codec, data)
+				got, err := decompress(codec, stored)
+				if err != nil {
+					t.Fatalf("%s, %s: decompress: %v", codec, name, err)
+				}
+				if !bytes.Equal(got, data) {
+					t.Fatalf("%s, %s: got %d bytes back, not the %d written", codec, name, len(got), len(data))
+				}
+			}
+		}
+		if stored := compress(t, codec, text); len(stored) > len(text)/10 {
+			t.Errorf("%s: text compressed to %d of %d bytes", codec, len(stored), len(text))
+		}
+	}
+}
+
+func TestCorruptionIsCaught(t *testing.T) {
+	data := bytes.Repeat([]byte("godfs chunk data "), 10000)
+	for _, codec := range []string{Gzip, Zstd} {
+		stored := compress(t, codec, data)
+
+		// Cut short, the stream never reaches its checksum
+		if got, err := decompress(codec, stored[:len(stored)-8]); err == nil {
+			t.Errorf("%s: truncated stream read back %d bytes without error", codec, len(got))
+		}
+
+		// The checksum at the end covers the data
+		damaged := bytes.Clone(stored)
+		damaged[len(damaged)-3] ^= 0xff
+		if got, err := decompress(codec, damaged); err == nil && bytes.Equal(got, data) {
+			t.Errorf("%s: damaged checksum went unnoticed", codec)
+		}
+	}
+}
+
+func TestWorthwhile(t *testing.T) {
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(2)).Read(random)
+	text := bytes.Repeat([]byte("lorem ipsum dolor sit amet "), 3000)
+
+	for _, tc := range []struct {
+		codec  string
+		sample []byte
+		want   bool
+	}{
+		{Zstd, text, true},
+		{Gzip, text, true},
+		{Zstd, random, false},
+		{Gzip, random, false},
+		{Zstd, nil, false},
+		{None, text, false},
+	} {
+		if got := Worthwhile(tc.codec, tc.sample); got != tc.want {
+			t.Errorf("Worthwhile(%q, %d bytes) = %v, want %v", tc.codec, len(tc.sample), got, tc.want)
+		}
+	}
+}
+
+func TestGRPCCompressors(t *testing.T) {
+	text := bytes.Repeat([]byte("GET /index.html 200\n"), 5000)
+	messages := [][]byte{text, nil, text[:100], bytes.ToUpper(text)}
+
+	for _, name := range []string{Gzip, Zstd} {
+		c := encoding.GetCompressor(name)
+		if c == nil {
+			t.Fatalf("no gRPC compressor registered as %q", name)
+		}
+
+		// Messages compress one after another, as on a stream
+		var compressed [][]byte
+		for _, msg := range messages {
+			var buf bytes.Buffer
+			w, err := c.Compress(&buf)
+			if err != nil {
+				t.Fatalf("%s: Compress: %v", name, err)
+			}
+			if _, err := w.Write(msg); err != nil {
+				t.Fatalf("%s: Write: %v", name, err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("%s: Close: %v", name, err)
+			}
+			compressed = append(compressed, buf.Bytes())
+		}
+		if len(compressed[0]) > len(text)/10 {
+			t.Errorf("%s: message compressed to %d of %d bytes", name, len(compressed[0]), len(text))
+		}
+
+		// and decompress with several readers open at once, each read to
+		// its end and no further, as gRPC does
+		var readers []io.Reader
+		for _, msg := range compressed {
+			r, err := c.Decompress(bytes.NewReader(msg))
+			if err != nil {
+				t.Fatalf("%s: Decompress: %v", name, err)
+			}
+			readers = append(readers, r)
+		}
+		for i, r := range readers {
+			got, err := io.ReadAll(r)
+			if err != nil || !bytes.Equal(got, messages[i]) {
+				t.Errorf("%s: message %d read back as %d bytes, %v; want its %d", name, i, len(got), err, len(messages[i]))
+			}
+			if n, err := r.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+				t.Errorf("%s: read past the end of message %d: %d, %v; want EOF", name, i, n, err)
+			}
+		}
+	}
+}
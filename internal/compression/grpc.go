@@ -0,0 +1,73 @@
+package compression
+
+import (
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+	"google.golang.org/grpc/encoding"
+
+	// Registers gRPC's own gzip compressor, alongside our zstd one
+	_ "google.golang.org/grpc/encoding/gzip"
+)
+
+// Besides compressing chunks at rest, the codecs can compress RPC messages
+// on the wire, which pays on slow links. gRPC negotiates this per call: a
+// client asks for a compressor by name, and a server that has it
+// registered decompresses the request and compresses its responses the
+// same way. Every binary that talks gRPC imports this package (through
+// chunkclient, at least), so every server understands both.
+
+// grpcZstd is a gRPC compressor using zstd.
+type grpcZstd struct{}
+
+func init() {
+	encoding.RegisterCompressor(grpcZstd{})
+}
+
+// Name is the name clients ask for: "zstd".
+func (grpcZstd) Name() string {
+	return Zstd
+}
+
+// Compress returns a writer compressing a message into w.
+func (grpcZstd) Compress(w io.Writer) (io.WriteCloser, error) {
+	return NewWriter(Zstd, w)
+}
+
+// zstdReaders pools decoders for messages. Unlike NewReader's callers,
+// gRPC never closes the readers it gets from Decompress; it just reads
+// them to the end, which is when the decoder goes back to the pool.
+var zstdReaders = sync.Pool{New: func() any {
+	// Only fails on bad options
+	d, _ := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	return d
+}}
+
+// Decompress returns a reader decompressing a message read from r.
+func (grpcZstd) Decompress(r io.Reader) (io.Reader, error) {
+	d := zstdReaders.Get().(*zstd.Decoder)
+	if err := d.Reset(r); err != nil {
+		zstdReaders.Put(d)
+		return nil, err
+	}
+	return &messageReader{d: d}, nil
+}
+
+// messageReader reads one message with a pooled decoder.
+type messageReader struct {
+	d *zstd.Decoder
+}
+
+func (r *messageReader) Read(p []byte) (int, error) {
+	if r.d == nil {
+		return 0, io.EOF
+	}
+	n, err := r.d.Read(p)
+	if err == io.EOF {
+		r.d.Reset(nil) // Drops r; the error just says so
+		zstdReaders.Put(r.d)
+		r.d = nil
+	}
+	return n, err
+}
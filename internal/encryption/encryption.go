@@ -0,0 +1,178 @@
+// Package encryption seals chunk data with AES-GCM. It is shared by the
+// master, which encrypts chunks as it writes them, and by everything that
+// reads them back: the master and the CLI client.
+//
+// Each file has its own random data key (see the master's keys.go for how
+// those are kept). A chunk isn't encrypted with the data key directly, but
+// with a key derived from it and the chunk's ID, so no two chunks ever
+// share a key - chunk IDs are random and never reused - and a chunk can't
+// be passed off as another.
+//
+// AES-GCM seals a whole message at once, and a chunk can be 64MB, so a
+// chunk is sealed as a sequence of segments of SegmentSize bytes (the last
+// may be shorter), each with its own authentication tag. The nonce is the
+// segment's index, plus a flag on the last one; that way segments can't be
+// reordered, and a chunk cut short at a segment boundary doesn't pass for
+// a complete one. It also makes the stored chunk seekable: reading a range
+// of it only takes the segments the range covers.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// KeySize is the size of data keys: AES-256.
+const KeySize = 32
+
+// SegmentSize is how much data each sealed segment holds; Overhead is
+// what sealing adds to each.
+const (
+	SegmentSize = 64 * 1024
+	Overhead    = 16
+)
+
+// ErrCorrupt is returned when stored data fails authentication: it was
+// damaged, tampered with, or cut short, or the key is wrong.
+var ErrCorrupt = errors.New("encrypted chunk is corrupt")
+
+// newAEAD returns the AES-GCM cipher for one chunk.
+func newAEAD(key []byte, chunkID string) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("data key is %d bytes, want %d", len(key), KeySize)
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(chunkID))
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// nonce returns the nonce for segment i, marking the last one.
+func nonce(i int64, last bool) []byte {
+	n := make([]byte, 12)
+	binary.BigEndian.PutUint64(n[3:11], uint64(i))
+	if last {
+		n[11] = 1
+	}
+	return n
+}
+
+// Segments returns how many segments a chunk takes storedSize bytes for.
+func Segments(storedSize int64) int64 {
+	return (storedSize + SegmentSize + Overhead - 1) / (SegmentSize + Overhead)
+}
+
+// Writer encrypts a chunk's data on its way to an underlying writer.
+type Writer struct {
+	aead cipher.AEAD
+	w    io.Writer
+	buf  []byte // Data waiting for a full segment
+	next int64  // Index of the next segment
+}
+
+// NewWriter returns a Writer sealing data with key for the chunk chunkID
+// and writing it to w. It must be closed to write the last segment.
+func NewWriter(key []byte, chunkID string, w io.Writer) (*Writer, error) {
+	aead, err := newAEAD(key, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer{aead: aead, w: w, buf: make([]byte, 0, SegmentSize)}, nil
+}
+
+// Write encrypts data. A segment is only written once the next one has
+// begun, since until then we can't tell whether it is the last.
+func (w *Writer) Write(data []byte) (int, error) {
+	n := len(data)
+	for len(data) > 0 {
+		if len(w.buf) == SegmentSize {
+			if err := w.seal(false); err != nil {
+				return 0, err
+			}
+		}
+		m := copy(w.buf[len(w.buf):SegmentSize], data)
+		w.buf = w.buf[:len(w.buf)+m]
+		data = data[m:]
+	}
+	return n, nil
+}
+
+// Close writes the last segment. It doesn't close the underlying writer.
+func (w *Writer) Close() error {
+	return w.seal(true)
+}
+
+// seal writes the buffered data as a segment.
+func (w *Writer) seal(last bool) error {
+	sealed := w.aead.Seal(nil, nonce(w.next, last), w.buf, nil)
+	w.next++
+	w.buf = w.buf[:0]
+	_, err := w.w.Write(sealed)
+	return err
+}
+
+// Reader decrypts a chunk's segments as it reads them.
+type Reader struct {
+	aead  cipher.AEAD
+	r     io.Reader
+	next  int64 // Index of the next segment
+	total int64 // Segments in the chunk
+	seg   []byte
+	plain []byte // Decrypted data not yet read
+}
+
+// NewReader returns a Reader for the chunk chunkID, sealed with key and
+// stored in storedSize bytes, reading its segments from r starting with
+// segment first (so r must start at byte first*(SegmentSize+Overhead)).
+func NewReader(key []byte, chunkID string, r io.Reader, first, storedSize int64) (*Reader, error) {
+	aead, err := newAEAD(key, chunkID)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{
+		aead:  aead,
+		r:     r,
+		next:  first,
+		total: Segments(storedSize),
+		seg:   make([]byte, SegmentSize+Overhead),
+	}, nil
+}
+
+// Read returns decrypted data. Each segment is authenticated before any
+// of it is returned.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.next >= r.total {
+			return 0, io.EOF
+		}
+		n, err := io.ReadFull(r.r, r.seg)
+		last := r.next == r.total-1
+		if err == io.ErrUnexpectedEOF || err == io.EOF {
+			// Only the last segment may be short
+			if !last {
+				return 0, fmt.Errorf("%w: segment %d is truncated", ErrCorrupt, r.next)
+			}
+		} else if err != nil {
+			return 0, err
+		}
+
+		r.plain, err = r.aead.Open(r.seg[:0], nonce(r.next, last), r.seg[:n], nil)
+		if err != nil {
+			return 0, fmt.Errorf("%w: segment %d fails authentication", ErrCorrupt, r.next)
+		}
+		r.next++
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
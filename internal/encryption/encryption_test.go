@@ -0,0 +1,114 @@
+package encryption
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+)
+
+// seal encrypts data for chunkID, writing it in uneven pieces.
+func seal(t *testing.T, key []byte, chunkID string, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := NewWriter(key, chunkID, &buf)
+	if err != nil {
+		t.Fatalf("NewWriter: %v", err)
+	}
+	for rest := data; len(rest) > 0; {
+		n := min(len(rest), 10007)
+		if _, err := w.Write(rest[:n]); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+		rest = rest[n:]
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// open decrypts a chunk stored as stored, from segment first on.
+func open(key []byte, chunkID string, stored []byte, first int64) ([]byte, error) {
+	start := min(first*(SegmentSize+Overhead), int64(len(stored)))
+	r, err := NewReader(key, chunkID, bytes.NewReader(stored[start:]), first, int64(len(stored)))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func testKey(seed int64) []byte {
+	key := make([]byte, KeySize)
+	rand.New(rand.NewSource(seed)).Read(key)
+	return key
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := testKey(1)
+	for _, size := range []int{0, 1, SegmentSize - 1, SegmentSize, SegmentSize + 1, 3*SegmentSize + 123} {
+		data := make([]byte, size)
+		rand.New(rand.NewSource(int64(size))).Read(data)
+		stored := seal(t, key, "chunk", data)
+
+		// Every segment, even an empty last one, carries a tag
+		segments := int64(size/SegmentSize + 1)
+		if size > 0 && size%SegmentSize == 0 {
+			segments--
+		}
+		if want := int64(size) + segments*Overhead; int64(len(stored)) != want {
+			t.Errorf("%d bytes sealed into %d, want %d", size, len(stored), want)
+		}
+		if got := Segments(int64(len(stored))); got != segments {
+			t.Errorf("Segments(%d) = %d, want %d", len(stored), got, segments)
+		}
+
+		if size > 0 && bytes.Contains(stored, data[:min(size, 32)]) {
+			t.Errorf("%d bytes sealed: plaintext shows through", size)
+		}
+
+		// Reading can start at any segment
+		for first := int64(0); first < segments; first++ {
+			got, err := open(key, "chunk", stored, first)
+			if err != nil {
+				t.Fatalf("%d bytes, from segment %d: %v", size, first, err)
+			}
+			if !bytes.Equal(got, data[first*SegmentSize:]) {
+				t.Errorf("%d bytes, from segment %d: got %d bytes back, not what was sealed", size, first, len(got))
+			}
+		}
+	}
+}
+
+func TestTamperingIsCaught(t *testing.T) {
+	key := testKey(2)
+	data := make([]byte, 3*SegmentSize+500)
+	rand.New(rand.NewSource(3)).Read(data)
+	stored := seal(t, key, "chunk", data)
+	sealed := int64(SegmentSize + Overhead)
+
+	swapped := bytes.Clone(stored)
+	copy(swapped[:sealed], stored[sealed:2*sealed])
+	copy(swapped[sealed:2*sealed], stored[:sealed])
+	flipped := bytes.Clone(stored)
+	flipped[sealed+100] ^= 1
+
+	for _, tc := range []struct {
+		name    string
+		key     []byte
+		chunkID string
+		stored  []byte
+	}{
+		{"flipped bit", key, "chunk", flipped},
+		{"segments swapped", key, "chunk", swapped},
+		{"cut at a segment boundary", key, "chunk", stored[:3*sealed]},
+		{"cut inside a segment", key, "chunk", stored[:2*sealed+10]},
+		{"another chunk's data", key, "other", stored},
+		{"wrong key", testKey(4), "chunk", stored},
+	} {
+		if _, err := open(tc.key, tc.chunkID, tc.stored, 0); !errors.Is(err, ErrCorrupt) {
+			t.Errorf("%s: err = %v, want ErrCorrupt", tc.name, err)
+		}
+	}
+}
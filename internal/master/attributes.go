@@ -0,0 +1,135 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Files carry user metadata - key/value attributes and tags - so that
+// things like the owner or the build that produced a file can be recorded
+// alongside it rather than squeezed into its name, and listings can be
+// filtered on them.
+//
+// All of it lives in the master's memory and in every metadata mutation,
+// so it is kept small.
+const (
+	maxAttributes           = 64
+	maxAttributeKeyLength   = 128
+	maxAttributeValueLength = 1024
+	maxTags                 = 64
+	maxTagLength            = 128
+)
+
+// ErrInvalidAttributes is returned for user metadata outside the limits.
+var ErrInvalidAttributes = errors.New("invalid attributes")
+
+// checkAttributes checks user metadata against the limits.
+func checkAttributes(attributes map[string]string, tags []string) error {
+	if len(attributes) > maxAttributes {
+		return fmt.Errorf("%w: more than %d attributes", ErrInvalidAttributes, maxAttributes)
+	}
+	for key, value := range attributes {
+		switch {
+		case key == "":
+			return fmt.Errorf("%w: empty attribute key", ErrInvalidAttributes)
+		case len(key) > maxAttributeKeyLength:
+			return fmt.Errorf("%w: attribute key longer than %d bytes", ErrInvalidAttributes, maxAttributeKeyLength)
+		case len(value) > maxAttributeValueLength:
+			return fmt.Errorf("%w: value of %q longer than %d bytes", ErrInvalidAttributes, key, maxAttributeValueLength)
+		case !utf8.ValidString(key) || !utf8.ValidString(value):
+			return fmt.Errorf("%w: %q is not valid UTF-8", ErrInvalidAttributes, key)
+		}
+	}
+
+	if len(tags) > maxTags {
+		return fmt.Errorf("%w: more than %d tags", ErrInvalidAttributes, maxTags)
+	}
+	for _, tag := range tags {
+		switch {
+		case tag == "":
+			return fmt.Errorf("%w: empty tag", ErrInvalidAttributes)
+		case len(tag) > maxTagLength:
+			return fmt.Errorf("%w: tag longer than %d bytes", ErrInvalidAttributes, maxTagLength)
+		case !utf8.ValidString(tag):
+			return fmt.Errorf("%w: tag %q is not valid UTF-8", ErrInvalidAttributes, tag)
+		}
+	}
+	return nil
+}
+
+// sortTags returns tags sorted and without duplicates, the form they are
+// stored in. It returns nil for no tags.
+func sortTags(tags []string) []string {
+	if len(tags) == 0 {
+		return nil
+	}
+	sorted := slices.Clone(tags)
+	slices.Sort(sorted)
+	return slices.Compact(sorted)
+}
+
+// AttributeChange is an edit to a file's user metadata.
+type AttributeChange struct {
+	Set        map[string]string // Attributes to add or change
+	Remove     []string          // Attribute keys to remove
+	AddTags    []string
+	RemoveTags []string
+}
+
+// apply makes the change to meta. Removals win over additions of the same
+// key or tag.
+func (c AttributeChange) apply(meta *FileMeta) {
+	for key, value := range c.Set {
+		if meta.Attributes == nil {
+			meta.Attributes = make(map[string]string)
+		}
+		meta.Attributes[key] = value
+	}
+	for _, key := range c.Remove {
+		delete(meta.Attributes, key)
+	}
+	if len(meta.Attributes) == 0 {
+		meta.Attributes = nil
+	}
+
+	tags := append(meta.Tags, c.AddTags...)
+	tags = slices.DeleteFunc(tags, func(tag string) bool {
+		return slices.Contains(c.RemoveTags, tag)
+	})
+	meta.Tags = sortTags(tags)
+}
+
+// SetAttributes changes a file's or directory's user metadata in place.
+func (s *Server) SetAttributes(ctx context.Context, req *api.SetAttributesRequest) (*api.SetAttributesResponse, error) {
+	filename, err := cleanPath(req.Filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+	if filename == rootDir {
+		return nil, metadataStatus(fmt.Errorf("the root directory has no attributes: %w", ErrInvalidPath))
+	}
+
+	s.changes.Lock()
+	defer s.changes.Unlock()
+	meta, err := s.metadata.SetAttributes(filename, AttributeChange{
+		Set:        req.Set,
+		Remove:     req.Remove,
+		AddTags:    req.AddTags,
+		RemoveTags: req.RemoveTags,
+	})
+	if err != nil {
+		return nil, metadataStatus(fmt.Errorf("failed to set attributes: %w", err))
+	}
+	s.publish(api.WatchEventType_WATCH_EVENT_ATTRIBUTES, meta, "")
+
+	return &api.SetAttributesResponse{
+		Success: true,
+		Message: fmt.Sprintf("Attributes of '%s' updated", filename),
+		File:    fileInfo(meta),
+	}, nil
+}
@@ -0,0 +1,127 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+func TestSetAttributes(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	createFile(t, s.metadata, "/f", 1)
+	set := func(req *api.SetAttributesRequest) (*api.FileInfo, error) {
+		resp, err := s.SetAttributes(ctx, req)
+		return resp.GetFile(), err
+	}
+	want := func(f *api.FileInfo, attributes map[string]string, tags ...string) {
+		t.Helper()
+		if !maps.Equal(f.Attributes, attributes) || !slices.Equal(f.Tags, tags) {
+			t.Errorf("attributes %v and tags %v, want %v and %v", f.Attributes, f.Tags, attributes, tags)
+		}
+	}
+
+	// Tags are kept sorted, once each
+	f, err := set(&api.SetAttributesRequest{
+		Filename: "/f",
+		Set:      map[string]string{"owner": "ml", "build": "41"},
+		AddTags:  []string{"prod", "gpu", "prod"},
+	})
+	if err != nil {
+		t.Fatalf("SetAttributes: %v", err)
+	}
+	want(f, map[string]string{"owner": "ml", "build": "41"}, "gpu", "prod")
+
+	// Edits leave what they don't mention alone; a removal wins over an
+	// addition of the same key or tag
+	f, err = set(&api.SetAttributesRequest{
+		Filename:   "/f",
+		Set:        map[string]string{"build": "42", "tmp": "x"},
+		Remove:     []string{"tmp"},
+		AddTags:    []string{"new"},
+		RemoveTags: []string{"new", "gpu"},
+	})
+	if err != nil {
+		t.Fatalf("SetAttributes: %v", err)
+	}
+	want(f, map[string]string{"owner": "ml", "build": "42"}, "prod")
+
+	// It isn't a new version of the file
+	if f.Generation != 1 {
+		t.Errorf("generation %d after setting attributes, want 1", f.Generation)
+	}
+
+	// Past the limits, nothing changes
+	tooMany := make([]string, maxTags)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprint("tag", i)
+	}
+	for _, req := range []*api.SetAttributesRequest{
+		{Filename: "/f", AddTags: tooMany},
+		{Filename: "/f", Set: map[string]string{"": "x"}},
+		{Filename: "/f", Set: map[string]string{"k": strings.Repeat("x", maxAttributeValueLength+1)}},
+		{Filename: "/f", AddTags: []string{"bad\xff"}},
+	} {
+		if _, err := set(req); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("SetAttributes(%v): err = %v, want InvalidArgument", req, err)
+		}
+	}
+	meta, err := s.metadata.Get("/f")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	want(fileInfo(meta), map[string]string{"owner": "ml", "build": "42"}, "prod")
+
+	// Directories have them too, but not the root, or missing files
+	mkdirs(t, s.metadata, "/d")
+	if f, err := set(&api.SetAttributesRequest{Filename: "/d", AddTags: []string{"x"}}); err != nil || !f.IsDir {
+		t.Errorf("SetAttributes on a directory: %v", err)
+	}
+	if _, err := set(&api.SetAttributesRequest{Filename: "/", AddTags: []string{"x"}}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("SetAttributes on the root: err = %v, want InvalidArgument", err)
+	}
+	if _, err := set(&api.SetAttributesRequest{Filename: "/missing", AddTags: []string{"x"}}); status.Code(err) != codes.NotFound {
+		t.Errorf("SetAttributes on a missing file: err = %v, want NotFound", err)
+	}
+}
+
+func TestListFiltersOnAttributes(t *testing.T) {
+	s := newTestServer(t, Config{})
+	for name, change := range map[string]AttributeChange{
+		"/a": {Set: map[string]string{"owner": "ml", "build": "41"}, AddTags: []string{"prod"}},
+		"/b": {Set: map[string]string{"owner": "ml"}, AddTags: []string{"prod", "gpu"}},
+		"/c": {Set: map[string]string{"owner": "web"}},
+		"/d": {},
+	} {
+		createFile(t, s.metadata, name, 1)
+		if _, err := s.metadata.SetAttributes(name, change); err != nil {
+			t.Fatalf("SetAttributes: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		attributes map[string]string
+		tags       []string
+		want       []string
+	}{
+		{attributes: map[string]string{"owner": "ml"}, want: []string{"/a", "/b"}},
+		{attributes: map[string]string{"build": ""}, want: []string{"/a"}},
+		{attributes: map[string]string{"owner": ""}, want: []string{"/a", "/b", "/c"}},
+		{tags: []string{"prod"}, want: []string{"/a", "/b"}},
+		{tags: []string{"prod", "gpu"}, want: []string{"/b"}},
+		{attributes: map[string]string{"owner": "web"}, tags: []string{"prod"}},
+	} {
+		got := listAll(t, s, &api.ListRequest{Attributes: tc.attributes, Tags: tc.tags})
+		if !slices.Equal(got, tc.want) {
+			t.Errorf("listing with attributes %v and tags %v = %v, want %v", tc.attributes, tc.tags, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package master
+
+import (
+	"fmt"
+	"math/bits"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Chunking is how an upload is cut into chunks.
+//
+// Fixed chunking cuts every ChunkSize bytes. It is as cheap as it gets, but
+// inserting a single byte near the start of a file shifts every boundary
+// after it, so no chunk of the new file matches one of the old, and content
+// addressing (see dedupChunk) finds nothing to share.
+//
+// Content-defined chunking (CDC) instead cuts where the data itself says
+// to: wherever a hash of the last few dozen bytes has a particular bit
+// pattern. An edit only moves the boundaries right around it, and the
+// chunks after those line up with the old file's again. That makes it the
+// mode for large files that change a little at a time, like VM images and
+// database dumps, at the cost of hashing every byte and of chunks averaging
+// a fraction of ChunkSize (so more of them to track).
+type Chunking int
+
+const (
+	FixedChunking Chunking = iota
+	ContentDefinedChunking
+)
+
+// DefaultCDCAverageSize is the average chunk size content-defined chunking
+// aims for (1MB). Smaller chunks find more duplicate data, bigger ones
+// keep the metadata small.
+const DefaultCDCAverageSize = 1024 * 1024
+
+// ParseChunking parses a chunking mode: "fixed" or "cdc".
+func ParseChunking(s string) (Chunking, error) {
+	switch s {
+	case "fixed":
+		return FixedChunking, nil
+	case "cdc":
+		return ContentDefinedChunking, nil
+	default:
+		return 0, fmt.Errorf("unknown chunking mode %q (want 'fixed' or 'cdc')", s)
+	}
+}
+
+// String returns the name ParseChunking accepts.
+func (c Chunking) String() string {
+	if c == ContentDefinedChunking {
+		return "cdc"
+	}
+	return "fixed"
+}
+
+// chunkingFor returns the chunking an upload asked for, or the server's
+// default if it didn't.
+func (s *Server) chunkingFor(mode api.ChunkingMode) (Chunking, error) {
+	switch mode {
+	case api.ChunkingMode_CHUNKING_MODE_DEFAULT:
+		return s.chunking, nil
+	case api.ChunkingMode_CHUNKING_MODE_FIXED:
+		return FixedChunking, nil
+	case api.ChunkingMode_CHUNKING_MODE_CONTENT_DEFINED:
+		return ContentDefinedChunking, nil
+	default:
+		return 0, fmt.Errorf("unknown chunking mode %v", mode)
+	}
+}
+
+// gear is the table of random values the rolling hash mixes in, one per
+// byte value. It must never change: chunks cut with a different table
+// would no longer line up with those already stored. So it comes from a
+// fixed seed, rather than from anything random.
+var gear = func() (table [256]uint64) {
+	// SplitMix64, a small, well-mixed generator
+	x := uint64(0x676f646673) // "godfs"
+	for i := range table {
+		x += 0x9e3779b97f4a7c15
+		z := x
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+	return table
+}()
+
+// cdcChunker finds content-defined chunk boundaries in a stream of data,
+// with FastCDC (Xia et al., USENIX ATC 2016).
+//
+// The gear hash shifts left one bit per byte and adds that byte's random
+// value, so each bit of the hash depends on the bytes since it was added:
+// the top bits on the last 64 or so. A boundary is wherever enough of
+// those top bits are zero. For chunks of a predictable size, the test is
+// stricter (more bits) until the chunk reaches the average size and
+// looser after, and boundaries are never less than min or more than max
+// bytes apart.
+//
+// A cdcChunker is not safe for concurrent use.
+type cdcChunker struct {
+	min, avg, max int64
+	strict, loose uint64 // Masks for before and after avg
+
+	hash uint64
+	size int64 // Bytes in the current chunk so far
+}
+
+// newCDCChunker returns a chunker aiming for chunks of avg bytes on
+// average, and at most maxSize.
+func newCDCChunker(avg, maxSize int64) *cdcChunker {
+	avg = max(min(avg, maxSize), 1)
+	n := bits.Len64(uint64(avg)) - 1 // log2(avg), rounded down
+	return &cdcChunker{
+		min:    avg / 4,
+		avg:    avg,
+		max:    maxSize,
+		strict: topBits(n + 2),
+		loose:  topBits(n - 2),
+	}
+}
+
+// topBits returns a mask of the n (at least one) most significant bits.
+func topBits(n int) uint64 {
+	return ^uint64(0) << (64 - min(max(n, 1), 64))
+}
+
+// cut reads data as the continuation of the current chunk and returns how
+// many bytes of it belong to that chunk, and whether they end it. The
+// bytes after a boundary begin the next chunk: call cut again with them.
+func (c *cdcChunker) cut(data []byte) (int, bool) {
+	for i, b := range data {
+		c.hash = c.hash<<1 + gear[b]
+		c.size++
+		if c.size < c.min {
+			continue
+		}
+
+		mask := c.loose
+		if c.size < c.avg {
+			mask = c.strict
+		}
+		if c.hash&mask == 0 || c.size >= c.max {
+			c.reset()
+			return i + 1, true
+		}
+	}
+	return len(data), false
+}
+
+// reset starts a new chunk. The hash only looks at the last 64 bytes
+// anyway, but starting it afresh makes a chunk's boundary depend on its
+// own data alone.
+func (c *cdcChunker) reset() {
+	c.hash = 0
+	c.size = 0
+}
@@ -0,0 +1,109 @@
+package master
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"slices"
+	"testing"
+)
+
+// cdcChunks cuts data into chunks with a fresh chunker, feeding it in
+// pieces of the given size, and returns the chunks.
+func cdcChunks(data []byte, avg, maxSize int64, piece int) [][]byte {
+	c := newCDCChunker(avg, maxSize)
+	var chunks [][]byte
+	start := 0
+	for off := 0; off < len(data); {
+		end := min(off+piece, len(data))
+		for off < end {
+			n, full := c.cut(data[off:end])
+			off += n
+			if full {
+				chunks = append(chunks, data[start:off])
+				start = off
+			}
+		}
+	}
+	if start < len(data) {
+		chunks = append(chunks, data[start:])
+	}
+	return chunks
+}
+
+// chunkHashes returns the SHA-256 of each chunk, as a set.
+func chunkHashes(chunks [][]byte) map[[32]byte]bool {
+	hashes := make(map[[32]byte]bool)
+	for _, chunk := range chunks {
+		hashes[sha256.Sum256(chunk)] = true
+	}
+	return hashes
+}
+
+func TestCDCCutsTheSameWhateverTheWrites(t *testing.T) {
+	data := randomData(1, 2<<20)
+	want := cdcChunks(data, 16*1024, 64*1024, len(data))
+	for _, piece := range []int{1, 1000, 4096, 100000} {
+		got := cdcChunks(data, 16*1024, 64*1024, piece)
+		if !slices.EqualFunc(got, want, bytes.Equal) {
+			t.Errorf("written %d bytes at a time: %d chunks, not the same %d as written whole", piece, len(got), len(want))
+		}
+	}
+}
+
+func TestCDCChunkSizes(t *testing.T) {
+	const avg, maxSize = 16 * 1024, 64 * 1024
+	chunks := cdcChunks(randomData(2, 4<<20), avg, maxSize, 32*1024)
+	for i, chunk := range chunks[:len(chunks)-1] {
+		if len(chunk) < avg/4 || len(chunk) > maxSize {
+			t.Errorf("chunk %d is %d bytes, want %d to %d", i, len(chunk), avg/4, maxSize)
+		}
+	}
+	if mean := (4 << 20) / len(chunks); mean < avg/2 || mean > 2*avg {
+		t.Errorf("chunks average %d bytes, want about %d", mean, avg)
+	}
+
+	// Data with no boundaries in it at all is cut at the maximum
+	for i, chunk := range cdcChunks(make([]byte, 5*maxSize), avg, maxSize, 32*1024) {
+		if len(chunk) != maxSize {
+			t.Errorf("chunk %d of zeros is %d bytes, want %d", i, len(chunk), maxSize)
+		}
+	}
+}
+
+func TestCDCBoundariesSurviveInserts(t *testing.T) {
+	data := randomData(3, 4<<20)
+	before := cdcChunks(data, 16*1024, 64*1024, 32*1024)
+
+	// A few bytes inserted in the middle, and some at the start
+	edited := slices.Concat(data[:2<<20], []byte("inserted"), data[2<<20:])
+	edited = slices.Concat([]byte("header"), edited)
+	after := cdcChunks(edited, 16*1024, 64*1024, 32*1024)
+
+	// Only the chunks around each edit differ; everything else is
+	// shared with the file as it was
+	old := chunkHashes(before)
+	changed := 0
+	for _, chunk := range after {
+		if !old[sha256.Sum256(chunk)] {
+			changed++
+		}
+	}
+	if changed > 4 {
+		t.Errorf("%d of %d chunks changed after two small inserts, want at most 4", changed, len(after))
+	}
+
+	// Fixed-size chunks, by contrast, all move
+	fixed := func(data []byte) [][]byte {
+		var chunks [][]byte
+		for chunk := range slices.Chunk(data, 16*1024) {
+			chunks = append(chunks, chunk)
+		}
+		return chunks
+	}
+	old = chunkHashes(fixed(data))
+	for _, chunk := range fixed(edited) {
+		if old[sha256.Sum256(chunk)] {
+			t.Fatal("a fixed-size chunk survived an insert at the start")
+		}
+	}
+}
@@ -0,0 +1,391 @@
+package master
+
+import (
+	"bufio"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/darshanmadesh/godfs/internal/chunkclient"
+	"github.com/darshanmadesh/godfs/internal/compression"
+	"github.com/darshanmadesh/godfs/internal/encryption"
+)
+
+// DefaultChunkSize is the size files are split into for storage (64MB).
+// Large chunks keep the number of chunks (and so the metadata) small,
+// at the cost of wasting some space on the last chunk of small files.
+const DefaultChunkSize = 64 * 1024 * 1024
+
+// ErrNotEnoughChunkservers is returned when a file asks for more replicas
+// than there are live chunkservers to hold them.
+var ErrNotEnoughChunkservers = errors.New("not enough chunkservers")
+
+// chunkDeleteTimeout bounds best-effort cleanup of chunks on chunkservers.
+const chunkDeleteTimeout = 30 * time.Second
+
+// newChunkID returns a random 128-bit chunk ID, hex encoded.
+// Random IDs never collide in practice, so the master doesn't need to
+// coordinate ID allocation with anyone.
+func newChunkID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate chunk ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
+}
+
+// pendingChunks tracks chunks that exist on chunkservers but aren't in
+// the metadata yet (an upload or repair is still in progress). Heartbeat
+// GC must leave these alone.
+type pendingChunks struct {
+	mu  sync.Mutex
+	ids map[string]int // chunk ID -> number of operations holding it
+}
+
+func newPendingChunks() *pendingChunks {
+	return &pendingChunks{ids: make(map[string]int)}
+}
+
+func (p *pendingChunks) add(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.ids[id]++
+}
+
+func (p *pendingChunks) remove(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.ids[id]--; p.ids[id] <= 0 {
+		delete(p.ids, id)
+	}
+}
+
+func (p *pendingChunks) has(id string) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.ids[id] > 0
+}
+
+// chunkWriter streams one chunk to its replicas.
+// Upload creates one per chunk, feeds it data, and Closes it when the
+// chunk is full or the file ends.
+type chunkWriter struct {
+	*chunkclient.Writer
+
+	id       string
+	replicas []string
+	registry *registry
+
+	// reserved is the space placement counted against each replica for
+	// the chunk before it was written.
+	reserved int64
+
+	// hash is the SHA-256 of the data written, the chunk's content ID.
+	// For an encrypted chunk it is an HMAC keyed with the data key, so
+	// the metadata doesn't give away what the data is.
+	hash hash.Hash
+
+	// size counts the data written, before compression. The embedded
+	// Writer counts what is actually sent and stored.
+	size int64
+
+	// codec compresses the data on its way to the replicas, if set.
+	// Whether that is worth it is decided on the first compressionSample
+	// bytes, which are held in sample until then; out is nil until the
+	// decision is made.
+	codec      string
+	sample     []byte
+	out        io.Writer
+	compressor io.WriteCloser
+
+	// sink is where the data goes once compressed: through encryptor, if
+	// the chunk is encrypted, and buf to the replicas.
+	sink      io.Writer
+	encryptor *encryption.Writer
+	buf       *bufio.Writer
+}
+
+// compressionSample is how much of a chunk is looked at to decide whether
+// compressing it is worthwhile.
+const compressionSample = 64 * 1024
+
+// pipelineWriter adapts a chunkclient.Writer to io.Writer, for compressors
+// to write into.
+type pipelineWriter struct {
+	w *chunkclient.Writer
+}
+
+func (p pipelineWriter) Write(data []byte) (int, error) {
+	if err := p.w.Write(data); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// newChunkWriter allocates a chunk ID, picks replication chunkservers with
+// room for size bytes (the most the chunk is expected to hold) and opens a
+// replication pipeline through them. The chunk is compressed with
+// codec (see package compression) unless its data turns out not to be
+// compressible, then encrypted with key (see package encryption) if that
+// is set.
+// The chunk ID is marked pending; the caller must remove it from
+// s.pending once the chunk is committed to metadata or abandoned.
+func (s *Server) newChunkWriter(ctx context.Context, replication int, size int64, codec string, key []byte) (*chunkWriter, error) {
+	id, err := newChunkID()
+	if err != nil {
+		return nil, err
+	}
+
+	// Compression can only be counted once it has happened, but sealing
+	// grows the data by a known amount
+	reserve := size
+	if key != nil {
+		reserve += (size/encryption.SegmentSize + 1) * encryption.Overhead
+	}
+	replicas, err := s.registry.place(replication, nil, reserve)
+	if err != nil {
+		return nil, err
+	}
+
+	s.pending.add(id)
+	w, err := s.chunks.NewWriter(ctx, id, replicas)
+	if err != nil {
+		s.registry.adjust(replicas, -reserve)
+		s.pending.remove(id)
+		return nil, err
+	}
+
+	cw := &chunkWriter{
+		Writer:   w,
+		id:       id,
+		replicas: replicas,
+		registry: s.registry,
+		reserved: reserve,
+		hash:     sha256.New(),
+		codec:    codec,
+		sink:     pipelineWriter{w},
+	}
+	if key != nil {
+		// Sealed segments are smaller than a message; gather them
+		cw.buf = bufio.NewWriterSize(pipelineWriter{w}, defaultChunkSize)
+		cw.encryptor, err = encryption.NewWriter(key, id, cw.buf)
+		if err != nil {
+			cw.Abort()
+			s.pending.remove(id)
+			return nil, err
+		}
+		cw.sink = cw.encryptor
+		cw.hash = hmac.New(sha256.New, key)
+	}
+	if codec == compression.None {
+		cw.out = cw.sink
+	}
+	return cw, nil
+}
+
+// Size returns the number of bytes written so far, before compression.
+func (w *chunkWriter) Size() int64 {
+	return w.size
+}
+
+// Write sends data to the replicas.
+func (w *chunkWriter) Write(data []byte) error {
+	w.hash.Write(data)
+	w.size += int64(len(data))
+
+	if w.out == nil {
+		w.sample = append(w.sample, data...)
+		if len(w.sample) < compressionSample {
+			return nil
+		}
+		return w.startCompression()
+	}
+	_, err := w.out.Write(data)
+	return err
+}
+
+// startCompression decides from the sample whether to compress the chunk,
+// and sends the sample on its way.
+func (w *chunkWriter) startCompression() error {
+	// Compressors write in small pieces; gather them into messages
+	if w.buf == nil {
+		w.buf = bufio.NewWriterSize(pipelineWriter{w.Writer}, defaultChunkSize)
+		w.sink = w.buf
+	}
+	w.out = w.sink
+	if compression.Worthwhile(w.codec, w.sample) {
+		var err error
+		w.compressor, err = compression.NewWriter(w.codec, w.sink)
+		if err != nil {
+			return err
+		}
+		w.out = w.compressor
+	} else {
+		w.codec = compression.None
+	}
+
+	_, err := w.out.Write(w.sample)
+	w.sample = nil
+	return err
+}
+
+// Close finishes the chunk and waits for every replica to confirm it.
+func (w *chunkWriter) Close() (ChunkMeta, error) {
+	// A chunk smaller than the sample is decided on all of it
+	if w.out == nil {
+		if err := w.startCompression(); err != nil {
+			return ChunkMeta{}, err
+		}
+	}
+	if w.compressor != nil {
+		err := w.compressor.Close()
+		w.compressor = nil
+		if err != nil {
+			return ChunkMeta{}, err
+		}
+	}
+	if w.encryptor != nil {
+		if err := w.encryptor.Close(); err != nil {
+			return ChunkMeta{}, err
+		}
+	}
+	if w.buf != nil {
+		if err := w.buf.Flush(); err != nil {
+			return ChunkMeta{}, err
+		}
+	}
+
+	if err := w.Writer.Close(); err != nil {
+		return ChunkMeta{}, err
+	}
+
+	for _, addr := range w.replicas {
+		w.registry.addReplica(addr, w.id)
+	}
+
+	// Count what the chunk really takes up, rather than the guess
+	w.registry.adjust(w.replicas, w.Writer.Size()-w.reserved)
+
+	return ChunkMeta{
+		ID:         w.id,
+		Size:       w.size,
+		Locations:  w.replicas,
+		CRC32C:     w.Checksum(),
+		Hash:       hex.EncodeToString(w.hash.Sum(nil)),
+		Codec:      w.codec,
+		StoredSize: w.Writer.Size(),
+	}, nil
+}
+
+// Abort gives up on the chunk; no replica keeps any of it.
+func (w *chunkWriter) Abort() {
+	if w.compressor != nil {
+		w.compressor.Close() // Back to the pool; the output goes nowhere
+		w.compressor = nil
+	}
+	w.Writer.Abort()
+
+	// Replicas throw away what they got
+	w.registry.adjust(w.replicas, -w.reserved)
+}
+
+// dedupChunk looks for a stored chunk with the same content as one just
+// written. If there is one, it deletes the new chunk and returns the
+// existing one to use in its place; otherwise it returns the new chunk.
+// Either way, the chunk returned is pending, and the caller must remove
+// it from s.pending as for newChunkWriter.
+//
+// This is what makes storage content addressed: the same data uploaded
+// any number of times, as whole files or as parts of them, is kept once,
+// shared like the chunks of a copy. The data still crosses the network
+// each time, since we only know what it is once we have all of it.
+func (s *Server) dedupChunk(chunk ChunkMeta) ChunkMeta {
+	existing, err := s.metadata.FindChunk(chunk.Hash)
+	if err != nil {
+		return chunk
+	}
+
+	// Hold the existing chunk pending before checking it is still
+	// referenced. releaseChunks checks in the opposite order, so either
+	// it sees our hold and keeps the chunk, or we see it gone.
+	s.pending.add(existing.ID)
+	if s.metadata.ChunkRefs(existing.ID) == 0 {
+		s.pending.remove(existing.ID)
+		return chunk
+	}
+
+	s.deleteChunks([]ChunkMeta{chunk})
+	s.pending.remove(chunk.ID)
+	return *existing
+}
+
+// readChunk streams length bytes of a chunk, starting at offset, to fn,
+// trying each replica in turn. A length of 0 reads to the end of the chunk.
+// key is the file's data key, if it is encrypted.
+func (s *Server) readChunk(ctx context.Context, chunk ChunkMeta, key []byte, offset, length int64, fn func([]byte) error) error {
+	// Reading to the end of a compressed chunk lets the codec check it
+	if offset+length == chunk.Size {
+		length = 0
+	}
+	return s.chunks.ReadStoredRange(ctx, chunkclient.StoredChunk{
+		ID:         chunk.ID,
+		Addresses:  chunk.Locations,
+		Codec:      chunk.Codec,
+		Key:        key,
+		StoredSize: chunk.storedSize(),
+	}, offset, length, fn)
+}
+
+// releaseChunks deletes those of a removed file's chunks that no other
+// file references. Copies share chunks, so a file going away must not
+// take data another file still uses with it.
+//
+// Call it after the metadata change that dropped the references. Nothing
+// can add a reference to a chunk whose count reached zero - a copy needs a
+// source file that has it, and an upload sharing it holds it pending first
+// (see dedupChunk) - so the chunks we delete stay unreferenced.
+func (s *Server) releaseChunks(chunks []ChunkMeta) {
+	var unreferenced []ChunkMeta
+	seen := make(map[string]bool)
+	for _, chunk := range chunks {
+		// A file repeating some data uses the same chunk more than once
+		if seen[chunk.ID] {
+			continue
+		}
+		seen[chunk.ID] = true
+		if s.metadata.ChunkRefs(chunk.ID) == 0 && !s.pending.has(chunk.ID) {
+			unreferenced = append(unreferenced, chunk)
+		}
+	}
+	s.deleteChunks(unreferenced)
+}
+
+// deleteChunks removes chunks from every chunkserver holding them.
+// This is best effort: failures are logged, not returned, because the
+// metadata no longer references these chunks and nothing can read them.
+func (s *Server) deleteChunks(chunks []ChunkMeta) {
+	// Use a fresh context: cleanup often runs because the request's own
+	// context was cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), chunkDeleteTimeout)
+	defer cancel()
+
+	for _, chunk := range chunks {
+		for _, addr := range chunk.Locations {
+			if err := s.chunks.DeleteChunk(ctx, addr, chunk.ID); err != nil {
+				// Heartbeat GC will catch it later
+				log.Printf("master: failed to delete chunk %s from %s: %v", chunk.ID, addr, err)
+				continue
+			}
+			s.registry.removeReplica(addr, chunk.ID)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package master
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkserver"
+	"github.com/darshanmadesh/godfs/internal/compression"
+	"github.com/darshanmadesh/godfs/internal/encryption"
+)
+
+// quietChunkserver starts a chunkserver for s that never sends a
+// heartbeat, reporting it to the registry once by hand instead, so what
+// the registry counts as used only changes with what s does. It returns
+// the chunkserver's address and the directory it stores chunks in.
+func quietChunkserver(t *testing.T, s *Server) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	server, err := chunkserver.NewServer(dir)
+	if err != nil {
+		t.Fatalf("chunkserver.NewServer: %v", err)
+	}
+	t.Cleanup(func() { server.Close() })
+	addr := serve(t, func(g *grpc.Server) {
+		api.RegisterChunkServiceServer(g, server)
+	})
+	report(s.registry, addr, 1<<30, 0)
+	return addr, dir
+}
+
+// writeChunk writes data as one chunk through a chunkWriter from s, which
+// reserves size, and returns the chunk's metadata.
+func writeChunk(t *testing.T, s *Server, size int64, codec string, key, data []byte) ChunkMeta {
+	t.Helper()
+	w, err := s.newChunkWriter(context.Background(), 1, size, codec, key)
+	if err != nil {
+		t.Fatalf("newChunkWriter: %v", err)
+	}
+	defer s.pending.remove(w.id)
+	if err := w.Write(data); err != nil {
+		w.Abort()
+		t.Fatalf("Write: %v", err)
+	}
+	chunk, err := w.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	return chunk
+}
+
+func TestChunkWriterSettlesItsReservation(t *testing.T) {
+	s := newTestServer(t, Config{})
+	addr, _ := quietChunkserver(t, s)
+	used := func() int64 {
+		s.registry.mu.Lock()
+		defer s.registry.mu.Unlock()
+		return s.registry.nodes[addr].used
+	}
+
+	// A chunk reserves what it is expected to hold while it is written,
+	// and counts what it took once it is
+	w, err := s.newChunkWriter(context.Background(), 1, 1000, compression.None, nil)
+	if err != nil {
+		t.Fatalf("newChunkWriter: %v", err)
+	}
+	if got := used(); got != 1000 {
+		t.Errorf("%d bytes used while a 1000 byte chunk is written, want 1000", got)
+	}
+	if err := w.Write(randomData(1, 300)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	chunk, err := w.Close()
+	s.pending.remove(w.id)
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := used(); got != 300 || chunk.StoredSize != 300 {
+		t.Errorf("%d bytes used for a chunk stored in %d, want 300", got, chunk.StoredSize)
+	}
+
+	// Sealing adds a known amount, reserved up front; an abandoned chunk
+	// gives everything back
+	key := randomData(2, encryption.KeySize)
+	w, err = s.newChunkWriter(context.Background(), 1, 1000, compression.None, key)
+	if err != nil {
+		t.Fatalf("newChunkWriter: %v", err)
+	}
+	if got, want := used(), 300+1000+encryption.Overhead; got != int64(want) {
+		t.Errorf("%d bytes used while a 1000 byte chunk is sealed, want %d", got, want)
+	}
+	w.Abort()
+	s.pending.remove(w.id)
+	if got := used(); got != 300 {
+		t.Errorf("%d bytes used after an abandoned chunk, want 300", got)
+	}
+
+	// A chunk that compresses well takes up what it was compressed to
+	chunk = writeChunk(t, s, 64*1024, compression.Zstd, nil, make([]byte, 64*1024))
+	if chunk.Codec != compression.Zstd || chunk.StoredSize >= 1000 {
+		t.Fatalf("64KB of zeros stored in %d bytes with codec %q, want a few with zstd", chunk.StoredSize, chunk.Codec)
+	}
+	if got := used(); got != 300+chunk.StoredSize {
+		t.Errorf("%d bytes used, want %d", got, 300+chunk.StoredSize)
+	}
+}
@@ -0,0 +1,308 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkserver"
+)
+
+// testCluster is a master and its chunkservers running in the test
+// process, talking gRPC over localhost, for tests that need chunks to
+// really be stored and read.
+type testCluster struct {
+	master       *Server
+	addr         string // The master's
+	client       api.FileServiceClient
+	chunkservers map[string]*testChunkserver
+}
+
+// testChunkserver is one chunkserver of a testCluster.
+type testChunkserver struct {
+	dir      string
+	stop     func()
+	stopOnce sync.Once
+}
+
+// startCluster starts a master with cfg, filling in short timeouts so
+// failures are noticed and repaired quickly, and n chunkservers offering
+// capacity bytes each (0 for what their disk has free), and waits until
+// every chunkserver has reported in.
+func startCluster(t *testing.T, cfg Config, n int, capacity int64) *testCluster {
+	t.Helper()
+	if cfg.HeartbeatTimeout == 0 {
+		cfg.HeartbeatTimeout = time.Second
+	}
+	if cfg.RepairInterval == 0 {
+		cfg.RepairInterval = 100 * time.Millisecond
+	}
+
+	c := &testCluster{
+		master:       newTestServer(t, cfg),
+		chunkservers: make(map[string]*testChunkserver),
+	}
+	c.addr = serve(t, func(g *grpc.Server) {
+		api.RegisterFileServiceServer(g, c.master)
+		api.RegisterMasterServiceServer(g, c.master)
+	})
+	c.client = c.connect(t)
+
+	for range n {
+		dir := t.TempDir()
+		server, err := chunkserver.NewServer(dir)
+		if err != nil {
+			t.Fatalf("chunkserver.NewServer: %v", err)
+		}
+		var g *grpc.Server
+		addr := serve(t, func(s *grpc.Server) {
+			g = s
+			api.RegisterChunkServiceServer(s, server)
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		heartbeats := make(chan struct{})
+		go func() {
+			defer close(heartbeats)
+			server.RunHeartbeats(ctx, chunkserver.HeartbeatConfig{
+				Master:   c.addr,
+				Address:  addr,
+				Interval: 100 * time.Millisecond,
+				Capacity: capacity,
+			})
+		}()
+
+		cs := &testChunkserver{dir: dir}
+		cs.stop = func() {
+			cs.stopOnce.Do(func() {
+				cancel()
+				<-heartbeats
+				g.Stop()
+				server.Close()
+			})
+		}
+		t.Cleanup(cs.stop)
+		c.chunkservers[addr] = cs
+	}
+
+	c.waitFor(t, "chunkservers to report", func() bool {
+		r := c.master.registry
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for addr := range c.chunkservers {
+			if n, ok := r.nodes[addr]; !ok || !r.aliveLocked(n, time.Now()) {
+				return false
+			}
+		}
+		return true
+	})
+	return c
+}
+
+// serve starts a gRPC server on a free localhost port, with the services
+// register adds, and returns its address.
+func serve(t *testing.T, register func(*grpc.Server)) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	g := grpc.NewServer()
+	register(g)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	return lis.Addr().String()
+}
+
+// connect returns a client of the master, connected with opts.
+func (c *testCluster) connect(t *testing.T, opts ...grpc.DialOption) api.FileServiceClient {
+	t.Helper()
+	opts = append(opts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpc.NewClient(c.addr, opts...)
+	if err != nil {
+		t.Fatalf("connect to master: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return api.NewFileServiceClient(conn)
+}
+
+// waitFor polls cond until it holds, failing the test if that takes
+// more than a few seconds.
+func (c *testCluster) waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// upload writes data to the file md describes, through the master.
+func (c *testCluster) upload(t *testing.T, md *api.FileMetadata, data []byte) *api.UploadResponse {
+	t.Helper()
+	md.Size = int64(len(data))
+	resp, err := c.tryUpload(md, data)
+	if err != nil {
+		t.Fatalf("upload of %s: %v", md.Filename, err)
+	}
+	return resp
+}
+
+// tryUpload sends md and then data in an Upload stream, and returns how
+// the master answered. Unlike upload, it leaves md.Size as it is, for
+// tests of uploads that should fail.
+func (c *testCluster) tryUpload(md *api.FileMetadata, data []byte) (*api.UploadResponse, error) {
+	stream, err := c.client.Upload(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	// A failed send means the server ended the stream; CloseAndRecv
+	// says why
+	err = stream.Send(&api.UploadRequest{Data: &api.UploadRequest_Metadata{Metadata: md}})
+	for rest := data; len(rest) > 0 && err == nil; {
+		n := min(len(rest), 32*1024)
+		err = stream.Send(&api.UploadRequest{Data: &api.UploadRequest_Chunk{Chunk: rest[:n]}})
+		rest = rest[n:]
+	}
+	return stream.CloseAndRecv()
+}
+
+// download reads a file, or a range or old version of one, through the
+// master.
+func (c *testCluster) download(t *testing.T, req *api.DownloadRequest) []byte {
+	t.Helper()
+	stream, err := c.client.Download(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	var data []byte
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return data
+		}
+		if err != nil {
+			t.Fatalf("download of %s: %v", req.Filename, err)
+		}
+		data = append(data, resp.GetChunk()...)
+	}
+}
+
+// stored returns the chunk id as the chunkserver at addr has it on disk,
+// or nil if it doesn't have it.
+func (c *testCluster) stored(t *testing.T, addr, id string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(c.chunkservers[addr].dir, id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		t.Fatalf("read chunk %s on %s: %v", id, addr, err)
+	}
+	return data
+}
+
+// randomData returns n bytes that don't compress or deduplicate.
+func randomData(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+// chunkFiles returns the IDs of the chunks the chunkserver at addr has on
+// disk, sorted.
+func (c *testCluster) chunkFiles(t *testing.T, addr string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(c.chunkservers[addr].dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, entry := range entries {
+		// Checksums and quarantined chunks have a suffix; chunks don't
+		if entry.Type().IsRegular() && filepath.Ext(entry.Name()) == "" {
+			ids = append(ids, entry.Name())
+		}
+	}
+	return ids
+}
+
+func TestDedupSharesChunksUntilTheLastFileGoes(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024}, 2, 0)
+	store := c.master.metadata
+
+	data := randomData(4, 200*1024)
+	c.upload(t, &api.FileMetadata{Filename: "/a", Replication: 2}, data)
+	resp := c.upload(t, &api.FileMetadata{Filename: "/b", Replication: 2}, data)
+	if resp.DedupChunks != resp.Chunks || resp.Chunks != 4 {
+		t.Fatalf("second upload shared %d of %d chunks, want 4 of 4", resp.DedupChunks, resp.Chunks)
+	}
+
+	// The second file is made of the first one's chunks, stored once
+	a, err := store.Get("/a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	b, err := store.Get("/b")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	var ids []string
+	refs := make(map[string]int)
+	for i, chunk := range b.Chunks {
+		if chunk.ID != a.Chunks[i].ID {
+			t.Errorf("chunk %d of /b is %s, not /a's %s", i, chunk.ID, a.Chunks[i].ID)
+		}
+		ids = append(ids, chunk.ID)
+		refs[chunk.ID] = 2
+	}
+	slices.Sort(ids)
+	wantRefs(t, store, refs)
+	for addr := range c.chunkservers {
+		if got := c.chunkFiles(t, addr); !slices.Equal(got, ids) {
+			t.Errorf("%s stores chunks %v, want %v", addr, got, ids)
+		}
+	}
+
+	// Deleting one file, or overwriting the other, leaves the chunks
+	// where they are: the old version of /b still has them
+	if _, err := c.client.Delete(context.Background(), &api.DeleteRequest{Filename: "/a", Permanent: true}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	c.upload(t, &api.FileMetadata{Filename: "/b", Replication: 2, Mode: api.WriteMode_WRITE_MODE_OVERWRITE}, randomData(5, 1000))
+	for id := range refs {
+		refs[id] = 1
+	}
+	wantRefs(t, store, refs)
+	for addr := range c.chunkservers {
+		for _, id := range ids {
+			if c.stored(t, addr, id) == nil {
+				t.Errorf("shared chunk %s gone from %s", id, addr)
+			}
+		}
+	}
+
+	// Once nothing references them they are deleted from the chunkservers
+	if _, err := c.client.Delete(context.Background(), &api.DeleteRequest{Filename: "/b", Permanent: true}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	for addr := range c.chunkservers {
+		if got := c.chunkFiles(t, addr); len(got) != 0 {
+			t.Errorf("chunks %v left on %s", got, addr)
+		}
+	}
+}
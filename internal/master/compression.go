@@ -0,0 +1,86 @@
+package master
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+// Chunks can be compressed at rest. The master compresses each chunk as
+// it streams it to the replicas, so chunkservers store (and replicate,
+// and scrub) the compressed bytes without knowing, and decompresses it
+// again when reading it back. Compression rules say which codec to use
+// for which files; an upload can also ask for one itself.
+//
+// Whatever the codec, a chunk whose data doesn't compress is stored as is
+// (see compression.Worthwhile), so compressing everything costs little
+// more than some CPU on data that is compressed already.
+
+// CompressionRule says how to compress the files under a path.
+type CompressionRule struct {
+	// Prefix is the directory (or file) the rule covers. Where several
+	// rules cover a file, the one with the longest prefix applies.
+	Prefix string
+
+	// Codec is the codec to compress with: compression.None, Gzip or Zstd.
+	Codec string
+}
+
+// ParseCompressionRule parses a rule written as "PREFIX=CODEC", where
+// CODEC is zstd, gzip or none: "/logs=zstd".
+func ParseCompressionRule(s string) (CompressionRule, error) {
+	prefix, name, ok := strings.Cut(s, "=")
+	if !ok || prefix == "" {
+		return CompressionRule{}, fmt.Errorf("invalid compression rule %q: want PREFIX=zstd|gzip|none", s)
+	}
+	codec, err := compression.Parse(name)
+	if err != nil {
+		return CompressionRule{}, fmt.Errorf("invalid compression rule %q: %w", s, err)
+	}
+	return CompressionRule{Prefix: prefix, Codec: codec}, nil
+}
+
+// covers reports whether the rule applies to filename.
+func (r CompressionRule) covers(filename string) bool {
+	return r.Prefix == rootDir || filename == r.Prefix || strings.HasPrefix(filename, r.Prefix+"/")
+}
+
+// compressionFor returns the codec to store a file with: the one the
+// upload asked for, or else the one its compression rule gives. Files no
+// rule covers aren't compressed.
+func (s *Server) compressionFor(filename string, requested api.Compression) (string, error) {
+	switch requested {
+	case api.Compression_COMPRESSION_DEFAULT:
+		// Go by the rules
+	case api.Compression_COMPRESSION_NONE:
+		return compression.None, nil
+	case api.Compression_COMPRESSION_GZIP:
+		return compression.Gzip, nil
+	case api.Compression_COMPRESSION_ZSTD:
+		return compression.Zstd, nil
+	default:
+		return "", fmt.Errorf("unknown compression %v", requested)
+	}
+
+	best := CompressionRule{Codec: compression.None}
+	for _, rule := range s.compression {
+		if rule.covers(filename) && len(rule.Prefix) > len(best.Prefix) {
+			best = rule
+		}
+	}
+	return best.Codec, nil
+}
+
+// chunkCompression describes a chunk's codec for clients.
+func chunkCompression(codec string) api.Compression {
+	switch codec {
+	case compression.Gzip:
+		return api.Compression_COMPRESSION_GZIP
+	case compression.Zstd:
+		return api.Compression_COMPRESSION_ZSTD
+	default:
+		return api.Compression_COMPRESSION_NONE
+	}
+}
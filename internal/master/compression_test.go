@@ -0,0 +1,140 @@
+package master
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+// checkRanges downloads ranges of a file - whole, within chunks and
+// segments, across their boundaries, and from the end - and checks each
+// against data, what the file should hold. chunkSize is the file's.
+func checkRanges(t *testing.T, c *testCluster, name string, data []byte, chunkSize int64) {
+	t.Helper()
+	size := int64(len(data))
+	for _, r := range []struct{ offset, length int64 }{
+		{0, 0},
+		{0, 1},
+		{100, 5000},
+		{chunkSize - 10, 20},
+		{chunkSize, chunkSize},
+		{chunkSize + 64*1024 - 3, 100000},
+		{size - 1, 0},
+		{-1000, 0},
+		{size - 100, 1000},
+	} {
+		got := c.download(t, &api.DownloadRequest{Filename: name, Offset: r.offset, Length: r.length})
+		start := r.offset
+		if start < 0 {
+			start += size
+		}
+		end := size
+		if r.length > 0 {
+			end = min(start+r.length, size)
+		}
+		if !bytes.Equal(got, data[start:end]) {
+			t.Errorf("%s, range %d+%d: got %d bytes, not the file's %d", name, r.offset, r.length, len(got), end-start)
+		}
+	}
+}
+
+func TestCompressionFor(t *testing.T) {
+	s := newTestServer(t, Config{Compression: []CompressionRule{
+		{Prefix: "/", Codec: compression.Gzip},
+		{Prefix: "/logs", Codec: compression.Zstd},
+		{Prefix: "/logs/raw", Codec: compression.None},
+	}})
+	for _, tc := range []struct {
+		filename  string
+		requested api.Compression
+		want      string
+	}{
+		{"/f", api.Compression_COMPRESSION_DEFAULT, compression.Gzip},
+		{"/logs/a", api.Compression_COMPRESSION_DEFAULT, compression.Zstd},
+		{"/logs", api.Compression_COMPRESSION_DEFAULT, compression.Zstd},
+		{"/logs2", api.Compression_COMPRESSION_DEFAULT, compression.Gzip},
+		{"/logs/raw/a", api.Compression_COMPRESSION_DEFAULT, compression.None},
+		{"/logs/raw/a", api.Compression_COMPRESSION_ZSTD, compression.Zstd},
+		{"/logs/a", api.Compression_COMPRESSION_NONE, compression.None},
+	} {
+		got, err := s.compressionFor(tc.filename, tc.requested)
+		if err != nil || got != tc.want {
+			t.Errorf("compressionFor(%s, %v) = %q, %v; want %q", tc.filename, tc.requested, got, err, tc.want)
+		}
+	}
+}
+
+func TestCompressedChunksOnDisk(t *testing.T) {
+	s := newTestServer(t, Config{})
+	_, dir := quietChunkserver(t, s)
+	text := bytes.Repeat([]byte("2026-10-16 request served in 12ms\n"), 8000)
+	random := randomData(1, 256*1024)
+
+	for _, tc := range []struct {
+		name      string
+		codec     string
+		data      []byte
+		wantCodec string
+	}{
+		{"text, zstd", compression.Zstd, text, compression.Zstd},
+		{"text, gzip", compression.Gzip, text, compression.Gzip},
+		{"random", compression.Zstd, random, compression.None}, // Doesn't pay
+		{"uncompressed", compression.None, text, compression.None},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chunk := writeChunk(t, s, int64(len(tc.data)), tc.codec, nil, tc.data)
+			if chunk.Codec != tc.wantCodec || chunk.Size != int64(len(tc.data)) {
+				t.Errorf("chunk of %d bytes with codec %q, want %d with %q", chunk.Size, chunk.Codec, len(tc.data), tc.wantCodec)
+			}
+
+			// What is on disk is what the metadata says: that many bytes,
+			// which decompress to the data
+			stored, err := os.ReadFile(filepath.Join(dir, chunk.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if int64(len(stored)) != chunk.StoredSize {
+				t.Errorf("%d bytes on disk, metadata says %d", len(stored), chunk.StoredSize)
+			}
+			if tc.wantCodec == compression.None {
+				if !bytes.Equal(stored, tc.data) {
+					t.Error("uncompressed chunk isn't stored as is")
+				}
+				return
+			}
+			if chunk.StoredSize >= chunk.Size/2 {
+				t.Errorf("compressed to %d bytes of %d", chunk.StoredSize, chunk.Size)
+			}
+			r, err := compression.NewReader(chunk.Codec, bytes.NewReader(stored))
+			if err != nil {
+				t.Fatalf("NewReader: %v", err)
+			}
+			defer r.Close()
+			if data, err := io.ReadAll(r); err != nil || !bytes.Equal(data, tc.data) {
+				t.Errorf("stored chunk decompresses to %d bytes, %v; not the data", len(data), err)
+			}
+		})
+	}
+}
+
+func TestCompressedFilesReadBack(t *testing.T) {
+	const chunkSize = 256 * 1024
+	c := startCluster(t, Config{
+		ChunkSize:   chunkSize,
+		Compression: []CompressionRule{{Prefix: "/logs", Codec: compression.Zstd}},
+	}, 1, 0)
+
+	// Ranges are found within chunks that are compressed as a whole
+	var text []byte
+	for i := 0; len(text) < 3*chunkSize+12345; i++ {
+		text = append(text, []byte("2026-10-16 request served in 12ms\n")...)
+		text = append(text, byte('a'+i%26))
+	}
+	c.upload(t, &api.FileMetadata{Filename: "/logs/text", Replication: 1, Parents: true}, text)
+	checkRanges(t, c, "/logs/text", text, chunkSize)
+}
@@ -0,0 +1,206 @@
+package master
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Watchers follow changes to the namespace as a stream of events, instead
+// of polling List. Each event has a sequence number, and the master keeps
+// the most recent ones, so a watcher that loses its connection can come
+// back with the last number it saw and carry on from there. One that was
+// gone too long to catch up is told so, rather than silently missing
+// events, and has to start over from a fresh listing.
+//
+// Events are numbered in the order the changes were made - each write to
+// the metadata and the publishing of its events happen under one lock - so
+// a watcher applying them in sequence ends up with the namespace as it is.
+
+// watchHistory is how many recent events are kept for watchers to catch
+// up from.
+const watchHistory = 10000
+
+// eventLog holds the recent events and wakes up watchers waiting for more.
+type eventLog struct {
+	mu sync.Mutex
+
+	// events are the most recent events, oldest first.
+	events []*api.WatchEvent
+
+	// next is the sequence number the next event gets.
+	next uint64
+
+	// changed is closed, and replaced, whenever an event is added: a
+	// broadcast to every waiting watcher at once.
+	changed chan struct{}
+	closed  bool
+}
+
+func newEventLog() *eventLog {
+	// Events aren't persisted, so numbering starts from the clock: a
+	// watcher resuming from before a restart has a lower number than any
+	// event since, and is told it missed some, rather than mistaking
+	// the new numbers for ones it has seen.
+	return &eventLog{
+		next:    uint64(time.Now().UnixNano()),
+		changed: make(chan struct{}),
+	}
+}
+
+// add numbers an event and adds it to the log.
+func (l *eventLog) add(e *api.WatchEvent) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Sequence = l.next
+	l.next++
+	if len(l.events) == watchHistory {
+		l.events = l.events[1:]
+	}
+	l.events = append(l.events, e)
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
+
+// last returns the sequence number of the latest event.
+func (l *eventLog) last() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next - 1
+}
+
+// since returns the events after seq, and a channel that is closed when
+// another arrives.
+func (l *eventLog) since(seq uint64) ([]*api.WatchEvent, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, nil, status.Error(codes.Unavailable, "master is shutting down")
+	}
+	oldest := l.next - uint64(len(l.events))
+	if seq+1 < oldest || seq >= l.next {
+		return nil, nil, status.Errorf(codes.OutOfRange,
+			"events after %d are no longer available: list again and watch from the next change", seq)
+	}
+
+	events := append([]*api.WatchEvent(nil), l.events[seq+1-oldest:]...)
+	return events, l.changed, nil
+}
+
+// close ends every watch.
+func (l *eventLog) close() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if !l.closed {
+		l.closed = true
+		close(l.changed)
+	}
+}
+
+// publish tells watchers about a change to the namespace. oldPath is only
+// for renames.
+func (s *Server) publish(eventType api.WatchEventType, meta *FileMeta, oldPath string) {
+	s.events.add(&api.WatchEvent{
+		Type:    eventType,
+		Path:    meta.Filename,
+		OldPath: oldPath,
+		File:    fileInfo(meta),
+		Time:    time.Now().Unix(),
+	})
+}
+
+// writeEvent returns the kind of event a write of meta is: the first
+// generation of a file creates it, later ones update it.
+func writeEvent(meta *FileMeta) api.WatchEventType {
+	if meta.generation() == 1 {
+		return api.WatchEventType_WATCH_EVENT_CREATE
+	}
+	return api.WatchEventType_WATCH_EVENT_UPDATE
+}
+
+// publishCreated publishes the directories a write created on its way.
+func (s *Server) publishCreated(dirs []*FileMeta) {
+	for _, dir := range dirs {
+		s.publish(api.WatchEventType_WATCH_EVENT_CREATE, dir, "")
+	}
+}
+
+// Watch streams namespace events for paths under a prefix until the client
+// goes away. It starts with the next change, or, with after_sequence set,
+// with the event after that one.
+func (s *Server) Watch(req *api.WatchRequest, stream api.FileService_WatchServer) error {
+	prefix := req.Prefix
+	if prefix != "" {
+		var err error
+		if prefix, err = cleanPrefix(prefix); err != nil {
+			return metadataStatus(err)
+		}
+	}
+
+	after := req.AfterSequence
+	if after == 0 {
+		after = s.events.last()
+	}
+
+	for {
+		events, changed, err := s.events.since(after)
+		if err != nil {
+			return err
+		}
+
+		for _, e := range events {
+			after = e.Sequence
+			if !watching(prefix, e) {
+				continue
+			}
+			if err := stream.Send(e); err != nil {
+				return err
+			}
+		}
+
+		if len(events) == 0 {
+			select {
+			case <-changed:
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			}
+		}
+	}
+}
+
+// watching reports whether a watcher of prefix is sent e. A rename
+// concerns both ends: watchers see files move in as well as out. And when
+// a directory above the prefix is removed or renamed, everything under the
+// prefix goes, or comes, with it in that one event, so it is sent too.
+func watching(prefix string, e *api.WatchEvent) bool {
+	if strings.HasPrefix(e.Path, prefix) || (e.OldPath != "" && strings.HasPrefix(e.OldPath, prefix)) {
+		return true
+	}
+	if !e.File.GetIsDir() {
+		return false
+	}
+	switch e.Type {
+	case api.WatchEventType_WATCH_EVENT_DELETE:
+		return strings.HasPrefix(prefix, e.Path+"/")
+	case api.WatchEventType_WATCH_EVENT_RENAME:
+		return strings.HasPrefix(prefix, e.Path+"/") || strings.HasPrefix(prefix, e.OldPath+"/")
+	}
+	return false
+}
+
+// StopWatching ends every Watch stream. A graceful gRPC shutdown waits
+// for all RPCs to return, which a watch never does by itself, so call this
+// first. Event history doesn't survive a restart, so watchers that
+// reconnect afterwards are told they missed events and must list again.
+func (s *Server) StopWatching() {
+	s.events.close()
+}
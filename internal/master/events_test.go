@@ -0,0 +1,252 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// watchStream is a Watch stream inside the test process: what Watch sends
+// arrives on events, and when it returns, its error on done.
+type watchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events chan *api.WatchEvent
+	done   chan error
+}
+
+func (w *watchStream) Context() context.Context { return w.ctx }
+
+func (w *watchStream) Send(e *api.WatchEvent) error {
+	w.events <- e
+	return nil
+}
+
+// watch starts a Watch on s. Without after_sequence set, it starts after
+// the latest event so far, so whatever the test does next is seen.
+func watch(t *testing.T, s *Server, req *api.WatchRequest) *watchStream {
+	t.Helper()
+	if req.AfterSequence == 0 {
+		req.AfterSequence = s.events.last()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	w := &watchStream{
+		ctx:    ctx,
+		events: make(chan *api.WatchEvent, 100),
+		done:   make(chan error, 1),
+	}
+	go func() { w.done <- s.Watch(req, w) }()
+	t.Cleanup(cancel)
+	return w
+}
+
+// describe sums an event up as "type path", with " from old-path" for
+// renames: "rename /b from /a".
+func describe(e *api.WatchEvent) string {
+	d := strings.ToLower(strings.TrimPrefix(e.Type.String(), "WATCH_EVENT_")) + " " + e.Path
+	if e.OldPath != "" {
+		d += " from " + e.OldPath
+	}
+	return d
+}
+
+// wantEvents checks a watch delivers exactly the events described, in
+// order, and nothing after them.
+func wantEvents(t *testing.T, w *watchStream, want ...string) []*api.WatchEvent {
+	t.Helper()
+	var events []*api.WatchEvent
+	var got []string
+	for len(got) < len(want) {
+		select {
+		case e := <-w.events:
+			events = append(events, e)
+			got = append(got, describe(e))
+		case err := <-w.done:
+			t.Fatalf("watch ended: %v; had %q, want %q", err, got, want)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for events: had %q, want %q", got, want)
+		}
+	}
+	select {
+	case e := <-w.events:
+		got = append(got, describe(e))
+	case <-time.After(50 * time.Millisecond):
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %q\nwant %q", got, want)
+	}
+	return events
+}
+
+func TestWatchFollowsChangesUnderThePrefix(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	mkdirs(t, s.metadata, "/logs", "/other")
+	createFile(t, s.metadata, "/other/f", 1)
+	w := watch(t, s, &api.WatchRequest{Prefix: "/logs/"})
+
+	// Changes under the prefix, including files moving in, but nothing
+	// outside it
+	must := func(_ any, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	must(s.Mkdir(ctx, &api.MkdirRequest{Path: "/logs/2026"}))
+	must(s.Mkdir(ctx, &api.MkdirRequest{Path: "/logs2"}))
+	must(s.Rename(ctx, &api.RenameRequest{Source: "/other/f", Destination: "/logs/f"}))
+	must(s.Copy(ctx, &api.CopyRequest{Source: "/logs/f", Destination: "/other/g"}))
+	must(s.SetAttributes(ctx, &api.SetAttributesRequest{Filename: "/logs/f", AddTags: []string{"x"}}))
+	must(s.Delete(ctx, &api.DeleteRequest{Filename: "/logs/f"}))
+	events := wantEvents(t, w,
+		"create /logs/2026",
+		"rename /logs/f from /other/f",
+		"attributes /logs/f",
+		"delete /logs/f",
+	)
+
+	// A watcher coming back after an event picks up from the next one
+	again := watch(t, s, &api.WatchRequest{Prefix: "/logs/", AfterSequence: events[1].Sequence})
+	wantEvents(t, again, "attributes /logs/f", "delete /logs/f")
+
+	// One gone too long is told it missed some
+	stale := watch(t, s, &api.WatchRequest{AfterSequence: 1})
+	if err := <-stale.done; status.Code(err) != codes.OutOfRange {
+		t.Errorf("watch from a forgotten event: err = %v, want OutOfRange", err)
+	}
+}
+
+func TestPrefixesAreCleaned(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	mkdirs(t, s.metadata, "/logs")
+
+	// However a prefix is written, it means the same
+	prefixes := []string{"/logs/", "logs/", "/logs//", "//logs/"}
+	var watches []*watchStream
+	for _, prefix := range prefixes {
+		watches = append(watches, watch(t, s, &api.WatchRequest{Prefix: prefix}))
+	}
+	createFile(t, s.metadata, "/logs/f", 1)
+	createFile(t, s.metadata, "/logs2", 1)
+	for _, name := range []string{"/logs2", "/logs/f"} {
+		if _, err := s.Delete(ctx, &api.DeleteRequest{Filename: name}); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+	}
+	for i, w := range watches {
+		t.Run(fmt.Sprintf("watch %q", prefixes[i]), func(t *testing.T) {
+			wantEvents(t, w, "delete /logs/f")
+		})
+	}
+	for _, prefix := range prefixes {
+		resp, err := s.ListTrash(ctx, &api.ListTrashRequest{Prefix: prefix})
+		if err != nil || len(resp.Entries) != 1 || resp.Entries[0].File.Filename != "/logs/f" {
+			t.Errorf("trash under %q: %v, %v; want /logs/f", prefix, resp.GetEntries(), err)
+		}
+	}
+
+	// One that isn't a path is refused
+	for _, prefix := range []string{"logs/../x", "/logs/./"} {
+		w := watch(t, s, &api.WatchRequest{Prefix: prefix})
+		if err := <-w.done; status.Code(err) != codes.InvalidArgument {
+			t.Errorf("watch of %q: err = %v, want InvalidArgument", prefix, err)
+		}
+		if _, err := s.ListTrash(ctx, &api.ListTrashRequest{Prefix: prefix}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("trash under %q: err = %v, want InvalidArgument", prefix, err)
+		}
+	}
+}
+
+func TestWatchSeesDirectoriesAboveThePrefixGo(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	mkdirs(t, s.metadata, "/a/b/c", "/x")
+	createFile(t, s.metadata, "/a/b/c/f", 1)
+	w := watch(t, s, &api.WatchRequest{Prefix: "/a/b/c/"})
+
+	// /a/b/c/f moves away with /a, comes back with /x, and goes with /a
+	// again; other directories near the prefix aren't anything to do with it
+	for _, rename := range [][2]string{{"/a", "/y"}, {"/y", "/a"}, {"/x", "/a/b/x"}} {
+		if _, err := s.Rename(ctx, &api.RenameRequest{Source: rename[0], Destination: rename[1]}); err != nil {
+			t.Fatalf("Rename: %v", err)
+		}
+	}
+	mkdirs(t, s.metadata, "/a/b/cd")
+	if _, err := s.Rmdir(ctx, &api.RmdirRequest{Path: "/a/b/cd"}); err != nil {
+		t.Fatalf("Rmdir: %v", err)
+	}
+	if _, err := s.Rmdir(ctx, &api.RmdirRequest{Path: "/a", Recursive: true}); err != nil {
+		t.Fatalf("Rmdir: %v", err)
+	}
+	wantEvents(t, w,
+		"rename /y from /a",
+		"rename /a from /y",
+		"delete /a/b/c/f",
+		"delete /a",
+	)
+}
+
+func TestEventsComeInTheOrderOfTheChanges(t *testing.T) {
+	s := newTestServer(t, Config{})
+	createFile(t, s.metadata, "/f", 1)
+	w := watch(t, s, &api.WatchRequest{Prefix: "/f"})
+
+	// Writers racing to overwrite the file, and to tag it
+	const writers = 20
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			meta := &FileMeta{Filename: "/f", Replication: 1}
+			if _, err := s.commitFile(meta, api.WriteMode_WRITE_MODE_OVERWRITE, 0, false); err != nil {
+				t.Errorf("commitFile: %v", err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			req := &api.SetAttributesRequest{Filename: "/f", AddTags: []string{fmt.Sprint(i)}}
+			if _, err := s.SetAttributes(context.Background(), req); err != nil {
+				t.Errorf("SetAttributes: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each event shows the file as it was right after the one before:
+	// generations one at a time, and tags added one at a time until new
+	// contents, which come without any
+	var generation int64 = 1
+	tags := 0
+	for range 2 * writers {
+		var e *api.WatchEvent
+		select {
+		case e = <-w.events:
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for events")
+		}
+		switch e.Type {
+		case api.WatchEventType_WATCH_EVENT_UPDATE:
+			generation++
+			tags = 0
+		case api.WatchEventType_WATCH_EVENT_ATTRIBUTES:
+			tags++
+		}
+		if e.File.Generation != generation || len(e.File.Tags) != tags {
+			t.Fatalf("event %s: generation %d with %d tags, want %d with %d",
+				describe(e), e.File.Generation, len(e.File.Tags), generation, tags)
+		}
+	}
+}
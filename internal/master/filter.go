@@ -0,0 +1,94 @@
+package master
+
+import (
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ListFilter narrows a listing to the entries matching all of its
+// conditions. The zero value matches everything. Filters are evaluated by
+// the metadata store as it scans, so a filtered listing still comes in
+// full pages and nothing that doesn't match is sent anywhere.
+type ListFilter struct {
+	// PathGlob is a path.Match pattern for the entry's whole path, like
+	// "/logs/2026-*/*.gz". As in a shell, "*" doesn't match "/", so each
+	// element of the pattern matches one element of the path.
+	PathGlob string
+
+	// NameGlob is a path.Match pattern for the entry's name alone, like
+	// "*.gz", at whatever depth it is.
+	NameGlob string
+
+	// Regexp, if set, must match somewhere in the entry's path.
+	Regexp *regexp.Regexp
+
+	// MinSize and MaxSize bound the entry's size in bytes, inclusive.
+	// Zero means no bound.
+	MinSize int64
+	MaxSize int64
+
+	// ModifiedAfter and ModifiedBefore bound the entry's modification
+	// time, inclusive. The zero time means no bound.
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+
+	// Attributes must all be set on the entry, to the values given - or
+	// to anything, where the value given is empty.
+	Attributes map[string]string
+
+	// Tags must all be on the entry.
+	Tags []string
+}
+
+// matches reports whether meta passes the filter.
+func (f *ListFilter) matches(meta *FileMeta) bool {
+	// Patterns were checked when the filter was built, so Match can't
+	// fail here
+	if f.PathGlob != "" {
+		if ok, _ := path.Match(f.PathGlob, meta.Filename); !ok {
+			return false
+		}
+	}
+	if f.NameGlob != "" {
+		if ok, _ := path.Match(f.NameGlob, path.Base(meta.Filename)); !ok {
+			return false
+		}
+	}
+	if f.Regexp != nil && !f.Regexp.MatchString(meta.Filename) {
+		return false
+	}
+
+	switch {
+	case f.MinSize > 0 && meta.Size < f.MinSize,
+		f.MaxSize > 0 && meta.Size > f.MaxSize,
+		!f.ModifiedAfter.IsZero() && meta.ModifiedAt.Before(f.ModifiedAfter),
+		!f.ModifiedBefore.IsZero() && meta.ModifiedAt.After(f.ModifiedBefore):
+		return false
+	}
+
+	for key, want := range f.Attributes {
+		value, ok := meta.Attributes[key]
+		if !ok || want != "" && value != want {
+			return false
+		}
+	}
+	for _, tag := range f.Tags {
+		// Stored tags are sorted
+		if _, ok := slices.BinarySearch(meta.Tags, tag); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// globPrefix returns the literal start of a glob pattern, before its first
+// special character: every path the pattern matches starts with it.
+func globPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
@@ -0,0 +1,328 @@
+package master
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/encryption"
+)
+
+// Encryption at rest uses envelope keys. Every file (every version of it,
+// strictly) gets its own random data key when it is uploaded, and its
+// chunks are encrypted with that (see package encryption). The data key
+// is stored in the file's metadata, but only wrapped - encrypted - by a
+// master key, which lives outside the metadata: in a key file, or in a
+// KMS. So neither the chunkservers' disks nor the metadata on their own
+// give away anything.
+//
+// That is the whole threat model: stolen disks, backups and metadata
+// snapshots. The master has no authentication, and it decrypts for anyone
+// who asks - Download returns plaintext, and GetChunkLocations hands out
+// files' data keys unwrapped - so keep its port to trusted clients.
+//
+// Rotating the master key only means rewrapping the data keys: the data
+// itself, encrypted with those, stays as it is.
+//
+// Each file having its own key means identical data in two encrypted
+// files can't be stored once, as chunks of unencrypted files are.
+
+// ErrUnknownKey is returned for a data key wrapped with a master key the
+// KMS doesn't have.
+var ErrUnknownKey = errors.New("unknown master key")
+
+// WrappedKey is a data key, encrypted with a master key.
+type WrappedKey struct {
+	// KeyID names the master key it is wrapped with.
+	KeyID string
+
+	// Key is the wrapped data key, in whatever form the KMS wraps it.
+	Key []byte
+}
+
+// KMS holds master keys, and wraps and unwraps data keys with them. It
+// has one active key, for wrapping new data keys; it may keep others to
+// unwrap data keys wrapped before the active key was rotated in.
+//
+// KeyFile is the built-in implementation. Anything else holding keys -
+// a cloud KMS, Vault, an HSM - only needs to implement these methods.
+type KMS interface {
+	// ActiveKey returns the ID of the key new data keys are wrapped with.
+	ActiveKey(ctx context.Context) (string, error)
+
+	// Wrap encrypts a data key with the master key keyID.
+	Wrap(ctx context.Context, keyID string, dataKey []byte) ([]byte, error)
+
+	// Unwrap decrypts a data key wrapped with the master key keyID.
+	// Returns ErrUnknownKey if there is no such key.
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// KMSReloader is implemented by KMSs that cache their keys. Rotation
+// reloads them first, so a key added since the master started is seen.
+type KMSReloader interface {
+	Reload() error
+}
+
+// KeyFile is a KMS keeping master keys in a local file, one per line, as
+// an ID and a base64-encoded 32-byte AES key, separated by a space:
+//
+//	2026-01 3q2+7wAAAAD...
+//	2026-10 q83vASNFZ4k...
+//
+// The last key is the active one. Blank lines and lines starting with #
+// are ignored. To rotate, append a new key, run the rotate-keys command,
+// and once it has rewrapped everything, remove the old key.
+//
+// The file holds the keys to all the data, so keep it readable by the
+// master alone, and somewhere other than the data and metadata.
+type KeyFile struct {
+	path string
+
+	mu     sync.RWMutex
+	keys   map[string]cipher.AEAD
+	active string
+}
+
+// NewKeyFile loads the master keys from the file at path.
+func NewKeyFile(path string) (*KeyFile, error) {
+	k := &KeyFile{path: path}
+	if err := k.Reload(); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload reads the key file again.
+func (k *KeyFile) Reload() error {
+	f, err := os.Open(k.path)
+	if err != nil {
+		return fmt.Errorf("failed to open key file: %w", err)
+	}
+	defer f.Close()
+
+	keys := make(map[string]cipher.AEAD)
+	var active string
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		id, encoded, ok := strings.Cut(text, " ")
+		if !ok {
+			return fmt.Errorf("%s:%d: want a key ID and a base64 key", k.path, line)
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != encryption.KeySize {
+			return fmt.Errorf("%s:%d: key %q is not a base64-encoded %d-byte key", k.path, line, id, encryption.KeySize)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", k.path, line, err)
+		}
+		keys[id], err = cipher.NewGCM(block)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", k.path, line, err)
+		}
+		active = id
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read key file: %w", err)
+	}
+	if active == "" {
+		return fmt.Errorf("key file %s has no keys", k.path)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.active = active
+	return nil
+}
+
+// ActiveKey returns the ID of the last key in the file.
+func (k *KeyFile) ActiveKey(ctx context.Context) (string, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return k.active, nil
+}
+
+// key returns the cipher for a master key.
+func (k *KeyFile) key(keyID string) (cipher.AEAD, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+	return aead, nil
+}
+
+// Wrap seals a data key with AES-GCM under a random nonce, which goes in
+// front of it. The key ID is authenticated along with it, so a wrapped key
+// can't be passed off as wrapped by another master key.
+func (k *KeyFile) Wrap(ctx context.Context, keyID string, dataKey []byte) ([]byte, error) {
+	aead, err := k.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, dataKey, []byte(keyID)), nil
+}
+
+// Unwrap opens a data key sealed by Wrap.
+func (k *KeyFile) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, err := k.key(keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, fmt.Errorf("wrapped key is too short")
+	}
+	nonce, sealed := wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():]
+	dataKey, err := aead.Open(nil, nonce, sealed, []byte(keyID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key with master key %q: %w", keyID, err)
+	}
+	return dataKey, nil
+}
+
+// Compile-time checks that KeyFile implements KMS and KMSReloader.
+var (
+	_ KMS         = (*KeyFile)(nil)
+	_ KMSReloader = (*KeyFile)(nil)
+)
+
+// newDataKey generates a data key for a new file, and wraps it with the
+// active master key. With no KMS configured, files aren't encrypted and
+// it returns nothing.
+func (s *Server) newDataKey(ctx context.Context) ([]byte, *WrappedKey, error) {
+	if s.kms == nil {
+		return nil, nil, nil
+	}
+
+	dataKey := make([]byte, encryption.KeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	keyID, err := s.kms.ActiveKey(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get master key: %w", err)
+	}
+	wrapped, err := s.kms.Wrap(ctx, keyID, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	return dataKey, &WrappedKey{KeyID: keyID, Key: wrapped}, nil
+}
+
+// dataKey unwraps a file's data key. Unencrypted files have none.
+// A missing master key won't turn up by retrying, so it is reported as
+// codes.FailedPrecondition; a KMS failing is left for the caller to retry.
+func (s *Server) dataKey(ctx context.Context, meta *FileMeta) ([]byte, error) {
+	if meta.DataKey == nil {
+		return nil, nil
+	}
+	if s.kms == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s is encrypted, but no master key is configured", meta.Filename)
+	}
+	key, err := s.kms.Unwrap(ctx, meta.DataKey.KeyID, meta.DataKey.Key)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, status.Errorf(codes.FailedPrecondition, "failed to unwrap data key of %s: %v", meta.Filename, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap data key of %s: %w", meta.Filename, err)
+	}
+	return key, nil
+}
+
+// rotateBatch is how many data keys RotateKeys rewraps per metadata write.
+const rotateBatch = 10000
+
+// RotateKeys rewraps every data key not wrapped with the active master key
+// with it. Afterwards, the old master keys aren't needed any more.
+//
+// Keys are unwrapped and rewrapped without holding up the metadata store,
+// which may mean calls to a remote KMS, then swapped in a batch at a time.
+// A key that changed meanwhile - its file was deleted, say - is left
+// alone; running the rotation again catches anything left over.
+func (s *Server) RotateKeys(ctx context.Context, req *api.RotateKeysRequest) (*api.RotateKeysResponse, error) {
+	if s.kms == nil {
+		return nil, status.Error(codes.FailedPrecondition, "encryption at rest is not configured")
+	}
+	if reloader, ok := s.kms.(KMSReloader); ok {
+		if err := reloader.Reload(); err != nil {
+			return nil, fmt.Errorf("failed to reload master keys: %w", err)
+		}
+	}
+	active, err := s.kms.ActiveKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get master key: %w", err)
+	}
+
+	keys, err := s.metadata.DataKeys()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list data keys: %w", err)
+	}
+
+	var rewrapped, total int64
+	batch := make(map[string]WrappedKey)
+	flush := func() error {
+		n, err := s.metadata.RewrapKeys(batch)
+		if err != nil {
+			return fmt.Errorf("failed to store rewrapped keys: %w", err)
+		}
+		rewrapped += int64(n)
+		clear(batch)
+		return nil
+	}
+	for _, key := range keys {
+		total++
+		if key.KeyID == active {
+			continue
+		}
+		dataKey, err := s.kms.Unwrap(ctx, key.KeyID, key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap data key: %w", err)
+		}
+		wrapped, err := s.kms.Wrap(ctx, active, dataKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to wrap data key: %w", err)
+		}
+		batch[string(key.Key)] = WrappedKey{KeyID: active, Key: wrapped}
+		if len(batch) == rotateBatch {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("master: rewrapped %d of %d data key(s) with master key %q", rewrapped, total, active)
+	return &api.RotateKeysResponse{
+		Success:   true,
+		Message:   fmt.Sprintf("Rewrapped %d of %d data key(s) with master key '%s'", rewrapped, total, active),
+		ActiveKey: active,
+		Rewrapped: rewrapped,
+		Total:     total,
+	}, nil
+}
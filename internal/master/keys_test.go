@@ -0,0 +1,224 @@
+package master
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/compression"
+	"github.com/darshanmadesh/godfs/internal/encryption"
+)
+
+// writeKeyFile writes a master key file with a key for each ID, the last
+// one active, and returns its path. An ID always gets the same key.
+func writeKeyFile(t *testing.T, path string, ids ...string) string {
+	t.Helper()
+	var lines []string
+	for _, id := range ids {
+		key := randomData(int64(crc32.ChecksumIEEE([]byte(id))), encryption.KeySize)
+		lines = append(lines, fmt.Sprintf("%s %s", id, base64.StdEncoding.EncodeToString(key)))
+	}
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// wantKeyIDs checks every data key in the store is wrapped with keyID,
+// and that there are n of them.
+func wantKeyIDs(t *testing.T, store MetadataStore, keyID string, n int) {
+	t.Helper()
+	keys, err := store.DataKeys()
+	if err != nil {
+		t.Fatalf("DataKeys: %v", err)
+	}
+	if len(keys) != n {
+		t.Errorf("%d data keys, want %d", len(keys), n)
+	}
+	for _, key := range keys {
+		if key.KeyID != keyID {
+			t.Errorf("data key wrapped with %q, want %q", key.KeyID, keyID)
+		}
+	}
+}
+
+func TestSealedChunksOnDisk(t *testing.T) {
+	s := newTestServer(t, Config{})
+	_, dir := quietChunkserver(t, s)
+	key := randomData(1, encryption.KeySize)
+	random := randomData(2, 3*encryption.SegmentSize+5000)
+	text := bytes.Repeat([]byte("2026-10-16 request served in 12ms\n"), 8000)
+
+	for _, tc := range []struct {
+		name  string
+		codec string
+		data  []byte
+	}{
+		{"sealed", compression.None, random},
+		{"compressed, then sealed", compression.Zstd, text},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			chunk := writeChunk(t, s, int64(len(tc.data)), tc.codec, key, tc.data)
+			stored, err := os.ReadFile(filepath.Join(dir, chunk.ID))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Nothing on disk or in the metadata gives the data away
+			if bytes.Contains(stored, tc.data[:64]) {
+				t.Error("plaintext found in the stored chunk")
+			}
+			if sum := sha256.Sum256(tc.data); chunk.Hash == hex.EncodeToString(sum[:]) {
+				t.Error("chunk hash is the plain SHA-256 of its data")
+			}
+
+			// Each segment grows by the overhead, which is all that is
+			// added, and only the key reads it back
+			if int64(len(stored)) != chunk.StoredSize {
+				t.Errorf("%d bytes on disk, metadata says %d", len(stored), chunk.StoredSize)
+			}
+			open := func(key []byte) ([]byte, error) {
+				r, err := encryption.NewReader(key, chunk.ID, bytes.NewReader(stored), 0, chunk.StoredSize)
+				if err != nil {
+					return nil, err
+				}
+				return io.ReadAll(r)
+			}
+			plain, err := open(key)
+			if err != nil {
+				t.Fatalf("decrypt: %v", err)
+			}
+			if overhead := encryption.Segments(chunk.StoredSize) * encryption.Overhead; chunk.StoredSize != int64(len(plain))+overhead {
+				t.Errorf("%d bytes stored for %d, want %d more", chunk.StoredSize, len(plain), overhead)
+			}
+			if tc.codec != compression.None {
+				r, err := compression.NewReader(tc.codec, bytes.NewReader(plain))
+				if err != nil {
+					t.Fatalf("NewReader: %v", err)
+				}
+				defer r.Close()
+				if plain, err = io.ReadAll(r); err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+			}
+			if !bytes.Equal(plain, tc.data) {
+				t.Errorf("chunk decrypts to %d bytes, not the data", len(plain))
+			}
+			if _, err := open(randomData(3, encryption.KeySize)); err == nil {
+				t.Error("chunk decrypted with another key")
+			}
+		})
+	}
+}
+
+func TestEncryptedFilesReadBack(t *testing.T) {
+	const chunkSize = 256 * 1024
+	kms, err := NewKeyFile(writeKeyFile(t, filepath.Join(t.TempDir(), "keys"), "k1"))
+	if err != nil {
+		t.Fatalf("NewKeyFile: %v", err)
+	}
+	c := startCluster(t, Config{ChunkSize: chunkSize, KMS: kms}, 1, 0)
+
+	// Ranges are found within sealed segments
+	random := randomData(1, 3*chunkSize+5000)
+	c.upload(t, &api.FileMetadata{Filename: "/random", Replication: 1}, random)
+	checkRanges(t, c, "/random", random, chunkSize)
+
+	// Each file has its own key, so the same data isn't shared
+	resp := c.upload(t, &api.FileMetadata{Filename: "/again", Replication: 1}, random)
+	if resp.DedupChunks != 0 {
+		t.Errorf("encrypted upload shared %d chunks with another file", resp.DedupChunks)
+	}
+}
+
+func TestRotateKeysRewrapsEveryDataKey(t *testing.T) {
+	keyFile := writeKeyFile(t, filepath.Join(t.TempDir(), "keys"), "k1")
+	kms, err := NewKeyFile(keyFile)
+	if err != nil {
+		t.Fatalf("NewKeyFile: %v", err)
+	}
+	s := newTestServer(t, Config{KMS: kms})
+	ctx := context.Background()
+	store := s.metadata
+
+	// Keys in every place one can be: a file, an old version of it, a copy
+	// sharing its key, and a file in the trash
+	dataKeys := make(map[string][]byte)
+	write := func(name string, version int) {
+		t.Helper()
+		key, wrapped, err := s.newDataKey(ctx)
+		if err != nil {
+			t.Fatalf("newDataKey: %v", err)
+		}
+		meta := fileWithChunks(name, [2]string{fmt.Sprint(name, version), "h"})
+		meta.DataKey = wrapped
+		if _, err := store.Replace(meta, AnyGeneration); err != nil {
+			t.Fatalf("Replace: %v", err)
+		}
+		dataKeys[fmt.Sprint(name, version)] = key
+	}
+	write("/a", 1)
+	write("/a", 2)
+	write("/t", 1)
+	if _, err := store.Copy("/a", "/b", false); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	dataKeys["/b1"] = dataKeys["/a2"]
+	trashed, err := store.Trash("/t")
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	wantKeyIDs(t, store, "k1", 3)
+
+	// A new key, then rotation onto it
+	writeKeyFile(t, keyFile, "k1", "k2")
+	resp, err := s.RotateKeys(ctx, &api.RotateKeysRequest{})
+	if err != nil {
+		t.Fatalf("RotateKeys: %v", err)
+	}
+	if resp.ActiveKey != "k2" || resp.Rewrapped != 3 || resp.Total != 3 {
+		t.Errorf("rotation: active %q, rewrapped %d of %d; want k2, 3 of 3", resp.ActiveKey, resp.Rewrapped, resp.Total)
+	}
+	wantKeyIDs(t, store, "k2", 3)
+
+	// Nothing is left for another run
+	if resp, err := s.RotateKeys(ctx, &api.RotateKeysRequest{}); err != nil || resp.Rewrapped != 0 {
+		t.Errorf("second rotation: rewrapped %d, %v; want 0", resp.GetRewrapped(), err)
+	}
+
+	// Without the old key, every data key still unwraps to what it was
+	writeKeyFile(t, keyFile, "k2")
+	if err := kms.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if _, err := store.Undelete(trashed.ID, ""); err != nil {
+		t.Fatalf("Undelete: %v", err)
+	}
+	for _, v := range []struct {
+		name    string
+		version int64
+	}{{"/a", 1}, {"/a", 2}, {"/b", 1}, {"/t", 1}} {
+		meta, err := store.GetVersion(v.name, v.version)
+		if err != nil {
+			t.Fatalf("GetVersion(%s, %d): %v", v.name, v.version, err)
+		}
+		key, err := s.dataKey(ctx, meta)
+		if err != nil {
+			t.Errorf("data key of %s version %d after rotation: %v", v.name, v.version, err)
+			continue
+		}
+		if !bytes.Equal(key, dataKeys[fmt.Sprint(v.name, v.version)]) {
+			t.Errorf("data key of %s version %d changed in rotation", v.name, v.version)
+		}
+	}
+}
@@ -0,0 +1,299 @@
+package master
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Listings come a page at a time, so a big namespace never has to fit in
+// one gRPC message (4MB by default). A FileInfo is a couple of hundred
+// bytes at most, which leaves plenty of room at MaxListPageSize.
+const (
+	DefaultListPageSize = 1000
+	MaxListPageSize     = 10000
+)
+
+// listCursor is what a List page token holds: the last entry of the
+// previous page, in the order the listing is sorted by. The next page
+// starts just after it, so files created or deleted between pages don't
+// shift the pages around - nothing is listed twice, and nothing that
+// existed throughout is skipped.
+type listCursor struct {
+	Sort       api.ListSort `json:"sort"`
+	Descending bool         `json:"desc,omitempty"`
+
+	// Key is the last entry's sort key: its size, or its modification
+	// time in nanoseconds. Unused when sorting by name.
+	Key  int64  `json:"key,omitempty"`
+	Path string `json:"path"`
+}
+
+// encode returns the cursor as an opaque page token.
+func (c listCursor) encode() string {
+	b, _ := json.Marshal(c) // Can't fail: plain fields only
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeListCursor parses a page token from a request. The token must come
+// from a listing sorted the same way.
+func decodeListCursor(token string, req *api.ListRequest) (listCursor, error) {
+	var c listCursor
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		err = json.Unmarshal(b, &c)
+	}
+	if err != nil || c.Path == "" {
+		return listCursor{}, status.Error(codes.InvalidArgument, "invalid page token")
+	}
+	if c.Sort != req.Sort || c.Descending != req.Descending {
+		return listCursor{}, status.Error(codes.InvalidArgument, "page token is for a listing in a different order")
+	}
+	return c, nil
+}
+
+// List returns metadata for the files in a directory, or for all files
+// and directories whose paths start with a prefix, a page at a time.
+//
+// Listings sorted by name are read straight off the metadata store's path
+// index, from just after the previous page. Other orders have to look at
+// every entry in scope to sort them, so they cost more on big directories.
+func (s *Server) List(ctx context.Context, req *api.ListRequest) (*api.ListResponse, error) {
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize < 0:
+		return nil, status.Error(codes.InvalidArgument, "page size must not be negative")
+	case pageSize == 0:
+		pageSize = DefaultListPageSize
+	case pageSize > MaxListPageSize:
+		pageSize = MaxListPageSize
+	}
+
+	var after *listCursor
+	if req.PageToken != "" {
+		c, err := decodeListCursor(req.PageToken, req)
+		if err != nil {
+			return nil, err
+		}
+		after = &c
+	}
+
+	opts, err := s.listScope(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var page []*FileMeta
+	var more bool
+	switch req.Sort {
+	case api.ListSort_LIST_SORT_NAME:
+		// Ask for one extra entry to learn whether there is another page
+		opts.Reverse = req.Descending
+		opts.Limit = pageSize + 1
+		if after != nil {
+			opts.After = after.Path
+		}
+		page, err = s.metadata.Scan(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		if len(page) > pageSize {
+			page, more = page[:pageSize], true
+		}
+	case api.ListSort_LIST_SORT_SIZE, api.ListSort_LIST_SORT_MODIFIED:
+		all, err := s.metadata.Scan(opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list files: %w", err)
+		}
+		key := listSortKey(req.Sort)
+		compare := func(k int64, p string, meta *FileMeta) int {
+			c := cmp.Or(cmp.Compare(k, key(meta)), strings.Compare(p, meta.Filename))
+			if req.Descending {
+				return -c
+			}
+			return c
+		}
+		slices.SortFunc(all, func(a, b *FileMeta) int {
+			return compare(key(a), a.Filename, b)
+		})
+
+		start := 0
+		if after != nil {
+			start, _ = slices.BinarySearchFunc(all, after, func(meta *FileMeta, c *listCursor) int {
+				return -compare(c.Key, c.Path, meta)
+			})
+			if start < len(all) && all[start].Filename == after.Path {
+				start++
+			}
+		}
+		end := min(start+pageSize, len(all))
+		page, more = all[start:end], end < len(all)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "unknown sort order %v", req.Sort)
+	}
+
+	// Convert internal FileMeta to API FileInfo
+	resp := &api.ListResponse{
+		Files: make([]*api.FileInfo, 0, len(page)),
+	}
+	for _, f := range page {
+		resp.Files = append(resp.Files, fileInfo(f))
+	}
+	if more {
+		last := page[len(page)-1]
+		resp.NextPageToken = listCursor{
+			Sort:       req.Sort,
+			Descending: req.Descending,
+			Key:        listSortKey(req.Sort)(last),
+			Path:       last.Filename,
+		}.encode()
+	}
+	return resp, nil
+}
+
+// listScope works out which part of the namespace a List request covers:
+// a directory's entries (or with recursive, everything below it), or
+// every path starting with a prefix; and which entries there its filters
+// let through.
+func (s *Server) listScope(req *api.ListRequest) (ScanOptions, error) {
+	var opts ScanOptions
+	dir := rootDir
+	if req.Directory == "" {
+		// Every path is absolute now; a prefix from before directories
+		// ("rep" for "/report.pdf") means the same thing with a slash.
+		if req.Prefix != "" {
+			opts.Prefix = legacyPath(req.Prefix)
+		}
+	} else {
+		var err error
+		if dir, err = s.listDir(req.Directory); err != nil {
+			return ScanOptions{}, err
+		}
+		opts.Prefix = strings.TrimSuffix(dir, "/") + "/"
+		opts.Shallow = !req.Recursive
+	}
+
+	filter, err := listFilter(req, dir)
+	if err != nil {
+		return ScanOptions{}, err
+	}
+	opts.Filter = filter
+
+	// A directory's entries are one level below it. A path pattern for
+	// anything deeper can only be meant to search further down, which is
+	// what recursive is for; quietly doing so would list more than asked.
+	if opts.Shallow && filter.PathGlob != "" && pathDepth(filter.PathGlob) > pathDepth(dir)+1 {
+		return ScanOptions{}, status.Errorf(codes.InvalidArgument, "glob %q matches paths below the entries of %s: list recursively to search them", req.Glob, dir)
+	}
+
+	// A path pattern says which directories its matches can be in, so
+	// there's no need to scan anywhere else - or, if its last element is
+	// all that's left, below them.
+	if lit := globPrefix(filter.PathGlob); filter.PathGlob != "" && strings.HasPrefix(lit, opts.Prefix) {
+		opts.Prefix = lit
+		opts.Shallow = !strings.Contains(filter.PathGlob[len(lit):], "/")
+	}
+	return opts, nil
+}
+
+// pathDepth returns how many elements a clean path has: 0 for the root.
+func pathDepth(p string) int {
+	if p == rootDir {
+		return 0
+	}
+	return strings.Count(p, "/")
+}
+
+// listDir checks that the directory a List request names exists, and
+// returns its clean path.
+func (s *Server) listDir(directory string) (string, error) {
+	dir, err := cleanPath(directory)
+	if err != nil {
+		return "", metadataStatus(err)
+	}
+	if dir == rootDir {
+		return dir, nil
+	}
+
+	meta, err := s.metadata.Get(dir)
+	switch {
+	case errors.Is(err, ErrFileNotFound):
+		return "", metadataStatus(fmt.Errorf("failed to list directory: %s: %w", dir, err))
+	case err != nil:
+		return "", fmt.Errorf("failed to get metadata: %w", err)
+	case !meta.IsDir:
+		return "", metadataStatus(fmt.Errorf("failed to list directory: %s: %w", dir, ErrNotADirectory))
+	}
+	return dir, nil
+}
+
+// listFilter builds the filter a List request asks for. Relative path
+// patterns are relative to dir, the directory listed.
+func listFilter(req *api.ListRequest, dir string) (ListFilter, error) {
+	filter := ListFilter{
+		MinSize:    req.MinSize,
+		MaxSize:    req.MaxSize,
+		Attributes: req.Attributes,
+		Tags:       req.Tags,
+	}
+	if req.MinSize < 0 || req.MaxSize < 0 {
+		return ListFilter{}, status.Error(codes.InvalidArgument, "size bounds must not be negative")
+	}
+	if req.ModifiedAfter > 0 {
+		filter.ModifiedAfter = time.Unix(req.ModifiedAfter, 0)
+	}
+	if req.ModifiedBefore > 0 {
+		// Inclusive of the whole second, as timestamps are listed in
+		// seconds but kept in nanoseconds
+		filter.ModifiedBefore = time.Unix(req.ModifiedBefore+1, 0).Add(-1)
+	}
+
+	if glob := req.Glob; glob != "" {
+		if _, err := path.Match(glob, ""); err != nil {
+			return ListFilter{}, status.Errorf(codes.InvalidArgument, "invalid glob %q: %v", glob, err)
+		}
+		switch {
+		case !strings.Contains(glob, "/"):
+			filter.NameGlob = glob
+		case strings.HasPrefix(glob, "/"):
+			filter.PathGlob = path.Clean(glob)
+		default:
+			filter.PathGlob = path.Join(dir, glob)
+		}
+	}
+
+	if req.Regex != "" {
+		re, err := regexp.Compile(req.Regex)
+		if err != nil {
+			return ListFilter{}, status.Errorf(codes.InvalidArgument, "invalid regex: %v", err)
+		}
+		filter.Regexp = re
+	}
+	return filter, nil
+}
+
+// listSortKey returns the function giving an entry's sort key for orders
+// other than by name, where it is unused.
+func listSortKey(sort api.ListSort) func(*FileMeta) int64 {
+	switch sort {
+	case api.ListSort_LIST_SORT_SIZE:
+		return func(meta *FileMeta) int64 { return meta.Size }
+	case api.ListSort_LIST_SORT_MODIFIED:
+		return func(meta *FileMeta) int64 { return meta.ModifiedAt.UnixNano() }
+	default:
+		return func(*FileMeta) int64 { return 0 }
+	}
+}
@@ -0,0 +1,187 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// newTestServer returns a master with an in-memory store and no
+// chunkservers, for tests that only touch metadata.
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	s, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+// mkdirs creates directories, with their parents.
+func mkdirs(t *testing.T, store MetadataStore, dirs ...string) {
+	t.Helper()
+	for _, dir := range dirs {
+		if _, err := store.Mkdir(dir, true); err != nil {
+			t.Fatalf("Mkdir(%s): %v", dir, err)
+		}
+	}
+}
+
+// listAll lists every page of a listing and returns the paths in order.
+func listAll(t *testing.T, s *Server, req *api.ListRequest) []string {
+	t.Helper()
+	var paths []string
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("listing doesn't end")
+		}
+		resp, err := s.List(context.Background(), req)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		for _, f := range resp.Files {
+			paths = append(paths, f.Filename)
+		}
+		if resp.NextPageToken == "" {
+			return paths
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+func TestListPagesByName(t *testing.T) {
+	s := newTestServer(t, Config{})
+	mkdirs(t, s.metadata, "/d")
+	var want []string
+	for i := range 25 {
+		name := fmt.Sprintf("/d/f%03d", i)
+		createFile(t, s.metadata, name, int64(i))
+		want = append(want, name)
+	}
+
+	req := &api.ListRequest{Directory: "/d", PageSize: 10}
+	first, err := s.List(context.Background(), req)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(first.Files) != 10 || first.NextPageToken == "" {
+		t.Fatalf("first page has %d entries, token %q; want 10 and a token", len(first.Files), first.NextPageToken)
+	}
+
+	// Changes between pages don't shift them: an entry before the cursor
+	// isn't seen, one after it is, and nothing is listed twice
+	createFile(t, s.metadata, "/d/f000a", 1)
+	createFile(t, s.metadata, "/d/f020a", 1)
+	req.PageToken = first.NextPageToken
+	rest := listAll(t, s, req)
+
+	var got []string
+	for _, f := range first.Files {
+		got = append(got, f.Filename)
+	}
+	got = append(got, rest...)
+	want = slices.Insert(want, 21, "/d/f020a")
+	if !slices.Equal(got, want) {
+		t.Errorf("paged listing = %v\nwant %v", got, want)
+	}
+}
+
+func TestListPagesBySize(t *testing.T) {
+	s := newTestServer(t, Config{})
+	mkdirs(t, s.metadata, "/d")
+	for i := range 12 {
+		// Sizes repeat, so pages break between entries with equal keys
+		createFile(t, s.metadata, fmt.Sprintf("/d/f%02d", i), int64(i%4))
+	}
+
+	got := listAll(t, s, &api.ListRequest{
+		Directory:  "/d",
+		PageSize:   5,
+		Sort:       api.ListSort_LIST_SORT_SIZE,
+		Descending: true,
+	})
+	want := []string{
+		"/d/f11", "/d/f07", "/d/f03",
+		"/d/f10", "/d/f06", "/d/f02",
+		"/d/f09", "/d/f05", "/d/f01",
+		"/d/f08", "/d/f04", "/d/f00",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("listing by size = %v\nwant %v", got, want)
+	}
+
+	// A token only works for the order it came from
+	resp, _ := s.List(context.Background(), &api.ListRequest{Directory: "/d", PageSize: 5, Sort: api.ListSort_LIST_SORT_SIZE})
+	_, err := s.List(context.Background(), &api.ListRequest{Directory: "/d", PageToken: resp.NextPageToken})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("token from another order: err = %v, want InvalidArgument", err)
+	}
+}
+
+func TestListGlobScope(t *testing.T) {
+	s := newTestServer(t, Config{})
+	mkdirs(t, s.metadata, "/logs/2026-01", "/logs/2026-02", "/other/2026-01")
+	for _, name := range []string{
+		"/logs/a.gz",
+		"/logs/2026-01/x.gz",
+		"/logs/2026-01/y.txt",
+		"/logs/2026-02/z.gz",
+		"/other/2026-01/q.gz",
+	} {
+		createFile(t, s.metadata, name, 1)
+	}
+
+	for _, tc := range []struct {
+		name string
+		req  *api.ListRequest
+		want []string
+	}{
+		{
+			name: "name glob, directory entries",
+			req:  &api.ListRequest{Directory: "/logs", Glob: "*.gz"},
+			want: []string{"/logs/a.gz"},
+		},
+		{
+			name: "name glob, recursive",
+			req:  &api.ListRequest{Directory: "/logs", Recursive: true, Glob: "*.gz"},
+			want: []string{"/logs/2026-01/x.gz", "/logs/2026-02/z.gz", "/logs/a.gz"},
+		},
+		{
+			name: "path glob for entries",
+			req:  &api.ListRequest{Directory: "/logs", Glob: "/logs/2026-*"},
+			want: []string{"/logs/2026-01", "/logs/2026-02"},
+		},
+		{
+			name: "relative path glob, recursive",
+			req:  &api.ListRequest{Directory: "/logs", Recursive: true, Glob: "2026-*/*.gz"},
+			want: []string{"/logs/2026-01/x.gz", "/logs/2026-02/z.gz"},
+		},
+		{
+			name: "path glob with a prefix",
+			req:  &api.ListRequest{Prefix: "/", Glob: "/*/2026-01/*.gz"},
+			want: []string{"/logs/2026-01/x.gz", "/other/2026-01/q.gz"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := listAll(t, s, tc.req); !slices.Equal(got, tc.want) {
+				t.Errorf("listing = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	// Without recursive, a directory listing doesn't reach below its
+	// entries, even for a pattern that would
+	for _, glob := range []string{"2026-*/*.gz", "/logs/*/*", "*/x.gz"} {
+		_, err := s.List(context.Background(), &api.ListRequest{Directory: "/logs", Glob: glob})
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("non-recursive listing with glob %q: err = %v, want InvalidArgument", glob, err)
+		}
+	}
+}
@@ -1,7 +1,15 @@
 package master
 
 import (
+	"cmp"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -12,18 +20,176 @@ import (
 var (
 	ErrFileNotFound      = errors.New("file not found")
 	ErrFileAlreadyExists = errors.New("file already exists")
+	ErrChunkNotFound     = errors.New("chunk not found")
+	ErrVersionNotFound   = errors.New("version not found")
+	ErrNotInTrash        = errors.New("no such trash entry")
+
+	// ErrGenerationMismatch means a conditional write lost a race: the
+	// file's current generation isn't the one the caller expected.
+	ErrGenerationMismatch = errors.New("generation mismatch")
+
+	// Namespace errors, for paths that exist but are the wrong kind of
+	// thing, or whose parent directory doesn't exist.
+	ErrParentNotFound    = errors.New("parent directory not found")
+	ErrNotADirectory     = errors.New("not a directory")
+	ErrIsADirectory      = errors.New("is a directory")
+	ErrDirectoryNotEmpty = errors.New("directory not empty")
 )
 
+// AnyGeneration tells Replace to write regardless of the file's current
+// generation (or whether it exists at all).
+const AnyGeneration int64 = -1
+
 // FileMeta represents metadata for a single file in the DFS.
-// This struct will grow as we add features (chunks, replicas, etc.)
+// This struct will grow as we add features (replicas, checksums, etc.)
+//
+// Directories are FileMetas too, with IsDir set and no chunks. Treating
+// them as entries in their own right (inodes, in Unix terms) rather than
+// inferring them from file names means a directory can exist while empty,
+// and a file can only be created inside a directory that exists.
 type FileMeta struct {
+	// Filename is the full, clean path of the file: "/team/ml/v3.bin".
+	// See cleanPath for what makes a path valid.
 	Filename   string
 	Size       int64
 	CreatedAt  time.Time
 	ModifiedAt time.Time
-	// Future fields:
-	// Chunks     []string  // List of chunk IDs
-	// Checksum   string    // File integrity hash
+
+	// IsDir marks a directory.
+	IsDir bool
+
+	// Replication is how many replicas of each chunk the file should have.
+	Replication int
+
+	// Chunks lists the pieces of the file in order. Concatenating the
+	// chunks' data reproduces the file.
+	Chunks []ChunkMeta
+
+	// Checksum is the hex SHA-256 of the file's contents, computed as the
+	// upload streamed through. Empty for files stored before checksums.
+	Checksum string
+
+	// Generation counts content versions: 1 when the file is created, and
+	// incremented each time an upload replaces it. Clients pass it back for
+	// compare-and-swap updates. The store assigns it; callers don't.
+	// It also identifies the file's old versions.
+	Generation int64
+
+	// SupersededAt is when a newer version replaced this one. It is zero
+	// for the current version of a file.
+	SupersededAt time.Time
+
+	// Attributes and Tags are user metadata: free-form key/value pairs,
+	// and labels, kept sorted. See checkAttributes for the limits. They
+	// belong to a version, like its contents: an upload brings its own.
+	Attributes map[string]string
+	Tags       []string
+
+	// DataKey is the key the file's chunks are encrypted with, wrapped
+	// by a master key (see keys.go). It is nil for unencrypted files.
+	DataKey *WrappedKey
+}
+
+// generation returns the file's generation. Files stored before
+// generations existed have none recorded and count as generation 1.
+func (m *FileMeta) generation() int64 {
+	return max(m.Generation, 1)
+}
+
+// ChunkMeta describes one chunk of a file.
+// The chunk's bytes live on chunkservers; the master only tracks where.
+type ChunkMeta struct {
+	// ID is the key the chunk is stored under on every chunkserver.
+	ID string
+
+	// Size is the number of bytes in this chunk. Every chunk except the
+	// last is usually the configured chunk size.
+	Size int64
+
+	// Locations are the addresses of chunkservers holding the chunk.
+	Locations []string
+
+	// CRC32C is the CRC-32C (Castagnoli) of the chunk's data, as confirmed
+	// by every replica when the chunk was written.
+	CRC32C uint32
+
+	// Hash is the SHA-256 of the chunk's data, hex encoded: its content
+	// ID. Uploads look chunks up by it to store identical data only once.
+	// Chunks written before content addressing have none.
+	Hash string
+
+	// Codec is how the chunk's data is compressed at rest (see package
+	// compression), and StoredSize the size it takes up on a replica.
+	// Size and Hash are about the data before compression; CRC32C is of
+	// the bytes stored, which is what chunkservers can check. Chunks
+	// stored as is have no codec, and may have no StoredSize either if
+	// they were written before compression existed.
+	Codec      string
+	StoredSize int64
+}
+
+// storedSize returns the space the chunk takes up on each replica.
+func (c *ChunkMeta) storedSize() int64 {
+	if c.StoredSize == 0 && c.Codec == "" {
+		return c.Size
+	}
+	return c.StoredSize
+}
+
+// ChunkUse is a chunk as the files using it need it kept.
+type ChunkUse struct {
+	Chunk ChunkMeta
+
+	// Replication is the highest replication of any file or old version
+	// referencing the chunk.
+	Replication int
+}
+
+// clone returns a deep copy of the metadata.
+// A plain struct copy (*meta) would share the Chunks slice, letting the
+// caller modify our internal state through it.
+func (m *FileMeta) clone() *FileMeta {
+	c := *m
+	if m.Chunks != nil {
+		c.Chunks = make([]ChunkMeta, len(m.Chunks))
+		for i, chunk := range m.Chunks {
+			chunk.Locations = append([]string(nil), chunk.Locations...)
+			c.Chunks[i] = chunk
+		}
+	}
+	c.Attributes = maps.Clone(m.Attributes)
+	c.Tags = slices.Clone(m.Tags)
+	if m.DataKey != nil {
+		key := *m.DataKey
+		c.DataKey = &key
+	}
+	return &c
+}
+
+// TrashEntry is a deleted file waiting in the trash: the file as it was
+// when deleted, at its original path, along with its old versions. Its
+// chunks stay referenced until the entry is purged, so undeleting it is
+// just a metadata change.
+type TrashEntry struct {
+	// ID identifies the entry. The same path can be deleted many times,
+	// so the path alone doesn't.
+	ID        string
+	DeletedAt time.Time
+	File      *FileMeta
+
+	// Versions are the file's old versions, newest first.
+	Versions []*FileMeta
+}
+
+// clone returns a deep copy of the entry.
+func (e *TrashEntry) clone() *TrashEntry {
+	c := *e
+	c.File = e.File.clone()
+	c.Versions = make([]*FileMeta, len(e.Versions))
+	for i, v := range e.Versions {
+		c.Versions[i] = v.clone()
+	}
+	return &c
 }
 
 // MetadataStore defines the interface for metadata operations.
@@ -31,25 +197,163 @@ type FileMeta struct {
 // 1. Swap implementations (in-memory -> database) without changing server code
 // 2. Create mock implementations for testing
 // 3. Document the contract clearly
+//
+// Paths passed to the store must already be clean (see cleanPath). Every
+// file or directory except the root, which always exists, needs its parent
+// directory to exist: writes fail with ErrParentNotFound otherwise.
 type MetadataStore interface {
 	// Create adds a new file's metadata. Returns ErrFileAlreadyExists if file exists.
 	Create(meta *FileMeta) error
 
+	// Replace stores a file's metadata, replacing the file if it exists,
+	// and returns the metadata it replaced (nil if the file is new), which
+	// is kept as an old version of the file. Unless ifGeneration is
+	// AnyGeneration, the write only happens if the file's current
+	// generation equals ifGeneration, 0 meaning the file must not exist;
+	// otherwise it returns ErrGenerationMismatch.
+	Replace(meta *FileMeta, ifGeneration int64) (*FileMeta, error)
+
 	// Get retrieves metadata for a file. Returns ErrFileNotFound if not found.
 	Get(filename string) (*FileMeta, error)
 
 	// Update modifies an existing file's metadata. Returns ErrFileNotFound if not found.
 	Update(meta *FileMeta) error
 
-	// Delete removes a file's metadata. Returns ErrFileNotFound if not found.
-	Delete(filename string) error
+	// Delete removes a file's metadata, along with its old versions, and
+	// returns everything it removed so the caller can release the chunks.
+	// Returns ErrFileNotFound if not found, and ErrIsADirectory for
+	// directories, which need Rmdir.
+	Delete(filename string) ([]*FileMeta, error)
 
-	// List returns all files matching the optional prefix filter.
-	// Pass empty string to list all files.
+	// Trash moves a file, with its old versions, out of the namespace and
+	// into the trash, and returns the new trash entry. Errors are as for
+	// Delete.
+	Trash(filename string) (*TrashEntry, error)
+
+	// ListTrash returns every trash entry, most recently deleted first.
+	ListTrash() ([]*TrashEntry, error)
+
+	// GetTrash retrieves a trash entry. Returns ErrNotInTrash if there is
+	// no entry with that ID.
+	GetTrash(id string) (*TrashEntry, error)
+
+	// Undelete moves a trash entry back into the namespace at dst, or at
+	// its original path if dst is empty, and returns the file's metadata.
+	// Fails with ErrFileAlreadyExists if something is there now.
+	Undelete(id, dst string) (*FileMeta, error)
+
+	// PurgeTrash removes trash entries for good and returns the files and
+	// versions they held, so the caller can release the chunks. IDs not in
+	// the trash are ignored.
+	PurgeTrash(ids []string) ([]*FileMeta, error)
+
+	// List returns all files matching the optional prefix filter, sorted
+	// by path. Pass empty string to list all files.
 	List(prefix string) ([]*FileMeta, error)
 
+	// Scan returns the files and directories opts selects, in path order
+	// (or reverse path order), leaving out those its filter rejects.
+	// Listing a page at a time costs in proportion to the entries
+	// looked at, not to the namespace.
+	Scan(opts ScanOptions) ([]*FileMeta, error)
+
+	// Mkdir creates a directory. With parents set it also creates any
+	// missing ancestors, and succeeds if the directory already exists,
+	// like "mkdir -p". It returns the directories it created, outermost
+	// first.
+	Mkdir(dir string, parents bool) ([]*FileMeta, error)
+
+	// Rmdir removes a directory. It must be empty unless recursive is set,
+	// in which case everything below it goes too, in one atomic step. With
+	// trash set, the files below go to the trash, one entry each, and are
+	// returned as trashed. Otherwise it returns the metadata of the files
+	// (and versions) removed, so the caller can release their chunks.
+	Rmdir(dir string, recursive, trash bool) (removed []*FileMeta, trashed []*TrashEntry, err error)
+
+	// ListDir returns the entries of a directory sorted by name: just its
+	// immediate children, or with recursive set, everything below it.
+	ListDir(dir string, recursive bool) ([]*FileMeta, error)
+
+	// Rename moves a file or directory (with everything below it, and
+	// their old versions) to a new path in one atomic step, and returns its
+	// metadata at the new path. If the destination is an existing file it
+	// is replaced when overwrite is set, becoming an old version of the
+	// moved file; otherwise Rename fails with ErrFileAlreadyExists. The
+	// source's own old versions don't survive a replace: they are returned
+	// so the caller can release their chunks. A directory is never replaced.
+	Rename(src, dst string, overwrite bool) (moved *FileMeta, removed []*FileMeta, err error)
+
+	// Copy creates dst as a copy of the file src, sharing its chunks, and
+	// returns the new file's metadata. The destination is handled as in
+	// Rename. Directories can't be copied, and neither are old versions.
+	Copy(src, dst string, overwrite bool) (*FileMeta, error)
+
+	// ListVersions returns every version of a file, newest first: the
+	// current one, then the old versions still kept.
+	// Returns ErrFileNotFound if the file doesn't exist.
+	ListVersions(filename string) ([]*FileMeta, error)
+
+	// GetVersion retrieves one version of a file by its generation, which
+	// may be the current one. Returns ErrVersionNotFound if it isn't kept.
+	GetVersion(filename string, generation int64) (*FileMeta, error)
+
+	// Restore makes an old version of a file current again, as a new
+	// generation; what was current becomes an old version in turn.
+	Restore(filename string, generation int64) (*FileMeta, error)
+
+	// DeleteVersions removes old versions of a file and returns them, so
+	// the caller can release their chunks. Generations that aren't kept
+	// are ignored; the current version can't be removed this way.
+	DeleteVersions(filename string, generations []int64) ([]*FileMeta, error)
+
+	// ListOldVersions returns the old versions of every file.
+	ListOldVersions() ([]*FileMeta, error)
+
+	// SetAttributes edits the user metadata of a file or directory in
+	// place, and returns its metadata after the change. Its contents,
+	// generation and timestamps stay as they are. Returns
+	// ErrInvalidAttributes if the result would break the limits.
+	SetAttributes(filename string, change AttributeChange) (*FileMeta, error)
+
 	// Exists checks if a file exists without returning full metadata.
 	Exists(filename string) bool
+
+	// GetChunk returns the metadata of a chunk referenced by any file
+	// (or old version). Returns ErrChunkNotFound if nothing references it.
+	GetChunk(chunkID string) (*ChunkMeta, error)
+
+	// FindChunk returns the metadata of a referenced chunk with the given
+	// content hash (see ChunkMeta.Hash), so new data identical to it can
+	// share it. Returns ErrChunkNotFound if there is none.
+	FindChunk(hash string) (*ChunkMeta, error)
+
+	// ChunkRefs returns the number of files and old versions (including
+	// those in the trash) referencing a chunk. Files made by Copy share
+	// their source's chunks, as do versions that didn't change them, so a
+	// chunk's data may only be deleted once this drops to zero.
+	ChunkRefs(chunkID string) int
+
+	// ReferencedChunks returns every chunk referenced by a file or old
+	// version, in the namespace or in the trash: the chunks ChunkRefs
+	// counts, and so the ones whose data must be kept. Each is listed
+	// once, with the highest replication anything using it asks for.
+	ReferencedChunks() ([]ChunkUse, error)
+
+	// DataKeys returns the wrapped data key of every encrypted file and
+	// old version, in the namespace or in the trash. Files sharing a key
+	// (copies, and versions with the same contents) share an entry.
+	DataKeys() ([]WrappedKey, error)
+
+	// RewrapKeys replaces wrapped data keys: every file and version whose
+	// key's Key, as a string, is in rewrap gets the value instead. It
+	// returns how many of the keys in rewrap it found.
+	RewrapKeys(rewrap map[string]WrappedKey) (int, error)
+
+	// SetChunkLocations replaces the locations of a chunk in every file
+	// and version that references it. Unlike Update, this doesn't touch ModifiedAt:
+	// moving replicas around doesn't change the file's contents.
+	// Returns ErrChunkNotFound if no file references the chunk.
+	SetChunkLocations(chunkID string, locations []string) error
 }
 
 // InMemoryMetadataStore implements MetadataStore using an in-memory map.
@@ -63,13 +367,92 @@ type InMemoryMetadataStore struct {
 
 	// files maps filename -> metadata
 	files map[string]*FileMeta
+
+	// index holds the paths of files in sorted order, for listings.
+	index pathIndex
+
+	// children is an index from a directory's path to the paths of its
+	// immediate children. Like chunkFiles, it is derived from files.
+	children map[string]map[string]struct{}
+
+	// versions holds the old versions of files, by path and generation.
+	// Only files that exist have old versions.
+	versions map[string]map[int64]*FileMeta
+
+	// trash holds deleted files waiting to be undeleted or purged, by ID.
+	trash map[string]*TrashEntry
+
+	// chunkFiles is an index from chunk ID to the files and versions
+	// referencing it. It is derived from files, versions and trash (never
+	// stored), kept up to date by apply. The size of each set is the
+	// chunk's reference count.
+	chunkFiles map[string]map[chunkRef]struct{}
+
+	// contentChunks is an index from content hash to the IDs of the
+	// chunks with that content. Usually there is one, but uploads racing
+	// each other can both store new data, and each copy stays findable
+	// while anything references it. Like chunkFiles it is derived, and
+	// only holds chunks that are referenced.
+	contentChunks map[string]map[string]struct{}
+
+	// journal, if set, durably records every mutation before it is applied.
+	// The plain in-memory store leaves it nil; WALMetadataStore plugs in here.
+	journal journal
+}
+
+// mutation describes a set of metadata changes that are applied as one
+// atomic unit. Every write to the store is expressed as a mutation so that a
+// durable store can log exactly what changed and replay it after a restart.
+type mutation struct {
+	Put    []*FileMeta `json:"put,omitempty"`
+	Delete []string    `json:"delete,omitempty"`
+
+	// PutVersions and DeleteVersions do the same for old versions, which
+	// are identified by filename and generation.
+	PutVersions    []*FileMeta  `json:"put_versions,omitempty"`
+	DeleteVersions []versionRef `json:"delete_versions,omitempty"`
+
+	// PutTrash and DeleteTrash add and remove trash entries, by ID.
+	PutTrash    []*TrashEntry `json:"put_trash,omitempty"`
+	DeleteTrash []string      `json:"delete_trash,omitempty"`
+}
+
+// versionRef identifies an old version of a file.
+type versionRef struct {
+	Filename   string `json:"filename"`
+	Generation int64  `json:"generation"`
+}
+
+// chunkRef is an entry in the chunk index: a file, or with a non-zero
+// generation, one of its old versions. With trash set, it is the file or
+// version in that trash entry instead, and filename is unused.
+type chunkRef struct {
+	filename   string
+	generation int64
+	trash      string
+}
+
+// journal is implemented by durable stores that want to observe mutations.
+type journal interface {
+	// append durably records m. It is called with the store's write lock held
+	// and before m is applied, so a failed append leaves the store unchanged.
+	append(m *mutation) error
+
+	// applied is called after m has been applied, still under the write lock.
+	// Implementations use it to decide when to take a snapshot.
+	applied()
 }
 
 // NewInMemoryMetadataStore creates a new in-memory metadata store.
 // In Go, constructor functions are named New<Type> by convention.
 func NewInMemoryMetadataStore() *InMemoryMetadataStore {
 	return &InMemoryMetadataStore{
-		files: make(map[string]*FileMeta),
+		files:         make(map[string]*FileMeta),
+		children:      make(map[string]map[string]struct{}),
+		versions:      make(map[string]map[int64]*FileMeta),
+		trash:         make(map[string]*TrashEntry),
+		chunkFiles:    make(map[string]map[chunkRef]struct{}),
+		contentChunks: make(map[string]map[string]struct{}),
 	}
 }
 
@@ -82,6 +465,9 @@ func (s *InMemoryMetadataStore) Create(meta *FileMeta) error {
 	if _, exists := s.files[meta.Filename]; exists {
 		return ErrFileAlreadyExists
 	}
+	if err := s.checkParent(meta.Filename); err != nil {
+		return err
+	}
 
 	// Set timestamps if not provided
 	now := time.Now()
@@ -91,14 +477,57 @@ func (s *InMemoryMetadataStore) Create(meta *FileMeta) error {
 	if meta.ModifiedAt.IsZero() {
 		meta.ModifiedAt = now
 	}
+	meta.Generation = 1
 
 	// Store a copy to prevent external modification
 	// This is defensive programming - the caller can't accidentally
 	// modify our internal state after Create() returns
-	stored := *meta
-	s.files[meta.Filename] = &stored
+	return s.commit(&mutation{Put: []*FileMeta{meta.clone()}})
+}
 
-	return nil
+// Replace creates or overwrites file metadata, optionally conditioned on
+// the current generation. The check and the write happen under one lock,
+// which is what makes this a compare-and-swap.
+func (s *InMemoryMetadataStore) Replace(meta *FileMeta, ifGeneration int64) (*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, exists := s.files[meta.Filename]
+	if exists && old.IsDir {
+		return nil, fmt.Errorf("%s: %w", meta.Filename, ErrIsADirectory)
+	}
+	if err := s.checkParent(meta.Filename); err != nil {
+		return nil, err
+	}
+
+	var current int64 // 0: doesn't exist
+	if exists {
+		current = old.generation()
+	}
+	if ifGeneration != AnyGeneration && ifGeneration != current {
+		return nil, fmt.Errorf("%w: expected %d, current is %d", ErrGenerationMismatch, ifGeneration, current)
+	}
+
+	now := time.Now()
+	meta.CreatedAt = now
+	if exists {
+		// Same file, new contents: it keeps its creation time
+		meta.CreatedAt = old.CreatedAt
+	}
+	meta.ModifiedAt = now
+	meta.Generation = current + 1
+
+	m := &mutation{Put: []*FileMeta{meta.clone()}}
+	if exists {
+		archive(m, old, now)
+	}
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+	return old.clone(), nil
 }
 
 // Get retrieves file metadata by filename.
@@ -112,8 +541,7 @@ func (s *InMemoryMetadataStore) Get(filename string) (*FileMeta, error) {
 	}
 
 	// Return a copy to prevent external modification of our data
-	result := *meta
-	return &result, nil
+	return meta.clone(), nil
 }
 
 // Update modifies existing file metadata.
@@ -129,47 +557,682 @@ func (s *InMemoryMetadataStore) Update(meta *FileMeta) error {
 	meta.ModifiedAt = time.Now()
 
 	// Store a copy
-	stored := *meta
-	s.files[meta.Filename] = &stored
+	return s.commit(&mutation{Put: []*FileMeta{meta.clone()}})
+}
 
-	return nil
+// Delete removes file metadata, and the file's old versions, from the store.
+func (s *InMemoryMetadataStore) Delete(filename string) ([]*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, exists := s.files[filename]
+	if !exists {
+		return nil, ErrFileNotFound
+	}
+	if meta.IsDir {
+		return nil, fmt.Errorf("%s: %w", filename, ErrIsADirectory)
+	}
+
+	m := &mutation{Delete: []string{filename}}
+	removed := append([]*FileMeta{meta.clone()}, s.dropVersions(m, filename)...)
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return removed, nil
 }
 
-// Delete removes file metadata from the store.
-func (s *InMemoryMetadataStore) Delete(filename string) error {
+// Trash moves a file and its old versions into the trash.
+func (s *InMemoryMetadataStore) Trash(filename string) (*TrashEntry, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, exists := s.files[filename]; !exists {
-		return ErrFileNotFound
+	meta, exists := s.files[filename]
+	if !exists {
+		return nil, ErrFileNotFound
+	}
+	if meta.IsDir {
+		return nil, fmt.Errorf("%s: %w", filename, ErrIsADirectory)
 	}
 
-	delete(s.files, filename)
-	return nil
+	m := &mutation{}
+	entry, err := s.trashFile(m, meta, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return entry.clone(), nil
+}
+
+// ListTrash returns the trash entries, most recently deleted first.
+func (s *InMemoryMetadataStore) ListTrash() ([]*TrashEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]*TrashEntry, 0, len(s.trash))
+	for _, entry := range s.trash {
+		result = append(result, entry.clone())
+	}
+	slices.SortFunc(result, func(a, b *TrashEntry) int {
+		return cmp.Or(b.DeletedAt.Compare(a.DeletedAt), strings.Compare(a.ID, b.ID))
+	})
+	return result, nil
+}
+
+// GetTrash retrieves a trash entry by ID.
+func (s *InMemoryMetadataStore) GetTrash(id string) (*TrashEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entry, exists := s.trash[id]
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotInTrash)
+	}
+	return entry.clone(), nil
+}
+
+// Undelete puts a trash entry back in the namespace. The file keeps its
+// generation and old versions, as if it had never been deleted.
+func (s *InMemoryMetadataStore) Undelete(id, dst string) (*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, exists := s.trash[id]
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", id, ErrNotInTrash)
+	}
+	if dst == "" {
+		dst = entry.File.Filename
+	}
+	if dst == rootDir {
+		return nil, fmt.Errorf("%s: %w", dst, ErrIsADirectory)
+	}
+	if _, exists := s.files[dst]; exists {
+		return nil, fmt.Errorf("%s: %w", dst, ErrFileAlreadyExists)
+	}
+	if err := s.checkParent(dst); err != nil {
+		return nil, err
+	}
+
+	restored := entry.File.clone()
+	restored.Filename = dst
+	m := &mutation{
+		DeleteTrash: []string{id},
+		Put:         []*FileMeta{restored},
+	}
+	for _, v := range entry.Versions {
+		v = v.clone()
+		v.Filename = dst
+		m.PutVersions = append(m.PutVersions, v)
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return restored.clone(), nil
+}
+
+// PurgeTrash deletes trash entries for good.
+func (s *InMemoryMetadataStore) PurgeTrash(ids []string) ([]*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m := &mutation{}
+	var removed []*FileMeta
+	for _, id := range ids {
+		entry, exists := s.trash[id]
+		if !exists {
+			continue
+		}
+		m.DeleteTrash = append(m.DeleteTrash, id)
+		removed = append(removed, entry.File.clone())
+		for _, v := range entry.Versions {
+			removed = append(removed, v.clone())
+		}
+	}
+	if len(m.DeleteTrash) == 0 {
+		return nil, nil
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// trashFile adds moving a file and its old versions into the trash to m,
+// and returns the new entry. Callers must hold the lock.
+func (s *InMemoryMetadataStore) trashFile(m *mutation, meta *FileMeta, now time.Time) (*TrashEntry, error) {
+	id, err := newTrashID()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := s.dropVersions(m, meta.Filename)
+	slices.SortFunc(versions, func(a, b *FileMeta) int {
+		return cmp.Compare(b.Generation, a.Generation)
+	})
+	entry := &TrashEntry{
+		ID:        id,
+		DeletedAt: now,
+		File:      meta.clone(),
+		Versions:  versions,
+	}
+	m.Delete = append(m.Delete, meta.Filename)
+	m.PutTrash = append(m.PutTrash, entry)
+	return entry, nil
+}
+
+// newTrashID returns a random 64-bit trash entry ID, hex encoded: short
+// enough to type, and still never going to collide within one trash.
+func newTrashID() (string, error) {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("failed to generate trash ID: %w", err)
+	}
+	return hex.EncodeToString(b[:]), nil
 }
 
 // List returns all files matching the prefix filter.
 func (s *InMemoryMetadataStore) List(prefix string) ([]*FileMeta, error) {
+	return s.Scan(ScanOptions{Prefix: prefix})
+}
+
+// Scan walks the path index, so entries come out already sorted.
+func (s *InMemoryMetadataStore) Scan(opts ScanOptions) ([]*FileMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	paths := s.index.scan(opts, func(p string) bool {
+		return opts.Filter.matches(s.files[p])
+	})
+	result := make([]*FileMeta, 0, len(paths))
+	for _, p := range paths {
+		// Return copies to prevent external modification
+		result = append(result, s.files[p].clone())
+	}
+	return result, nil
+}
+
+// Mkdir creates a directory, and with parents set, its missing ancestors.
+// All the directories are created in one mutation, so a crash can't leave
+// half of a path behind.
+func (s *InMemoryMetadataStore) Mkdir(dir string, parents bool) ([]*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir == rootDir {
+		if parents {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%s: %w", dir, ErrFileAlreadyExists)
+	}
+
+	// Walk down from the root: "/a", "/a/b", "/a/b/c"
+	m := &mutation{}
+	now := time.Now()
+	for i := 1; i <= len(dir); i++ {
+		if i < len(dir) && dir[i] != '/' {
+			continue
+		}
+		p := dir[:i]
+		last := i == len(dir)
+
+		if meta, exists := s.files[p]; exists {
+			switch {
+			case !meta.IsDir:
+				return nil, fmt.Errorf("%s: %w", p, ErrNotADirectory)
+			case last && !parents:
+				return nil, fmt.Errorf("%s: %w", p, ErrFileAlreadyExists)
+			}
+			continue
+		}
+		if !last && !parents {
+			return nil, fmt.Errorf("%s: %w", p, ErrParentNotFound)
+		}
+		m.Put = append(m.Put, &FileMeta{
+			Filename:   p,
+			IsDir:      true,
+			CreatedAt:  now,
+			ModifiedAt: now,
+			Generation: 1,
+		})
+	}
+
+	if len(m.Put) == 0 {
+		return nil, nil // mkdir -p of a directory that exists
+	}
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+
+	created := make([]*FileMeta, 0, len(m.Put))
+	for _, meta := range m.Put {
+		created = append(created, meta.clone())
+	}
+	return created, nil
+}
+
+// Rmdir removes a directory, and with recursive set, its whole subtree.
+func (s *InMemoryMetadataStore) Rmdir(dir string, recursive, trash bool) (removed []*FileMeta, trashed []*TrashEntry, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if dir == rootDir {
+		return nil, nil, fmt.Errorf("cannot remove the root directory: %w", ErrInvalidPath)
+	}
+	meta, exists := s.files[dir]
+	if !exists {
+		return nil, nil, fmt.Errorf("%s: %w", dir, ErrFileNotFound)
+	}
+	if !meta.IsDir {
+		return nil, nil, fmt.Errorf("%s: %w", dir, ErrNotADirectory)
+	}
+	if !recursive && len(s.children[dir]) > 0 {
+		return nil, nil, fmt.Errorf("%s: %w", dir, ErrDirectoryNotEmpty)
+	}
+
+	m := &mutation{Delete: []string{dir}}
+	now := time.Now()
+	for _, entry := range s.descendants(dir) {
+		switch {
+		case entry.IsDir:
+			m.Delete = append(m.Delete, entry.Filename)
+		case trash:
+			t, err := s.trashFile(m, entry, now)
+			if err != nil {
+				return nil, nil, err
+			}
+			trashed = append(trashed, t)
+		default:
+			m.Delete = append(m.Delete, entry.Filename)
+			removed = append(removed, entry.clone())
+			removed = append(removed, s.dropVersions(m, entry.Filename)...)
+		}
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, nil, err
+	}
+	for i, t := range trashed {
+		trashed[i] = t.clone()
+	}
+	return removed, trashed, nil
+}
+
+// ListDir returns a directory's children, or all its descendants.
+func (s *InMemoryMetadataStore) ListDir(dir string, recursive bool) ([]*FileMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if dir != rootDir {
+		meta, exists := s.files[dir]
+		if !exists {
+			return nil, fmt.Errorf("%s: %w", dir, ErrFileNotFound)
+		}
+		if !meta.IsDir {
+			return nil, fmt.Errorf("%s: %w", dir, ErrNotADirectory)
+		}
+	}
+
+	var entries []*FileMeta
+	if recursive {
+		entries = s.descendants(dir)
+	} else {
+		entries = make([]*FileMeta, 0, len(s.children[dir]))
+		for child := range s.children[dir] {
+			entries = append(entries, s.files[child])
+		}
+	}
+
+	result := make([]*FileMeta, 0, len(entries))
+	for _, meta := range entries {
+		result = append(result, meta.clone())
+	}
+	sortByFilename(result)
+	return result, nil
+}
+
+// sortByFilename sorts metadata by path, so listings come out in a stable,
+// readable order rather than in map order.
+func sortByFilename(files []*FileMeta) {
+	slices.SortFunc(files, func(a, b *FileMeta) int {
+		return strings.Compare(a.Filename, b.Filename)
+	})
+}
+
+// Rename moves src to dst. Chunks are stored by ID, not by name, so this
+// is purely a metadata change: a single mutation deletes the old paths and
+// puts the new ones, and no reader ever sees both or neither.
+func (s *InMemoryMetadataStore) Rename(src, dst string, overwrite bool) (moved *FileMeta, removed []*FileMeta, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if src == rootDir {
+		return nil, nil, fmt.Errorf("cannot rename the root directory: %w", ErrInvalidPath)
+	}
+	if dst == rootDir {
+		return nil, nil, fmt.Errorf("%s: %w", dst, ErrIsADirectory)
+	}
+	meta, exists := s.files[src]
+	if !exists {
+		return nil, nil, fmt.Errorf("%s: %w", src, ErrFileNotFound)
+	}
+	if src == dst {
+		return meta.clone(), nil, nil
+	}
+	if meta.IsDir && strings.HasPrefix(dst, src+"/") {
+		return nil, nil, fmt.Errorf("cannot move %s into itself: %w", src, ErrInvalidPath)
+	}
+	if err := s.checkParent(dst); err != nil {
+		return nil, nil, err
+	}
+
+	moved = meta.clone()
+	moved.Filename = dst
+	m := &mutation{
+		Delete: []string{src},
+		Put:    []*FileMeta{moved},
+	}
+
+	old, replacing := s.files[dst]
+	if replacing {
+		switch {
+		case !overwrite:
+			return nil, nil, fmt.Errorf("%s: %w", dst, ErrFileAlreadyExists)
+		case old.IsDir:
+			return nil, nil, fmt.Errorf("%s: %w", dst, ErrIsADirectory)
+		case meta.IsDir:
+			return nil, nil, fmt.Errorf("%s: %w", dst, ErrNotADirectory)
+		}
+		// New contents at dst, as far as anyone watching it can tell:
+		// bump past its generation so compare-and-swap writers notice.
+		// What was there is kept as an old version. The source's own
+		// history can't come along: its generations would clash.
+		moved.Generation = old.generation() + 1
+		archive(m, old, time.Now())
+		removed = s.dropVersions(m, src)
+	} else {
+		s.moveVersions(m, src, dst)
+	}
+
+	if meta.IsDir {
+		// Everything below moves with it, under the new prefix
+		for _, entry := range s.descendants(src) {
+			child := entry.clone()
+			child.Filename = dst + strings.TrimPrefix(entry.Filename, src)
+			m.Delete = append(m.Delete, entry.Filename)
+			m.Put = append(m.Put, child)
+			s.moveVersions(m, entry.Filename, child.Filename)
+		}
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, nil, err
+	}
+	return moved.clone(), removed, nil
+}
+
+// Copy makes dst a new file with src's contents. Only metadata is written:
+// the copy references the same chunks, so it costs the same however big
+// the file is. Nothing ever modifies a chunk once written - a new upload
+// writes new chunks - so sharing them is safe, and the chunk index counts
+// the references that keep them alive.
+func (s *InMemoryMetadataStore) Copy(src, dst string, overwrite bool) (*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, exists := s.files[src]
+	if src == rootDir || exists && meta.IsDir {
+		return nil, fmt.Errorf("%s: %w", src, ErrIsADirectory)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", src, ErrFileNotFound)
+	}
+	if dst == rootDir {
+		return nil, fmt.Errorf("%s: %w", dst, ErrIsADirectory)
+	}
+	if err := s.checkParent(dst); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	copied := meta.clone()
+	copied.Filename = dst
+	copied.CreatedAt = now
+	copied.ModifiedAt = now
+	copied.Generation = 1
+	m := &mutation{Put: []*FileMeta{copied}}
+
+	old, replacing := s.files[dst]
+	if replacing {
+		switch {
+		case src == dst:
+			return nil, fmt.Errorf("cannot copy %s onto itself: %w", src, ErrInvalidPath)
+		case !overwrite:
+			return nil, fmt.Errorf("%s: %w", dst, ErrFileAlreadyExists)
+		case old.IsDir:
+			return nil, fmt.Errorf("%s: %w", dst, ErrIsADirectory)
+		}
+		copied.CreatedAt = old.CreatedAt
+		copied.Generation = old.generation() + 1
+		archive(m, old, now)
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return copied.clone(), nil
+}
+
+// ListVersions returns a file's current and old versions, newest first.
+func (s *InMemoryMetadataStore) ListVersions(filename string) ([]*FileMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	meta, exists := s.files[filename]
+	if !exists {
+		return nil, ErrFileNotFound
+	}
+	if meta.IsDir {
+		return nil, fmt.Errorf("%s: %w", filename, ErrIsADirectory)
+	}
+
+	result := []*FileMeta{meta.clone()}
+	for _, v := range s.sortedVersions(filename) {
+		result = append(result, v.clone())
+	}
+	return result, nil
+}
+
+// GetVersion retrieves one version of a file.
+func (s *InMemoryMetadataStore) GetVersion(filename string, generation int64) (*FileMeta, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	// Pre-allocate slice with estimated capacity for efficiency
-	// This avoids repeated allocations as the slice grows
-	result := make([]*FileMeta, 0, len(s.files))
+	if meta, exists := s.files[filename]; exists && !meta.IsDir && meta.generation() == generation {
+		return meta.clone(), nil
+	}
+	v, exists := s.versions[filename][generation]
+	if !exists {
+		return nil, fmt.Errorf("%s generation %d: %w", filename, generation, ErrVersionNotFound)
+	}
+	return v.clone(), nil
+}
+
+// Restore brings back an old version of a file. Like Copy, only metadata
+// changes: the restored version shares the old version's chunks.
+func (s *InMemoryMetadataStore) Restore(filename string, generation int64) (*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.files[filename]
+	if !exists {
+		return nil, ErrFileNotFound
+	}
+	if current.IsDir {
+		return nil, fmt.Errorf("%s: %w", filename, ErrIsADirectory)
+	}
+	v, exists := s.versions[filename][generation]
+	if !exists {
+		return nil, fmt.Errorf("%s generation %d: %w", filename, generation, ErrVersionNotFound)
+	}
+
+	now := time.Now()
+	restored := v.clone()
+	restored.CreatedAt = current.CreatedAt
+	restored.ModifiedAt = now
+	restored.Generation = current.generation() + 1
+	restored.SupersededAt = time.Time{}
+
+	m := &mutation{Put: []*FileMeta{restored}}
+	archive(m, current, now)
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return restored.clone(), nil
+}
+
+// DeleteVersions removes some of a file's old versions.
+func (s *InMemoryMetadataStore) DeleteVersions(filename string, generations []int64) ([]*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	for filename, meta := range s.files {
-		// If prefix is empty, include all files
-		// Otherwise, check if filename starts with prefix
-		if prefix == "" || len(filename) >= len(prefix) && filename[:len(prefix)] == prefix {
-			// Return copies to prevent external modification
-			copy := *meta
-			result = append(result, &copy)
+	m := &mutation{}
+	var removed []*FileMeta
+	for _, gen := range generations {
+		if v, exists := s.versions[filename][gen]; exists {
+			m.DeleteVersions = append(m.DeleteVersions, versionRef{Filename: filename, Generation: gen})
+			removed = append(removed, v.clone())
 		}
 	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+
+	if err := s.commit(m); err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// ListOldVersions returns every file's old versions, by path and then
+// newest first.
+func (s *InMemoryMetadataStore) ListOldVersions() ([]*FileMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
+	var result []*FileMeta
+	for filename := range s.versions {
+		for _, v := range s.sortedVersions(filename) {
+			result = append(result, v.clone())
+		}
+	}
+	slices.SortStableFunc(result, func(a, b *FileMeta) int {
+		return strings.Compare(a.Filename, b.Filename)
+	})
 	return result, nil
 }
 
+// archive adds old, which a write is replacing, to the file's old versions.
+func archive(m *mutation, old *FileMeta, now time.Time) {
+	v := old.clone()
+	v.Generation = old.generation()
+	v.SupersededAt = now
+	m.PutVersions = append(m.PutVersions, v)
+}
+
+// dropVersions adds the deletion of a file's old versions to m and
+// returns them. Callers must hold the lock.
+func (s *InMemoryMetadataStore) dropVersions(m *mutation, filename string) []*FileMeta {
+	var dropped []*FileMeta
+	for gen, v := range s.versions[filename] {
+		m.DeleteVersions = append(m.DeleteVersions, versionRef{Filename: filename, Generation: gen})
+		dropped = append(dropped, v.clone())
+	}
+	return dropped
+}
+
+// moveVersions adds moving a file's old versions from src to dst to m.
+// Callers must hold the lock.
+func (s *InMemoryMetadataStore) moveVersions(m *mutation, src, dst string) {
+	for gen, v := range s.versions[src] {
+		m.DeleteVersions = append(m.DeleteVersions, versionRef{Filename: src, Generation: gen})
+		moved := v.clone()
+		moved.Filename = dst
+		m.PutVersions = append(m.PutVersions, moved)
+	}
+}
+
+// sortedVersions returns a file's old versions newest first, uncopied.
+// Callers must hold the lock.
+func (s *InMemoryMetadataStore) sortedVersions(filename string) []*FileMeta {
+	result := make([]*FileMeta, 0, len(s.versions[filename]))
+	for _, v := range s.versions[filename] {
+		result = append(result, v)
+	}
+	slices.SortFunc(result, func(a, b *FileMeta) int {
+		return cmp.Compare(b.Generation, a.Generation)
+	})
+	return result
+}
+
+// descendants returns everything below dir, uncopied.
+// Callers must hold the lock.
+func (s *InMemoryMetadataStore) descendants(dir string) []*FileMeta {
+	var result []*FileMeta
+	for child := range s.children[dir] {
+		meta := s.files[child]
+		result = append(result, meta)
+		if meta.IsDir {
+			result = append(result, s.descendants(child)...)
+		}
+	}
+	return result
+}
+
+// checkParent reports whether the parent directory of p exists.
+// Callers must hold the lock.
+func (s *InMemoryMetadataStore) checkParent(p string) error {
+	parent := path.Dir(p)
+	if parent == rootDir {
+		return nil
+	}
+	meta, exists := s.files[parent]
+	if !exists {
+		return fmt.Errorf("%s: %w", parent, ErrParentNotFound)
+	}
+	if !meta.IsDir {
+		return fmt.Errorf("%s: %w", parent, ErrNotADirectory)
+	}
+	return nil
+}
+
+// SetAttributes changes a file's user metadata. The check against the
+// limits and the write happen under one lock, so concurrent edits can't
+// add up to more than is allowed.
+func (s *InMemoryMetadataStore) SetAttributes(filename string, change AttributeChange) (*FileMeta, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	meta, exists := s.files[filename]
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", filename, ErrFileNotFound)
+	}
+
+	updated := meta.clone()
+	change.apply(updated)
+	if err := checkAttributes(updated.Attributes, updated.Tags); err != nil {
+		return nil, err
+	}
+
+	if err := s.commit(&mutation{Put: []*FileMeta{updated}}); err != nil {
+		return nil, err
+	}
+	return updated.clone(), nil
+}
+
 // Exists checks if a file exists in the store.
 func (s *InMemoryMetadataStore) Exists(filename string) bool {
 	s.mu.RLock()
@@ -179,6 +1242,393 @@ func (s *InMemoryMetadataStore) Exists(filename string) bool {
 	return exists
 }
 
+// GetChunk returns a chunk's metadata.
+func (s *InMemoryMetadataStore) GetChunk(chunkID string) (*ChunkMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.getChunk(chunkID)
+}
+
+// getChunk is GetChunk for callers already holding the lock.
+func (s *InMemoryMetadataStore) getChunk(chunkID string) (*ChunkMeta, error) {
+	// Every file referencing the chunk has the same ChunkMeta for it,
+	// so any one of them will do.
+	for ref := range s.chunkFiles[chunkID] {
+		for _, chunk := range s.resolve(ref).Chunks {
+			if chunk.ID == chunkID {
+				chunk.Locations = append([]string(nil), chunk.Locations...)
+				return &chunk, nil
+			}
+		}
+	}
+	return nil, ErrChunkNotFound
+}
+
+// FindChunk returns the metadata of a chunk whose data has the given
+// SHA-256, or ErrChunkNotFound if no file references one.
+func (s *InMemoryMetadataStore) FindChunk(hash string) (*ChunkMeta, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Any copy will do; the lowest ID keeps the choice stable, so new
+	// uploads pile onto one copy rather than spreading over them
+	ids := s.contentChunks[hash]
+	if len(ids) == 0 {
+		return nil, ErrChunkNotFound
+	}
+	return s.getChunk(slices.Min(slices.Collect(maps.Keys(ids))))
+}
+
+// ChunkRefs counts the files and versions referencing a chunk.
+func (s *InMemoryMetadataStore) ChunkRefs(chunkID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.chunkFiles[chunkID])
+}
+
+// SetChunkLocations updates a chunk's locations in every file using it.
+func (s *InMemoryMetadataStore) SetChunkLocations(chunkID string, locations []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refs := s.chunkFiles[chunkID]
+	if len(refs) == 0 {
+		return ErrChunkNotFound
+	}
+
+	// All referencing files change together in one mutation
+	m := &mutation{}
+	trashed := make(map[string]*TrashEntry)
+	for ref := range refs {
+		if ref.trash != "" {
+			// A trash entry is written whole, however many of its
+			// versions use the chunk
+			if _, ok := trashed[ref.trash]; !ok {
+				entry := s.trash[ref.trash].clone()
+				setChunkLocations(entry.File, chunkID, locations)
+				for _, v := range entry.Versions {
+					setChunkLocations(v, chunkID, locations)
+				}
+				trashed[ref.trash] = entry
+				m.PutTrash = append(m.PutTrash, entry)
+			}
+			continue
+		}
+
+		meta := s.resolve(ref).clone()
+		setChunkLocations(meta, chunkID, locations)
+		if ref.generation == 0 {
+			m.Put = append(m.Put, meta)
+		} else {
+			m.PutVersions = append(m.PutVersions, meta)
+		}
+	}
+
+	return s.commit(m)
+}
+
+// ReferencedChunks returns every referenced chunk, once each.
+func (s *InMemoryMetadataStore) ReferencedChunks() ([]ChunkUse, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Walking the files rather than the chunk index finds each chunk's
+	// metadata without searching its file for it; both cover the same
+	// files and versions.
+	uses := make(map[string]int, len(s.chunkFiles))
+	var chunks []ChunkUse
+	s.eachFile(func(meta *FileMeta) {
+		for _, chunk := range meta.Chunks {
+			i, ok := uses[chunk.ID]
+			if !ok {
+				i = len(chunks)
+				uses[chunk.ID] = i
+				chunk.Locations = append([]string(nil), chunk.Locations...)
+				chunks = append(chunks, ChunkUse{Chunk: chunk})
+			}
+			chunks[i].Replication = max(chunks[i].Replication, meta.Replication)
+		}
+	})
+	return chunks, nil
+}
+
+// DataKeys returns every wrapped data key in use, once each.
+func (s *InMemoryMetadataStore) DataKeys() ([]WrappedKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var keys []WrappedKey
+	s.eachFile(func(meta *FileMeta) {
+		if meta.DataKey != nil && !seen[string(meta.DataKey.Key)] {
+			seen[string(meta.DataKey.Key)] = true
+			keys = append(keys, *meta.DataKey)
+		}
+	})
+	return keys, nil
+}
+
+// RewrapKeys swaps wrapped data keys for rewrapped ones, in one mutation.
+func (s *InMemoryMetadataStore) RewrapKeys(rewrap map[string]WrappedKey) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	found := make(map[string]bool)
+	rewrapped := func(meta *FileMeta) bool {
+		if meta.DataKey == nil {
+			return false
+		}
+		key, ok := rewrap[string(meta.DataKey.Key)]
+		if ok {
+			found[string(meta.DataKey.Key)] = true
+			meta.DataKey = &key
+		}
+		return ok
+	}
+
+	m := &mutation{}
+	for _, meta := range s.files {
+		if meta = meta.clone(); rewrapped(meta) {
+			m.Put = append(m.Put, meta)
+		}
+	}
+	for _, versions := range s.versions {
+		for _, v := range versions {
+			if v = v.clone(); rewrapped(v) {
+				m.PutVersions = append(m.PutVersions, v)
+			}
+		}
+	}
+	for _, entry := range s.trash {
+		// A trash entry is written whole
+		entry = entry.clone()
+		changed := rewrapped(entry.File)
+		for _, v := range entry.Versions {
+			changed = rewrapped(v) || changed
+		}
+		if changed {
+			m.PutTrash = append(m.PutTrash, entry)
+		}
+	}
+
+	if len(found) == 0 {
+		return 0, nil
+	}
+	return len(found), s.commit(m)
+}
+
+// eachFile calls fn for every file and old version, in the namespace and
+// in the trash. Callers must hold the lock, and fn must not modify them.
+func (s *InMemoryMetadataStore) eachFile(fn func(*FileMeta)) {
+	for _, meta := range s.files {
+		fn(meta)
+	}
+	for _, versions := range s.versions {
+		for _, v := range versions {
+			fn(v)
+		}
+	}
+	for _, entry := range s.trash {
+		fn(entry.File)
+		for _, v := range entry.Versions {
+			fn(v)
+		}
+	}
+}
+
+// setChunkLocations sets the locations of a chunk within meta.
+func setChunkLocations(meta *FileMeta, chunkID string, locations []string) {
+	for i := range meta.Chunks {
+		if meta.Chunks[i].ID == chunkID {
+			meta.Chunks[i].Locations = append([]string(nil), locations...)
+		}
+	}
+}
+
+// resolve returns the file or old version a chunk index entry refers to.
+// Callers must hold the lock.
+func (s *InMemoryMetadataStore) resolve(ref chunkRef) *FileMeta {
+	if ref.trash != "" {
+		entry := s.trash[ref.trash]
+		if ref.generation == 0 {
+			return entry.File
+		}
+		for _, v := range entry.Versions {
+			if v.Generation == ref.generation {
+				return v
+			}
+		}
+		return nil
+	}
+	if ref.generation == 0 {
+		return s.files[ref.filename]
+	}
+	return s.versions[ref.filename][ref.generation]
+}
+
+// commit records m in the journal (if any) and then applies it.
+// Callers must hold the write lock.
+func (s *InMemoryMetadataStore) commit(m *mutation) error {
+	if s.journal != nil {
+		if err := s.journal.append(m); err != nil {
+			return fmt.Errorf("failed to journal mutation: %w", err)
+		}
+	}
+
+	s.apply(m)
+
+	if s.journal != nil {
+		s.journal.applied()
+	}
+	return nil
+}
+
+// apply makes the changes described by m visible. It never fails, which is
+// what lets a replayed log reproduce exactly the state that was written.
+// Callers must hold the write lock.
+func (s *InMemoryMetadataStore) apply(m *mutation) {
+	for _, filename := range m.Delete {
+		filename = legacyPath(filename)
+		if old, ok := s.files[filename]; ok {
+			s.unindexChunks(chunkRef{filename: filename}, old)
+			s.unindexChild(old.Filename)
+		}
+		delete(s.files, filename)
+		s.index.remove(filename)
+	}
+	for _, v := range m.DeleteVersions {
+		ref := chunkRef{filename: v.Filename, generation: v.Generation}
+		if old, ok := s.versions[v.Filename][v.Generation]; ok {
+			s.unindexChunks(ref, old)
+		}
+		delete(s.versions[v.Filename], v.Generation)
+		if len(s.versions[v.Filename]) == 0 {
+			delete(s.versions, v.Filename)
+		}
+	}
+	for _, meta := range m.Put {
+		meta.Filename = legacyPath(meta.Filename)
+		ref := chunkRef{filename: meta.Filename}
+		if old, ok := s.files[meta.Filename]; ok {
+			s.unindexChunks(ref, old)
+		}
+		s.files[meta.Filename] = meta
+		s.index.insert(meta.Filename)
+		s.indexChunks(ref, meta)
+		s.indexChild(meta.Filename)
+	}
+	for _, meta := range m.PutVersions {
+		ref := chunkRef{filename: meta.Filename, generation: meta.Generation}
+		versions, ok := s.versions[meta.Filename]
+		if !ok {
+			versions = make(map[int64]*FileMeta)
+			s.versions[meta.Filename] = versions
+		}
+		if old, ok := versions[meta.Generation]; ok {
+			s.unindexChunks(ref, old)
+		}
+		versions[meta.Generation] = meta
+		s.indexChunks(ref, meta)
+	}
+	for _, id := range m.DeleteTrash {
+		if old, ok := s.trash[id]; ok {
+			s.indexTrash(old, s.unindexChunks)
+		}
+		delete(s.trash, id)
+	}
+	for _, entry := range m.PutTrash {
+		if old, ok := s.trash[entry.ID]; ok {
+			s.indexTrash(old, s.unindexChunks)
+		}
+		s.trash[entry.ID] = entry
+		s.indexTrash(entry, s.indexChunks)
+	}
+}
+
+// legacyPath maps names from before directories existed, when every file
+// sat at the top level and had no leading slash, to their path: "a.bin"
+// becomes "/a.bin". Paths written since pass through unchanged.
+func legacyPath(name string) string {
+	if strings.HasPrefix(name, "/") {
+		return name
+	}
+	return rootDir + name
+}
+
+// indexChild records p as a child of its parent directory.
+func (s *InMemoryMetadataStore) indexChild(p string) {
+	parent := path.Dir(p)
+	children, ok := s.children[parent]
+	if !ok {
+		children = make(map[string]struct{})
+		s.children[parent] = children
+	}
+	children[p] = struct{}{}
+}
+
+// unindexChild removes p from its parent directory's children.
+func (s *InMemoryMetadataStore) unindexChild(p string) {
+	parent := path.Dir(p)
+	children := s.children[parent]
+	delete(children, p)
+	if len(children) == 0 {
+		delete(s.children, parent)
+	}
+}
+
+// indexChunks records that meta, found at ref, references each of its chunks.
+func (s *InMemoryMetadataStore) indexChunks(ref chunkRef, meta *FileMeta) {
+	for _, chunk := range meta.Chunks {
+		refs, ok := s.chunkFiles[chunk.ID]
+		if !ok {
+			refs = make(map[chunkRef]struct{})
+			s.chunkFiles[chunk.ID] = refs
+		}
+		refs[ref] = struct{}{}
+
+		// Encrypted chunks are only any use with their file's key, so
+		// they aren't shared.
+		if chunk.Hash != "" && meta.DataKey == nil {
+			ids, ok := s.contentChunks[chunk.Hash]
+			if !ok {
+				ids = make(map[string]struct{})
+				s.contentChunks[chunk.Hash] = ids
+			}
+			ids[chunk.ID] = struct{}{}
+		}
+	}
+}
+
+// indexTrash calls index (indexChunks or unindexChunks) for the file and
+// each version in a trash entry.
+func (s *InMemoryMetadataStore) indexTrash(entry *TrashEntry, index func(chunkRef, *FileMeta)) {
+	index(chunkRef{trash: entry.ID}, entry.File)
+	for _, v := range entry.Versions {
+		index(chunkRef{trash: entry.ID, generation: v.Generation}, v)
+	}
+}
+
+// unindexChunks removes meta's chunk references from the index.
+func (s *InMemoryMetadataStore) unindexChunks(ref chunkRef, meta *FileMeta) {
+	for _, chunk := range meta.Chunks {
+		refs := s.chunkFiles[chunk.ID]
+		delete(refs, ref)
+		if len(refs) == 0 {
+			delete(s.chunkFiles, chunk.ID)
+
+			// Only this copy of the content goes: another chunk with
+			// the same hash, stored by a racing upload, takes over
+			ids := s.contentChunks[chunk.Hash]
+			delete(ids, chunk.ID)
+			if len(ids) == 0 {
+				delete(s.contentChunks, chunk.Hash)
+			}
+		}
+	}
+}
+
 // Compile-time check that InMemoryMetadataStore implements MetadataStore.
 // This is a Go idiom - if the implementation is wrong, you get a compile error
 // rather than a runtime error.
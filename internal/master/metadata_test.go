@@ -0,0 +1,179 @@
+package master
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fileWithChunks returns metadata for a file made of the given chunks,
+// each given as an ID and a content hash.
+func fileWithChunks(name string, chunks ...[2]string) *FileMeta {
+	meta := &FileMeta{Filename: name, Replication: 1}
+	for _, c := range chunks {
+		meta.Chunks = append(meta.Chunks, ChunkMeta{ID: c[0], Hash: c[1], Size: 1})
+		meta.Size++
+	}
+	return meta
+}
+
+// wantRefs checks the reference count of each chunk.
+func wantRefs(t *testing.T, store MetadataStore, want map[string]int) {
+	t.Helper()
+	for id, n := range want {
+		if got := store.ChunkRefs(id); got != n {
+			t.Errorf("ChunkRefs(%s) = %d, want %d", id, got, n)
+		}
+	}
+}
+
+// wantFound checks which chunk FindChunk returns for a hash: id, or with
+// id empty, none.
+func wantFound(t *testing.T, store MetadataStore, hash, id string) {
+	t.Helper()
+	chunk, err := store.FindChunk(hash)
+	switch {
+	case id == "" && !errors.Is(err, ErrChunkNotFound):
+		t.Errorf("FindChunk(%s) = %v, %v; want ErrChunkNotFound", hash, chunk, err)
+	case id != "" && err != nil:
+		t.Errorf("FindChunk(%s): %v; want chunk %s", hash, err, id)
+	case id != "" && chunk.ID != id:
+		t.Errorf("FindChunk(%s) = chunk %s, want %s", hash, chunk.ID, id)
+	}
+}
+
+func TestChunkRefsFollowCopiesVersionsAndTrash(t *testing.T) {
+	store := NewInMemoryMetadataStore()
+	if err := store.Create(fileWithChunks("/a", [2]string{"c1", "h1"}, [2]string{"c2", "h2"})); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := store.Copy("/a", "/b", false); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 2, "c2": 2})
+
+	// Overwriting /a keeps what it had as an old version; the chunk it
+	// still shares with the new contents gains a reference
+	if _, err := store.Replace(fileWithChunks("/a", [2]string{"c1", "h1"}, [2]string{"c3", "h3"}), AnyGeneration); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 3, "c2": 2, "c3": 1})
+
+	if _, err := store.Delete("/b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 2, "c2": 1, "c3": 1})
+	wantFound(t, store, "h2", "c2")
+
+	if _, err := store.DeleteVersions("/a", []int64{1}); err != nil {
+		t.Fatalf("DeleteVersions: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 1, "c2": 0, "c3": 1})
+	wantFound(t, store, "h2", "")
+
+	// The trash holds on to a file's chunks until it is purged
+	entry, err := store.Trash("/a")
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 1, "c3": 1})
+	wantFound(t, store, "h3", "c3")
+	if _, err := store.PurgeTrash([]string{entry.ID}); err != nil {
+		t.Fatalf("PurgeTrash: %v", err)
+	}
+	wantRefs(t, store, map[string]int{"c1": 0, "c3": 0})
+	wantFound(t, store, "h1", "")
+	wantFound(t, store, "h3", "")
+}
+
+func TestFindChunkFallsBackToDuplicates(t *testing.T) {
+	store := NewInMemoryMetadataStore()
+
+	create := func(meta *FileMeta) {
+		t.Helper()
+		if err := store.Create(meta); err != nil {
+			t.Fatalf("Create(%s): %v", meta.Filename, err)
+		}
+	}
+
+	// Two uploads racing each other both stored the same new data
+	create(fileWithChunks("/b", [2]string{"c2", "h"}))
+	create(fileWithChunks("/a", [2]string{"c1", "h"}))
+	wantFound(t, store, "h", "c1")
+
+	// Either copy going away leaves the other to share
+	if _, err := store.Delete("/a"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	wantFound(t, store, "h", "c2")
+	create(fileWithChunks("/c", [2]string{"c2", "h"}))
+	if _, err := store.Delete("/b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	wantFound(t, store, "h", "c2")
+	if _, err := store.Delete("/c"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	wantFound(t, store, "h", "")
+
+	// Encrypted chunks are never shared
+	secret := fileWithChunks("/s", [2]string{"c3", "h"})
+	secret.DataKey = &WrappedKey{KeyID: "k", Key: []byte("wrapped")}
+	create(secret)
+	wantFound(t, store, "h", "")
+}
+
+func TestReplaceComparesGenerations(t *testing.T) {
+	store := NewInMemoryMetadataStore()
+	replace := func(size, ifGeneration int64) error {
+		_, err := store.Replace(&FileMeta{Filename: "/f", Size: size, Replication: 1}, ifGeneration)
+		return err
+	}
+
+	// Generation 0 means the file mustn't exist yet
+	if err := replace(1, 0); err != nil {
+		t.Fatalf("Replace of a new file at generation 0: %v", err)
+	}
+	if err := replace(2, 0); !errors.Is(err, ErrGenerationMismatch) {
+		t.Errorf("Replace of an existing file at generation 0: err = %v, want ErrGenerationMismatch", err)
+	}
+	if err := store.Create(&FileMeta{Filename: "/f", Replication: 1}); !errors.Is(err, ErrFileAlreadyExists) {
+		t.Errorf("Create of an existing file: err = %v, want ErrFileAlreadyExists", err)
+	}
+
+	// Each write moves the generation on, so a stale one fails
+	if err := replace(2, 1); err != nil {
+		t.Fatalf("Replace at the current generation: %v", err)
+	}
+	if err := replace(3, 1); !errors.Is(err, ErrGenerationMismatch) {
+		t.Errorf("Replace at a stale generation: err = %v, want ErrGenerationMismatch", err)
+	}
+	if err := replace(3, AnyGeneration); err != nil {
+		t.Fatalf("unconditional Replace: %v", err)
+	}
+	if meta, err := store.Get("/f"); err != nil || meta.Generation != 3 || meta.Size != 3 {
+		t.Errorf("after three writes: %+v, %v; want generation 3, size 3", meta, err)
+	}
+
+	// Of writers racing from the same generation, exactly one wins
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	won := 0
+	for i := range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := replace(int64(10+i), 3); err == nil {
+				mu.Lock()
+				won++
+				mu.Unlock()
+			} else if !errors.Is(err, ErrGenerationMismatch) {
+				t.Errorf("racing Replace: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if won != 1 {
+		t.Errorf("%d of 10 racing writes won, want 1", won)
+	}
+}
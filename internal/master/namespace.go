@@ -0,0 +1,264 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// rootDir is the top of the namespace. It always exists and is never
+// stored: every other directory is an entry in the metadata store.
+const rootDir = "/"
+
+// Limits on path length, the same as most Unix filesystems'.
+const (
+	maxPathLength = 4096
+	maxNameLength = 255
+)
+
+// ErrInvalidPath is returned for paths that can't name a file.
+var ErrInvalidPath = errors.New("invalid path")
+
+// cleanPath checks a client-supplied path and returns it in the canonical
+// form the metadata store uses: absolute, with single slashes and no
+// trailing slash ("/team/ml/v3.bin"). A path without a leading slash is
+// taken relative to the root, so plain "v3.bin" still means "/v3.bin".
+//
+// "." and ".." are rejected rather than resolved. The namespace has no
+// current directory for them to be relative to, and refusing them keeps
+// any path from climbing out of the root - which matters to clients that
+// map remote paths onto their local disk.
+func cleanPath(p string) (string, error) {
+	if p == "" {
+		return "", fmt.Errorf("%w: empty path", ErrInvalidPath)
+	}
+	if len(p) > maxPathLength {
+		return "", fmt.Errorf("%w: longer than %d bytes", ErrInvalidPath, maxPathLength)
+	}
+	if strings.ContainsRune(p, 0) {
+		return "", fmt.Errorf("%w: contains a NUL byte", ErrInvalidPath)
+	}
+
+	for _, name := range strings.Split(p, "/") {
+		switch {
+		case name == "." || name == "..":
+			return "", fmt.Errorf("%w: %q must not contain %q", ErrInvalidPath, p, name)
+		case len(name) > maxNameLength:
+			return "", fmt.Errorf("%w: name longer than %d bytes", ErrInvalidPath, maxNameLength)
+		}
+	}
+
+	// With no dot elements left, Clean just squeezes out repeated and
+	// trailing slashes.
+	return path.Clean(rootDir + p), nil
+}
+
+// cleanFilePath is cleanPath for paths that must name a file, not the root.
+func cleanFilePath(p string) (string, error) {
+	clean, err := cleanPath(p)
+	if err == nil && clean == rootDir {
+		return "", fmt.Errorf("%w: %s", ErrIsADirectory, rootDir)
+	}
+	return clean, err
+}
+
+// cleanPrefix is cleanPath for path prefixes, as Watch and ListTrash take.
+// A trailing slash is kept, so "/logs/" still only matches what is inside
+// /logs, not /logs2 as well.
+func cleanPrefix(p string) (string, error) {
+	clean, err := cleanPath(p)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasSuffix(p, "/") && clean != rootDir {
+		clean += "/"
+	}
+	return clean, nil
+}
+
+// getFile looks up a file for reading. Paths that are invalid, missing or
+// name a directory are reported with the matching gRPC status code.
+func (s *Server) getFile(filename string) (*FileMeta, error) {
+	p, err := cleanFilePath(filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	meta, err := s.metadata.Get(p)
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			return nil, status.Errorf(codes.NotFound, "file not found: %s", filename)
+		}
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if meta.IsDir {
+		return nil, metadataStatus(fmt.Errorf("%s: %w", p, ErrIsADirectory))
+	}
+	return meta, nil
+}
+
+// Mkdir creates a directory.
+func (s *Server) Mkdir(ctx context.Context, req *api.MkdirRequest) (*api.MkdirResponse, error) {
+	dir, err := cleanPath(req.Path)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	s.changes.Lock()
+	defer s.changes.Unlock()
+	created, err := s.metadata.Mkdir(dir, req.Parents)
+	if err != nil {
+		return nil, metadataStatus(fmt.Errorf("failed to create directory: %w", err))
+	}
+	s.publishCreated(created)
+
+	return &api.MkdirResponse{
+		Success: true,
+		Message: fmt.Sprintf("Directory '%s' created", dir),
+	}, nil
+}
+
+// Rmdir removes a directory. Removing a non-empty directory takes
+// recursive, which deletes every file below it as well: into the trash,
+// unless permanent is set.
+//
+// As with Delete, the metadata goes first - the whole subtree disappears
+// in one step - and the chunks are deleted afterwards.
+func (s *Server) Rmdir(ctx context.Context, req *api.RmdirRequest) (*api.RmdirResponse, error) {
+	dir, err := cleanPath(req.Path)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	s.changes.Lock()
+	removed, trashed, err := s.metadata.Rmdir(dir, req.Recursive, !req.Permanent)
+	if err != nil {
+		s.changes.Unlock()
+		return nil, metadataStatus(fmt.Errorf("failed to remove directory: %w", err))
+	}
+
+	// removed includes old versions; only count the files
+	files := int32(len(trashed))
+	for _, entry := range trashed {
+		s.publish(api.WatchEventType_WATCH_EVENT_DELETE, entry.File, "")
+	}
+	for _, meta := range removed {
+		if meta.SupersededAt.IsZero() {
+			s.publish(api.WatchEventType_WATCH_EVENT_DELETE, meta, "")
+			files++
+		}
+	}
+	s.publish(api.WatchEventType_WATCH_EVENT_DELETE, &FileMeta{Filename: dir, IsDir: true}, "")
+	s.changes.Unlock()
+	s.releaseFiles(removed)
+	if files > 0 {
+		log.Printf("master: removed directory %s and %d file(s) below it", dir, files)
+	}
+
+	return &api.RmdirResponse{
+		Success:      true,
+		Message:      fmt.Sprintf("Directory '%s' removed", dir),
+		FilesDeleted: files,
+	}, nil
+}
+
+// Rename moves a file or directory to a new path, atomically: readers see
+// it at one path or the other, never both or neither. That makes it the
+// publish step of a "write to a temporary name, then rename" workflow.
+//
+// If the destination is an existing file, the rename fails unless
+// overwrite is set, in which case the old file is kept as an old version
+// of the new one.
+func (s *Server) Rename(ctx context.Context, req *api.RenameRequest) (*api.RenameResponse, error) {
+	src, err := cleanPath(req.Source)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+	dst, err := cleanPath(req.Destination)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	s.changes.Lock()
+	moved, removed, err := s.metadata.Rename(src, dst, req.Overwrite)
+	if err != nil {
+		s.changes.Unlock()
+		return nil, metadataStatus(fmt.Errorf("failed to rename %s: %w", src, err))
+	}
+	s.publish(api.WatchEventType_WATCH_EVENT_RENAME, moved, src)
+	s.changes.Unlock()
+
+	s.releaseFiles(removed)
+	if !moved.IsDir {
+		s.applyRetention(dst)
+	}
+
+	return &api.RenameResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Renamed '%s' to '%s'", src, dst),
+		Generation: moved.generation(),
+	}, nil
+}
+
+// Copy creates a new file with the contents of an existing one, without
+// moving any data: the copy shares the source's chunks, so it is as quick
+// for a terabyte as for a byte. The two files are independent from then on
+// - replacing or deleting either leaves the other as it was - because
+// chunks are never modified in place, and a chunk is only deleted once no
+// file references it.
+//
+// The destination is handled as in Rename.
+func (s *Server) Copy(ctx context.Context, req *api.CopyRequest) (*api.CopyResponse, error) {
+	src, err := cleanPath(req.Source)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+	dst, err := cleanPath(req.Destination)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	s.changes.Lock()
+	copied, err := s.metadata.Copy(src, dst, req.Overwrite)
+	if err != nil {
+		s.changes.Unlock()
+		return nil, metadataStatus(fmt.Errorf("failed to copy %s: %w", src, err))
+	}
+	s.publish(writeEvent(copied), copied, "")
+	s.changes.Unlock()
+	s.applyRetention(dst)
+
+	return &api.CopyResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Copied '%s' to '%s'", src, dst),
+		Generation: copied.generation(),
+	}, nil
+}
+
+// metadataStatus gives a failed metadata operation the gRPC status code
+// clients can act on: AlreadyExists and FailedPrecondition tell them to
+// re-read and retry; NotFound and InvalidArgument, to fix the request.
+func metadataStatus(err error) error {
+	switch {
+	case errors.Is(err, ErrInvalidPath), errors.Is(err, ErrInvalidAttributes):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, ErrFileNotFound), errors.Is(err, ErrParentNotFound),
+		errors.Is(err, ErrVersionNotFound), errors.Is(err, ErrNotInTrash):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrFileAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, ErrGenerationMismatch), errors.Is(err, ErrNotADirectory),
+		errors.Is(err, ErrIsADirectory), errors.Is(err, ErrDirectoryNotEmpty):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,188 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+func TestCleanPath(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"/team/ml/v3.bin", "/team/ml/v3.bin"},
+		{"v3.bin", "/v3.bin"},
+		{"//team///ml/", "/team/ml"},
+		{"/", "/"},
+		{"", ""},
+		{"/team/../etc", ""},
+		{"./v3.bin", ""},
+		{"/team/.", ""},
+		{"/a\x00b", ""},
+		{"/" + strings.Repeat("x", maxNameLength+1), ""},
+	} {
+		got, err := cleanPath(tc.in)
+		if tc.want == "" {
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("cleanPath(%q) = %q, %v; want ErrInvalidPath", tc.in, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("cleanPath(%q) = %q, %v; want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func TestMkdirAndRmdir(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	mkdir := func(p string, parents bool) error {
+		_, err := s.Mkdir(ctx, &api.MkdirRequest{Path: p, Parents: parents})
+		return err
+	}
+	rmdir := func(p string, recursive bool) (*api.RmdirResponse, error) {
+		return s.Rmdir(ctx, &api.RmdirRequest{Path: p, Recursive: recursive})
+	}
+
+	// A directory needs its parent, unless parents are made too
+	if err := mkdir("/a/b", false); status.Code(err) != codes.NotFound {
+		t.Errorf("Mkdir without its parent: err = %v, want NotFound", err)
+	}
+	if err := mkdir("/a/b/c", true); err != nil {
+		t.Fatalf("Mkdir with parents: %v", err)
+	}
+	if err := mkdir("/a/b", true); err != nil {
+		t.Errorf("Mkdir with parents of an existing directory: %v", err)
+	}
+	if err := mkdir("/a/b", false); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Mkdir of an existing directory: err = %v, want AlreadyExists", err)
+	}
+
+	// Files and directories don't stand in for each other
+	createFile(t, s.metadata, "/a/b/f", 1)
+	if err := mkdir("/a/b/f/g", true); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Mkdir below a file: err = %v, want FailedPrecondition", err)
+	}
+	if err := s.metadata.Create(&FileMeta{Filename: "/x/f", Replication: 1}); !errors.Is(err, ErrParentNotFound) {
+		t.Errorf("file in a missing directory: err = %v, want ErrParentNotFound", err)
+	}
+	if _, err := rmdir("/a/b/f", false); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Rmdir of a file: err = %v, want FailedPrecondition", err)
+	}
+
+	// Only an empty directory goes without recursive
+	if _, err := rmdir("/a", false); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Rmdir of a non-empty directory: err = %v, want FailedPrecondition", err)
+	}
+	if _, err := rmdir("/a/b/c", false); err != nil {
+		t.Errorf("Rmdir of an empty directory: %v", err)
+	}
+	if _, err := rmdir("/", true); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Rmdir of the root: err = %v, want InvalidArgument", err)
+	}
+
+	// With it the whole subtree goes, its files into the trash
+	createFile(t, s.metadata, "/a/g", 1)
+	resp, err := rmdir("/a", true)
+	if err != nil {
+		t.Fatalf("recursive Rmdir: %v", err)
+	}
+	if resp.FilesDeleted != 2 {
+		t.Errorf("recursive Rmdir deleted %d files, want 2", resp.FilesDeleted)
+	}
+	wantFiles(t, s.metadata)
+	trash, err := s.metadata.ListTrash()
+	if err != nil {
+		t.Fatalf("ListTrash: %v", err)
+	}
+	var trashed []string
+	for _, entry := range trash {
+		trashed = append(trashed, entry.File.Filename)
+	}
+	slices.Sort(trashed)
+	if !slices.Equal(trashed, []string{"/a/b/f", "/a/g"}) {
+		t.Errorf("trash holds %v, want /a/b/f and /a/g", trashed)
+	}
+}
+
+func TestListShowsDirectories(t *testing.T) {
+	s := newTestServer(t, Config{})
+	mkdirs(t, s.metadata, "/d/sub/deeper")
+	createFile(t, s.metadata, "/d/f", 1)
+	createFile(t, s.metadata, "/d/sub/g", 1)
+
+	if got := listAll(t, s, &api.ListRequest{Directory: "/d"}); !slices.Equal(got, []string{"/d/f", "/d/sub"}) {
+		t.Errorf("listing of /d = %v, want its two entries", got)
+	}
+	want := []string{"/d/f", "/d/sub", "/d/sub/deeper", "/d/sub/g"}
+	if got := listAll(t, s, &api.ListRequest{Directory: "/d", Recursive: true}); !slices.Equal(got, want) {
+		t.Errorf("recursive listing of /d = %v, want %v", got, want)
+	}
+	if _, err := s.List(context.Background(), &api.ListRequest{Directory: "/d/f"}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("listing of a file: err = %v, want FailedPrecondition", err)
+	}
+}
+
+func TestCopySharesChunks(t *testing.T) {
+	s := newTestServer(t, Config{})
+	ctx := context.Background()
+	copyFile := func(src, dst string, overwrite bool) (*api.CopyResponse, error) {
+		return s.Copy(ctx, &api.CopyRequest{Source: src, Destination: dst, Overwrite: overwrite})
+	}
+	if err := s.metadata.Create(fileWithChunks("/src", [2]string{"c1", "h1"}, [2]string{"c2", "h2"})); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.metadata.Create(fileWithChunks("/other", [2]string{"c3", "h3"})); err != nil {
+		t.Fatal(err)
+	}
+
+	// The copy is a new file made of the same chunks
+	if _, err := copyFile("/src", "/dst", false); err != nil {
+		t.Fatalf("Copy: %v", err)
+	}
+	dst, err := s.metadata.Get("/dst")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if len(dst.Chunks) != 2 || dst.Chunks[0].ID != "c1" || dst.Chunks[1].ID != "c2" || dst.Generation != 1 {
+		t.Errorf("copy has chunks %v at generation %d, want c1 and c2 at 1", dst.Chunks, dst.Generation)
+	}
+	wantRefs(t, s.metadata, map[string]int{"c1": 2, "c2": 2})
+
+	// Deleting the source leaves the copy's chunks in place
+	if _, err := s.Delete(ctx, &api.DeleteRequest{Filename: "/src", Permanent: true}); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	wantRefs(t, s.metadata, map[string]int{"c1": 1, "c2": 1})
+
+	// An existing destination is only replaced when asked, and kept as
+	// an old version
+	if _, err := copyFile("/other", "/dst", false); status.Code(err) != codes.AlreadyExists {
+		t.Errorf("Copy onto a file: err = %v, want AlreadyExists", err)
+	}
+	resp, err := copyFile("/other", "/dst", true)
+	if err != nil {
+		t.Fatalf("Copy with overwrite: %v", err)
+	}
+	if resp.Generation != 2 {
+		t.Errorf("overwriting copy at generation %d, want 2", resp.Generation)
+	}
+	if old, err := s.metadata.GetVersion("/dst", 1); err != nil || old.Chunks[0].ID != "c1" {
+		t.Errorf("replaced copy not kept as version 1: %v", err)
+	}
+	wantRefs(t, s.metadata, map[string]int{"c1": 1, "c3": 2})
+
+	// Directories aren't copied
+	mkdirs(t, s.metadata, "/d")
+	if _, err := copyFile("/d", "/e", false); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("Copy of a directory: err = %v, want FailedPrecondition", err)
+	}
+}
@@ -0,0 +1,334 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// DefaultHeartbeatTimeout is how long a chunkserver may go without a
+// heartbeat before the master considers it dead. It should be several
+// heartbeat intervals, so one dropped heartbeat doesn't cause a repair storm.
+const DefaultHeartbeatTimeout = 30 * time.Second
+
+// DefaultGCGrace is how long a chunkserver must keep reporting a chunk the
+// master doesn't reference before the master tells it to delete the chunk.
+// Deleting lazily gives an operator time to notice a misconfigured master
+// (say, one started with an empty metadata directory) before it wipes data.
+const DefaultGCGrace = 10 * time.Minute
+
+// nodeInfo is what the master knows about one chunkserver.
+type nodeInfo struct {
+	addr          string
+	capacity      int64
+	used          int64
+	lastHeartbeat time.Time
+
+	// dead is set by sweep once the node misses heartbeats for too long,
+	// so we log each death only once.
+	dead bool
+
+	// chunks is the set of chunk IDs in the node's latest report.
+	chunks map[string]struct{}
+
+	// recent holds chunks the master placed on the node that haven't shown
+	// up in a report yet (value: when they were placed). Without this, a
+	// chunk written just after a report would look missing until the next.
+	recent map[string]time.Time
+
+	// orphans holds reported chunks the master doesn't reference, with
+	// when we first noticed. They are deleted after the GC grace period.
+	orphans map[string]time.Time
+}
+
+// registry tracks chunkservers from their heartbeats.
+// It is soft state: nothing here is persisted, because after a master
+// restart every live chunkserver re-reports within one heartbeat interval.
+type registry struct {
+	mu    sync.Mutex
+	nodes map[string]*nodeInfo
+
+	// deadAfter is the heartbeat timeout.
+	deadAfter time.Duration
+
+	// startedAt lets us hold off repairs until chunkservers have had a
+	// chance to report after a master restart.
+	startedAt time.Time
+
+	// next rotates the starting point of placement.
+	next int
+}
+
+func newRegistry(deadAfter time.Duration) *registry {
+	return &registry{
+		nodes:     make(map[string]*nodeInfo),
+		deadAfter: deadAfter,
+		startedAt: time.Now(),
+	}
+}
+
+// heartbeat records a report from a chunkserver.
+// Returns true if the node is new or was previously dead.
+func (r *registry) heartbeat(req *api.HeartbeatRequest) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	n, ok := r.nodes[req.Address]
+	if !ok {
+		n = &nodeInfo{
+			addr:    req.Address,
+			recent:  make(map[string]time.Time),
+			orphans: make(map[string]time.Time),
+		}
+		r.nodes[req.Address] = n
+	}
+	revived := !ok || n.dead
+
+	n.capacity = req.CapacityBytes
+	n.used = req.UsedBytes
+	n.lastHeartbeat = now
+	n.dead = false
+
+	n.chunks = make(map[string]struct{}, len(req.ChunkIds))
+	for _, id := range req.ChunkIds {
+		n.chunks[id] = struct{}{}
+	}
+
+	// Once a placed chunk shows up in a report we no longer need to
+	// remember it separately. If it still hasn't shown up after a full
+	// timeout, the write must have been lost: forget it.
+	for id, placed := range n.recent {
+		if _, reported := n.chunks[id]; reported || now.Sub(placed) > r.deadAfter {
+			delete(n.recent, id)
+		}
+	}
+
+	return revived
+}
+
+// aliveLocked reports whether n has heartbeated recently.
+// Callers must hold r.mu.
+func (r *registry) aliveLocked(n *nodeInfo, now time.Time) bool {
+	return now.Sub(n.lastHeartbeat) <= r.deadAfter
+}
+
+// hasReplica reports whether addr is alive and holds chunkID.
+func (r *registry) hasReplica(addr, chunkID string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n, ok := r.nodes[addr]
+	if !ok || !r.aliveLocked(n, time.Now()) {
+		return false
+	}
+	if _, ok := n.chunks[chunkID]; ok {
+		return true
+	}
+	_, ok = n.recent[chunkID]
+	return ok
+}
+
+// addReplica notes that the master just stored chunkID on addr.
+func (r *registry) addReplica(addr, chunkID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n, ok := r.nodes[addr]; ok {
+		n.recent[chunkID] = time.Now()
+	}
+}
+
+// removeReplica notes that chunkID was deleted from addr.
+func (r *registry) removeReplica(addr, chunkID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if n, ok := r.nodes[addr]; ok {
+		delete(n.chunks, chunkID)
+		delete(n.recent, chunkID)
+		delete(n.orphans, chunkID)
+	}
+}
+
+// place picks n distinct live chunkservers with at least size bytes free,
+// skipping any in exclude. The first one heads the replication pipeline.
+func (r *registry) place(n int, exclude []string, size int64) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	skip := make(map[string]bool, len(exclude))
+	for _, addr := range exclude {
+		skip[addr] = true
+	}
+
+	now := time.Now()
+	var candidates []*nodeInfo
+	for _, node := range r.nodes {
+		if skip[node.addr] || !r.aliveLocked(node, now) || node.capacity-node.used < size {
+			continue
+		}
+		candidates = append(candidates, node)
+	}
+
+	if n > len(candidates) {
+		return nil, fmt.Errorf("%w: want %d, have %d live chunkservers with space",
+			ErrNotEnoughChunkservers, n, len(candidates))
+	}
+
+	// Sort for a stable order, then take n consecutive nodes from a
+	// rotating start. This spreads chunks (and pipeline heads) evenly.
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].addr < candidates[j].addr
+	})
+
+	addrs := make([]string, n)
+	for i := range addrs {
+		node := candidates[(r.next+i)%len(candidates)]
+		addrs[i] = node.addr
+		// Count the space now, so a burst of placements between two
+		// heartbeats doesn't overfill a node.
+		node.used += size
+	}
+	r.next++
+	return addrs, nil
+}
+
+// adjust changes the space counted as used on each of addrs by delta:
+// place reserves what a chunk is expected to take up, and once the chunk
+// is written (or abandoned) the difference is settled here. Like place's
+// reservation, it only lasts until the node's next heartbeat reports
+// what it really uses.
+func (r *registry) adjust(addrs []string, delta int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, addr := range addrs {
+		if n, ok := r.nodes[addr]; ok {
+			n.used = max(n.used+delta, 0)
+		}
+	}
+}
+
+// settled reports whether chunkservers have had time to report since the
+// master started. Before that, every chunk looks under-replicated simply
+// because we haven't heard from anyone yet.
+func (r *registry) settled() bool {
+	return time.Since(r.startedAt) > r.deadAfter
+}
+
+// sweep marks nodes that have stopped heartbeating as dead and returns
+// the ones that died since the last sweep.
+func (r *registry) sweep() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var died []string
+	for _, n := range r.nodes {
+		if !n.dead && !r.aliveLocked(n, now) {
+			n.dead = true
+			died = append(died, n.addr)
+		}
+	}
+	return died
+}
+
+// collectOrphans takes chunks reported by addr that aren't referenced
+// (isOrphan returns true) and returns those that have stayed orphaned for
+// longer than grace.
+func (r *registry) collectOrphans(addr string, grace time.Duration, isOrphan func(chunkID string) bool) []string {
+	r.mu.Lock()
+	n, ok := r.nodes[addr]
+	if !ok {
+		r.mu.Unlock()
+		return nil
+	}
+	reported := make([]string, 0, len(n.chunks))
+	for id := range n.chunks {
+		reported = append(reported, id)
+	}
+	r.mu.Unlock()
+
+	// isOrphan consults the metadata store, so call it without our lock.
+	orphaned := make(map[string]bool)
+	for _, id := range reported {
+		if isOrphan(id) {
+			orphaned[id] = true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var expired []string
+	for id := range orphaned {
+		since, seen := n.orphans[id]
+		if !seen {
+			n.orphans[id] = now
+			continue
+		}
+		if now.Sub(since) > grace {
+			expired = append(expired, id)
+		}
+	}
+
+	// Chunks that are referenced again (or gone) are no longer orphans
+	for id := range n.orphans {
+		if !orphaned[id] {
+			delete(n.orphans, id)
+		}
+	}
+	return expired
+}
+
+// Heartbeat implements MasterService. Chunkservers call it periodically to
+// report that they are alive, how full they are, and which chunks they hold.
+// The reply lists chunks the chunkserver should delete.
+func (s *Server) Heartbeat(ctx context.Context, req *api.HeartbeatRequest) (*api.HeartbeatResponse, error) {
+	if req.Address == "" {
+		return nil, fmt.Errorf("heartbeat is missing the chunkserver address")
+	}
+
+	if s.registry.heartbeat(req) {
+		log.Printf("master: chunkserver %s is up (%d chunks, %d/%d bytes used)",
+			req.Address, len(req.ChunkIds), req.UsedBytes, req.CapacityBytes)
+	}
+
+	// A reported chunk is garbage if no file references it at this
+	// location. Check pending first: an upload commits its metadata
+	// before clearing pending, so in this order we can never see a
+	// committed chunk as unreferenced.
+	orphans := s.registry.collectOrphans(req.Address, s.gcGrace, func(chunkID string) bool {
+		if s.pending.has(chunkID) {
+			return false
+		}
+		chunk, err := s.metadata.GetChunk(chunkID)
+		if err != nil {
+			return true
+		}
+		for _, addr := range chunk.Locations {
+			if addr == req.Address {
+				return false
+			}
+		}
+		return true
+	})
+
+	for _, id := range orphans {
+		s.registry.removeReplica(req.Address, id)
+	}
+	if len(orphans) > 0 {
+		log.Printf("master: asking %s to delete %d unreferenced chunk(s)", req.Address, len(orphans))
+	}
+
+	return &api.HeartbeatResponse{
+		DeleteChunkIds: orphans,
+	}, nil
+}
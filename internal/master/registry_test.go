@@ -0,0 +1,96 @@
+package master
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// report sends the registry a heartbeat from addr.
+func report(r *registry, addr string, capacity, used int64, chunks ...string) {
+	r.heartbeat(&api.HeartbeatRequest{
+		Address:       addr,
+		CapacityBytes: capacity,
+		UsedBytes:     used,
+		ChunkIds:      chunks,
+	})
+}
+
+func TestPlacePicksLiveNodesWithSpace(t *testing.T) {
+	r := newRegistry(time.Minute)
+	report(r, "a", 1000, 0)
+	report(r, "b", 1000, 0)
+	report(r, "c", 1000, 950) // Nearly full
+	report(r, "d", 1000, 0)
+	r.nodes["d"].lastHeartbeat = time.Now().Add(-2 * time.Minute) // Dead
+
+	addrs, err := r.place(2, nil, 100)
+	if err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	slices.Sort(addrs)
+	if !slices.Equal(addrs, []string{"a", "b"}) {
+		t.Errorf("placed on %v, want a and b", addrs)
+	}
+
+	// Excluded nodes aren't used, even when nothing else has room
+	if _, err := r.place(1, []string{"a", "b"}, 100); !errors.Is(err, ErrNotEnoughChunkservers) {
+		t.Errorf("place excluding a and b: err = %v, want ErrNotEnoughChunkservers", err)
+	}
+	if addrs, err := r.place(1, []string{"a", "b"}, 50); err != nil || !slices.Equal(addrs, []string{"c"}) {
+		t.Errorf("place excluding a and b: %v, %v; want c", addrs, err)
+	}
+
+	// More replicas than nodes with room is an error, not fewer replicas
+	if _, err := r.place(3, nil, 100); !errors.Is(err, ErrNotEnoughChunkservers) {
+		t.Errorf("place 3 on 2 nodes with room: err = %v, want ErrNotEnoughChunkservers", err)
+	}
+}
+
+func TestPlaceReservesSpaceUntilTheNextHeartbeat(t *testing.T) {
+	r := newRegistry(time.Minute)
+	report(r, "a", 1000, 0)
+
+	// Placements between heartbeats count against the node's space
+	for i := range 3 {
+		if _, err := r.place(1, nil, 300); err != nil {
+			t.Fatalf("placement %d: %v", i, err)
+		}
+	}
+	if _, err := r.place(1, nil, 300); !errors.Is(err, ErrNotEnoughChunkservers) {
+		t.Fatalf("fourth placement on a full node: err = %v, want ErrNotEnoughChunkservers", err)
+	}
+
+	// A heartbeat reports what is really used
+	report(r, "a", 1000, 200)
+	if _, err := r.place(1, nil, 800); err != nil {
+		t.Errorf("placement after a heartbeat: %v", err)
+	}
+}
+
+func TestPlaceRotatesThePipelineHead(t *testing.T) {
+	r := newRegistry(time.Minute)
+	for _, addr := range []string{"a", "b", "c"} {
+		report(r, addr, 1<<30, 0)
+	}
+
+	heads := make(map[string]int)
+	for range 6 {
+		addrs, err := r.place(2, nil, 1)
+		if err != nil {
+			t.Fatalf("place: %v", err)
+		}
+		if addrs[0] == addrs[1] {
+			t.Fatalf("placed twice on %s", addrs[0])
+		}
+		heads[addrs[0]]++
+	}
+	for _, addr := range []string{"a", "b", "c"} {
+		if heads[addr] != 2 {
+			t.Errorf("%s headed %d of 6 pipelines, want 2", addr, heads[addr])
+		}
+	}
+}
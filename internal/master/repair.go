@@ -0,0 +1,189 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// DefaultRepairInterval is how often the repairer scans for
+// under-replicated chunks.
+const DefaultRepairInterval = 30 * time.Second
+
+// maxRepairsPerPass limits how many chunks one pass re-replicates, so
+// losing a big chunkserver doesn't saturate the network all at once.
+// Whatever is left over is picked up on the next pass.
+const maxRepairsPerPass = 100
+
+// repairTimeout bounds a single chunk copy.
+const repairTimeout = 5 * time.Minute
+
+// chunkHealth is the repairer's view of one chunk.
+type chunkHealth struct {
+	chunk       ChunkMeta
+	replication int      // Highest replication wanted by any file or version using it
+	healthy     []string // Locations that are alive and hold the chunk
+}
+
+// repairLoop runs repair passes (and other housekeeping) until Close is
+// called.
+func (s *Server) repairLoop(interval time.Duration) {
+	defer close(s.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.repairOnce()
+			s.expireUploadSessions()
+			s.expireVersions()
+			s.sweepTrash()
+		}
+	}
+}
+
+// repairOnce finds chunks with fewer healthy replicas than their file's
+// replication factor and copies them to other live chunkservers.
+func (s *Server) repairOnce() {
+	for _, addr := range s.registry.sweep() {
+		log.Printf("master: chunkserver %s missed heartbeats, marking dead", addr)
+	}
+
+	// Right after a restart nobody has reported yet; repairing now
+	// would re-replicate every chunk in the cluster.
+	if !s.registry.settled() {
+		return
+	}
+
+	work := s.underReplicated()
+	if len(work) == 0 {
+		return
+	}
+
+	// Most endangered chunks first: one surviving replica is more urgent
+	// than two.
+	sort.Slice(work, func(i, j int) bool {
+		return len(work[i].healthy) < len(work[j].healthy)
+	})
+	if len(work) > maxRepairsPerPass {
+		work = work[:maxRepairsPerPass]
+	}
+
+	for _, h := range work {
+		if len(h.healthy) == 0 {
+			log.Printf("master: chunk %s has no live replicas, cannot repair", h.chunk.ID)
+			continue
+		}
+		if err := s.repairChunk(h); err != nil {
+			log.Printf("master: failed to repair chunk %s: %v", h.chunk.ID, err)
+		}
+	}
+}
+
+// underReplicated lists chunks that need more replicas.
+func (s *Server) underReplicated() []*chunkHealth {
+	// Every chunk still referenced, not just those of current files: an
+	// old version or a file in the trash can be restored, and then its
+	// chunks had better still be there.
+	chunks, err := s.metadata.ReferencedChunks()
+	if err != nil {
+		log.Printf("master: repair failed to list chunks: %v", err)
+		return nil
+	}
+
+	var work []*chunkHealth
+	for _, use := range chunks {
+		h := &chunkHealth{chunk: use.Chunk, replication: use.Replication}
+		for _, addr := range use.Chunk.Locations {
+			if s.registry.hasReplica(addr, use.Chunk.ID) {
+				h.healthy = append(h.healthy, addr)
+			}
+		}
+		if len(h.healthy) < h.replication {
+			work = append(work, h)
+		}
+	}
+	return work
+}
+
+// repairChunk copies one chunk from a healthy replica to new chunkservers
+// and points the metadata at the new set of replicas.
+func (s *Server) repairChunk(h *chunkHealth) error {
+	// Never place on a node already listed, even a dead one: if it comes
+	// back we don't want its stale copy counted twice. The copies take up
+	// what the chunk is stored in, compressed or sealed as it is.
+	size := h.chunk.storedSize()
+	targets, err := s.registry.place(h.replication-len(h.healthy), h.chunk.Locations, size)
+	if err != nil {
+		return err
+	}
+
+	// The new replicas aren't in the metadata until we update it below;
+	// mark them pending so heartbeat GC doesn't delete them meanwhile.
+	s.pending.add(h.chunk.ID)
+	defer s.pending.remove(h.chunk.ID)
+
+	ctx, cancel := context.WithTimeout(context.Background(), repairTimeout)
+	defer cancel()
+
+	// Ask a healthy replica to push the chunk to the targets; the data
+	// flows between chunkservers, not through the master.
+	var copyErr error
+	for _, source := range h.healthy {
+		if copyErr = s.replicateFrom(ctx, source, h.chunk.ID, targets); copyErr == nil {
+			break
+		}
+	}
+	if copyErr != nil {
+		// Nothing was stored on the targets after all
+		s.registry.adjust(targets, -size)
+		return copyErr
+	}
+
+	for _, addr := range targets {
+		s.registry.addReplica(addr, h.chunk.ID)
+	}
+
+	// Dead locations are dropped here. If such a node comes back, its
+	// copy is unreferenced and heartbeat GC removes it.
+	locations := append(append([]string(nil), h.healthy...), targets...)
+	if err := s.metadata.SetChunkLocations(h.chunk.ID, locations); err != nil {
+		if errors.Is(err, ErrChunkNotFound) {
+			// The file was deleted while we copied. The new replicas
+			// are garbage now; heartbeat GC will remove them.
+			return nil
+		}
+		return err
+	}
+
+	log.Printf("master: re-replicated chunk %s to %v", h.chunk.ID, targets)
+	return nil
+}
+
+// replicateFrom asks source to copy chunkID to targets.
+func (s *Server) replicateFrom(ctx context.Context, source, chunkID string, targets []string) error {
+	client, err := s.chunks.Client(source)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.ReplicateChunk(ctx, &api.ReplicateChunkRequest{
+		ChunkId: chunkID,
+		Targets: targets,
+	})
+	if err != nil {
+		return err
+	}
+	if !resp.Success {
+		return errors.New(resp.Message)
+	}
+	return nil
+}
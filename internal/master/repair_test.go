@@ -0,0 +1,123 @@
+package master
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+// storedOn returns meta asking for replication replicas, with every chunk
+// stored on addrs.
+func storedOn(meta *FileMeta, replication int, addrs ...string) *FileMeta {
+	meta.Replication = replication
+	for i := range meta.Chunks {
+		meta.Chunks[i].Locations = addrs
+	}
+	return meta
+}
+
+// wantRepairs checks which chunks underReplicated lists, and the healthy
+// replicas and replication it gives each.
+func wantRepairs(t *testing.T, s *Server, want map[string]chunkHealth) {
+	t.Helper()
+	got := make(map[string]*chunkHealth)
+	for _, h := range s.underReplicated() {
+		got[h.chunk.ID] = h
+	}
+	if len(got) != len(want) {
+		t.Errorf("%d chunks to repair, want %d", len(got), len(want))
+	}
+	for id, w := range want {
+		h, ok := got[id]
+		switch {
+		case !ok:
+			t.Errorf("chunk %s not repaired", id)
+		case h.replication != w.replication || !slices.Equal(h.healthy, w.healthy):
+			t.Errorf("chunk %s repaired to %d replicas from %v, want %d from %v", id, h.replication, h.healthy, w.replication, w.healthy)
+		}
+	}
+}
+
+func TestRepairCoversOldVersions(t *testing.T) {
+	s := newTestServer(t, Config{})
+	store := s.metadata
+
+	// Version 1 used c1 and c2; version 2 kept c2 and, with more
+	// replicas, replaced c1 by c3
+	if err := store.Create(storedOn(fileWithChunks("/f", [2]string{"c1", "h1"}, [2]string{"c2", "h2"}), 2, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Replace(storedOn(fileWithChunks("/f", [2]string{"c3", "h3"}, [2]string{"c2", "h2"}), 3, "a", "b"), AnyGeneration); err != nil {
+		t.Fatal(err)
+	}
+
+	// b lost every chunk but c3
+	report(s.registry, "a", 1<<30, 0, "c1", "c2", "c3")
+	report(s.registry, "b", 1<<30, 0, "c3")
+
+	// c1 is only in the old version, and still needs its two replicas;
+	// c2, shared, needs what the current version asks for
+	wantRepairs(t, s, map[string]chunkHealth{
+		"c1": {replication: 2, healthy: []string{"a"}},
+		"c2": {replication: 3, healthy: []string{"a"}},
+		"c3": {replication: 3, healthy: []string{"a", "b"}},
+	})
+}
+
+func TestRepairCoversTrash(t *testing.T) {
+	s := newTestServer(t, Config{})
+	store := s.metadata
+	if err := store.Create(storedOn(fileWithChunks("/f", [2]string{"c1", "h1"}), 2, "a", "b")); err != nil {
+		t.Fatal(err)
+	}
+	entry, err := store.Trash("/f")
+	if err != nil {
+		t.Fatalf("Trash: %v", err)
+	}
+
+	// b is lost; the trashed file's chunk is repaired like any other
+	report(s.registry, "a", 1<<30, 0, "c1")
+	wantRepairs(t, s, map[string]chunkHealth{
+		"c1": {replication: 2, healthy: []string{"a"}},
+	})
+
+	// and the new replicas are where it comes back from the trash
+	if err := store.SetChunkLocations("c1", []string{"a", "c"}); err != nil {
+		t.Fatalf("SetChunkLocations: %v", err)
+	}
+	restored, err := store.Undelete(entry.ID, "")
+	if err != nil {
+		t.Fatalf("Undelete: %v", err)
+	}
+	if locations := restored.Chunks[0].Locations; !slices.Equal(locations, []string{"a", "c"}) {
+		t.Errorf("restored file's chunk on %v, want a and c", locations)
+	}
+}
+
+func TestRepairReservesTheStoredSize(t *testing.T) {
+	s := newTestServer(t, Config{})
+	source, _ := quietChunkserver(t, s)
+
+	// The target has room for the chunk as stored, compressed, but not
+	// for what it holds
+	report(s.registry, "b", 1000, 0)
+	h := &chunkHealth{
+		chunk:       ChunkMeta{ID: "c1", Size: 4000, Codec: compression.Zstd, StoredSize: 600, Locations: []string{source}},
+		replication: 2,
+		healthy:     []string{source},
+	}
+
+	// The source doesn't really have it, so the copy fails, and the space
+	// counted for it on the target is given back
+	err := s.repairChunk(h)
+	if err == nil || errors.Is(err, ErrNotEnoughChunkservers) {
+		t.Fatalf("repair from a source without the chunk: err = %v, want the copy to fail", err)
+	}
+	s.registry.mu.Lock()
+	defer s.registry.mu.Unlock()
+	if used := s.registry.nodes["b"].used; used != 0 {
+		t.Errorf("%d bytes still counted on the target after a failed copy, want 0", used)
+	}
+}
@@ -2,59 +2,288 @@ package master
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/chunkclient"
 )
 
 // Default chunk size for streaming file transfers (1MB).
-// Smaller than the 64MB chunks we'll use for storage in Phase 2,
+// Smaller than the 64MB chunks we use for storage (DefaultChunkSize),
 // this is just for gRPC streaming efficiency.
 const defaultChunkSize = 1024 * 1024 // 1MB
 
-// Server implements the gRPC FileService interface.
-// It coordinates metadata storage and file data storage.
+// Replication limits.
+// Three replicas survive two simultaneous failures, which is the classic
+// GFS/HDFS trade-off between durability and storage cost.
+const (
+	DefaultReplication = 3
+	MaxReplication     = 16
+)
+
+// Config holds the settings for a master Server.
+type Config struct {
+	// Metadata is where file metadata is kept. nil means a fresh
+	// InMemoryMetadataStore (handy for experiments, but forgotten on restart).
+	Metadata MetadataStore
+
+	// ChunkSize is the maximum size of each stored chunk.
+	// Zero means DefaultChunkSize.
+	ChunkSize int64
+
+	// Chunking is how uploads that don't choose are cut into chunks.
+	// The zero value is FixedChunking.
+	Chunking Chunking
+
+	// CDCAverageSize is the average chunk size for content-defined
+	// chunking, at most a quarter of ChunkSize. Zero means
+	// DefaultCDCAverageSize.
+	CDCAverageSize int64
+
+	// DefaultReplication is the number of replicas for files that don't
+	// ask for a specific number. Zero means DefaultReplication.
+	DefaultReplication int
+
+	// HeartbeatTimeout is how long a chunkserver may stay silent before
+	// it is considered dead. Zero means DefaultHeartbeatTimeout.
+	HeartbeatTimeout time.Duration
+
+	// RepairInterval is how often to look for under-replicated chunks.
+	// Zero means DefaultRepairInterval.
+	RepairInterval time.Duration
+
+	// GCGrace is how long an unreferenced chunk survives on a chunkserver
+	// before it is deleted. Zero means DefaultGCGrace.
+	GCGrace time.Duration
+
+	// UploadSessionTimeout is how long a resumable upload may sit idle
+	// before it is abandoned. Zero means DefaultUploadSessionTimeout.
+	UploadSessionTimeout time.Duration
+
+	// Retention says how many old versions of files to keep, and for how
+	// long. Files no rule covers keep DefaultKeepVersions versions.
+	Retention []RetentionRule
+
+	// TrashGrace is how long deleted files stay in the trash before they
+	// are purged. Zero means DefaultTrashGrace.
+	TrashGrace time.Duration
+
+	// Compression says which files to compress at rest, and how. Files no
+	// rule covers, and that don't ask, are stored uncompressed.
+	Compression []CompressionRule
+
+	// KMS holds the master keys that wrap files' data keys (see keys.go).
+	// With one, every file uploaded is encrypted at rest; nil means files
+	// are stored as they are.
+	KMS KMS
+}
+
+// Server implements the gRPC FileService and MasterService interfaces.
+// It owns the namespace (file metadata) and decides where chunks live;
+// the chunk bytes themselves are stored on chunkservers.
 type Server struct {
-	// Embed the unimplemented server for forward compatibility.
+	// Embed the unimplemented servers for forward compatibility.
 	// This is a gRPC best practice - if new methods are added to the
 	// proto, your code won't break (it just returns "unimplemented").
 	api.UnimplementedFileServiceServer
+	api.UnimplementedMasterServiceServer
 
-	// metadata stores file metadata (names, sizes, timestamps)
+	// metadata stores file metadata (names, sizes, timestamps, chunks)
 	metadata MetadataStore
 
-	// dataDir is where actual file data is stored on disk.
-	// In Phase 1, we store complete files. In later phases,
-	// this becomes chunk storage.
-	dataDir string
+	// chunks holds connections to chunkservers.
+	chunks *chunkclient.Pool
+
+	// registry tracks live chunkservers from their heartbeats and
+	// decides where new chunks go.
+	registry *registry
+
+	// pending holds chunks written to chunkservers but not yet committed
+	// to metadata, so garbage collection leaves them alone.
+	pending *pendingChunks
+
+	// gcGrace is how long a chunk must stay unreferenced before we ask a
+	// chunkserver to delete it.
+	gcGrace time.Duration
+
+	// sessions holds resumable uploads in progress.
+	sessions       *uploadSessions
+	sessionTimeout time.Duration
+
+	// retention holds the rules for keeping old versions.
+	retention []RetentionRule
+
+	// compression holds the rules for compressing files at rest.
+	compression []CompressionRule
+
+	// kms wraps and unwraps data keys, if files are encrypted at rest.
+	kms KMS
+
+	// trashGrace is how long deleted files wait in the trash.
+	trashGrace time.Duration
+
+	// events holds recent namespace changes for watchers.
+	events *eventLog
+
+	// changes is held from a metadata write until its events are
+	// published, so events are numbered in the order the writes were
+	// made: a watcher sees a file's generations in order.
+	changes sync.Mutex
+
+	// stop and done coordinate the background repairer with Close.
+	stop chan struct{}
+	done chan struct{}
+
+	// chunkSize is the maximum size of a stored chunk.
+	chunkSize int64
+
+	// chunking is the default way to cut uploads into chunks, and
+	// cdcAverageSize the chunk size content-defined chunking aims for.
+	chunking       Chunking
+	cdcAverageSize int64
+
+	// defaultReplication applies to uploads that don't set a replication factor.
+	defaultReplication int
 }
 
 // NewServer creates a new DFS master server.
-// dataDir is the directory where file data will be stored.
-func NewServer(dataDir string) (*Server, error) {
-	// Ensure data directory exists
-	if err := os.MkdirAll(dataDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create data directory: %w", err)
+func NewServer(cfg Config) (*Server, error) {
+	metadata := cfg.Metadata
+	if metadata == nil {
+		metadata = NewInMemoryMetadataStore()
 	}
 
-	return &Server{
-		metadata: NewInMemoryMetadataStore(),
-		dataDir:  dataDir,
-	}, nil
+	chunkSize := cfg.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	// Leave room for chunks to vary in size on both sides of the average
+	cdcAverageSize := cfg.CDCAverageSize
+	if cdcAverageSize <= 0 {
+		cdcAverageSize = DefaultCDCAverageSize
+	}
+	cdcAverageSize = min(cdcAverageSize, chunkSize/4)
+
+	replication := cfg.DefaultReplication
+	if replication <= 0 {
+		replication = DefaultReplication
+	}
+	if replication > MaxReplication {
+		return nil, fmt.Errorf("default replication %d exceeds maximum of %d", replication, MaxReplication)
+	}
+
+	heartbeatTimeout := cfg.HeartbeatTimeout
+	if heartbeatTimeout <= 0 {
+		heartbeatTimeout = DefaultHeartbeatTimeout
+	}
+	repairInterval := cfg.RepairInterval
+	if repairInterval <= 0 {
+		repairInterval = DefaultRepairInterval
+	}
+	gcGrace := cfg.GCGrace
+	if gcGrace <= 0 {
+		gcGrace = DefaultGCGrace
+	}
+	sessionTimeout := cfg.UploadSessionTimeout
+	if sessionTimeout <= 0 {
+		sessionTimeout = DefaultUploadSessionTimeout
+	}
+	trashGrace := cfg.TrashGrace
+	if trashGrace <= 0 {
+		trashGrace = DefaultTrashGrace
+	}
+
+	// Rules must name paths the way the namespace does to match anything
+	retention := make([]RetentionRule, 0, len(cfg.Retention))
+	for _, rule := range cfg.Retention {
+		prefix, err := cleanPath(rule.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention prefix: %w", err)
+		}
+		rule.Prefix = prefix
+		retention = append(retention, rule)
+	}
+	compressionRules := make([]CompressionRule, 0, len(cfg.Compression))
+	for _, rule := range cfg.Compression {
+		prefix, err := cleanPath(rule.Prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compression prefix: %w", err)
+		}
+		rule.Prefix = prefix
+		compressionRules = append(compressionRules, rule)
+	}
+
+	s := &Server{
+		metadata:           metadata,
+		chunks:             chunkclient.NewPool(),
+		registry:           newRegistry(heartbeatTimeout),
+		pending:            newPendingChunks(),
+		gcGrace:            gcGrace,
+		sessions:           newUploadSessions(),
+		sessionTimeout:     sessionTimeout,
+		retention:          retention,
+		compression:        compressionRules,
+		kms:                cfg.KMS,
+		trashGrace:         trashGrace,
+		events:             newEventLog(),
+		stop:               make(chan struct{}),
+		done:               make(chan struct{}),
+		chunkSize:          chunkSize,
+		chunking:           cfg.Chunking,
+		cdcAverageSize:     cdcAverageSize,
+		defaultReplication: replication,
+	}
+
+	// Keep chunks at their replication factor in the background
+	go s.repairLoop(repairInterval)
+
+	return s, nil
+}
+
+// Close stops background work and releases connections to chunkservers.
+func (s *Server) Close() {
+	close(s.stop)
+	<-s.done
+	s.chunks.Close()
 }
 
 // Upload handles streaming file uploads from clients.
 // The client sends: 1) metadata message, then 2) multiple chunk messages.
+//
+// Incoming data is cut into chunks of s.chunkSize bytes, and each chunk is
+// streamed down a replication pipeline as it arrives - we never buffer a
+// whole chunk (let alone a whole file) in the master's memory. A chunk only
+// counts as written once every replica has acknowledged it.
+//
+// The declared size is a promise, not a fact: we count the bytes actually
+// received and refuse to commit a file whose stream came up short (say, the
+// client disconnected) or ran long. Metadata is only ever registered for a
+// complete file, so Stat never reports a size we don't have.
+//
+// An upload is invisible until its commit point, the metadata write: before
+// that its chunks are staged under fresh IDs that nothing references, so
+// a concurrent Download can't see a partial file, and a failed upload of an
+// existing name never touches the existing file's chunks.
+//
+// The metadata's write mode says what to do if the file already exists:
+// fail (the default), replace it, or replace it only if it is still at the
+// generation the client last saw - a compare-and-swap that lets several
+// writers update a file without losing each other's changes.
+//
+// A failed Upload has to start over. For large files over unreliable
+// links, clients should use the resumable upload session RPCs instead.
 func (s *Server) Upload(stream api.FileService_UploadServer) error {
-	var (
-		filename string
-		fileSize int64
-		file     *os.File
-	)
+	var u *upload
 
 	// Receive messages from the stream until EOF or error
 	for {
@@ -66,10 +295,8 @@ func (s *Server) Upload(stream api.FileService_UploadServer) error {
 			break
 		}
 		if err != nil {
-			// Clean up partial file on error
-			if file != nil {
-				file.Close()
-				os.Remove(filepath.Join(s.dataDir, filename))
+			if u != nil {
+				u.abort()
 			}
 			return fmt.Errorf("failed to receive chunk: %w", err)
 		}
@@ -78,172 +305,220 @@ func (s *Server) Upload(stream api.FileService_UploadServer) error {
 		switch data := req.Data.(type) {
 		case *api.UploadRequest_Metadata:
 			// First message contains file metadata
-			filename = data.Metadata.Filename
-			fileSize = data.Metadata.Size
-
-			// Validate filename to prevent path traversal attacks
-			// This is a security best practice!
-			if filepath.Base(filename) != filename {
-				return errors.New("invalid filename: must not contain path separators")
+			if u != nil {
+				u.abort()
+				return status.Error(codes.InvalidArgument, "metadata sent twice")
 			}
-
-			// Create the file for writing
-			filePath := filepath.Join(s.dataDir, filename)
-			file, err = os.Create(filePath)
+			u, err = s.newUpload(stream.Context(), data.Metadata)
 			if err != nil {
-				return fmt.Errorf("failed to create file: %w", err)
+				return err
 			}
 
 		case *api.UploadRequest_Chunk:
 			// Subsequent messages contain file data chunks
-			if file == nil {
-				return errors.New("received chunk before metadata")
+			if u == nil {
+				return status.Error(codes.InvalidArgument, "received chunk before metadata")
 			}
-
-			// Write chunk to file
-			if _, err := file.Write(data.Chunk); err != nil {
-				file.Close()
-				os.Remove(filepath.Join(s.dataDir, filename))
-				return fmt.Errorf("failed to write chunk: %w", err)
+			if err := u.write(stream.Context(), data.Chunk); err != nil {
+				u.abort()
+				return err
 			}
 		}
 	}
 
-	// Close the file
-	if file != nil {
-		if err := file.Close(); err != nil {
-			return fmt.Errorf("failed to close file: %w", err)
-		}
+	if u == nil {
+		return status.Error(codes.InvalidArgument, "upload ended before metadata was received")
 	}
 
-	// Store metadata
-	meta := &FileMeta{
-		Filename: filename,
-		Size:     fileSize,
-	}
-	if err := s.metadata.Create(meta); err != nil {
-		// If metadata creation fails, remove the data file
-		os.Remove(filepath.Join(s.dataDir, filename))
-		return fmt.Errorf("failed to store metadata: %w", err)
+	meta, err := u.finish()
+	if err != nil {
+		// There's no resuming a plain Upload
+		u.abort()
+		return err
 	}
 
 	// Send success response
 	return stream.SendAndClose(&api.UploadResponse{
-		Success: true,
-		Message: fmt.Sprintf("File '%s' uploaded successfully", filename),
-		FileId:  filename, // In Phase 1, filename is the ID
+		Success:     true,
+		Message:     fmt.Sprintf("File '%s' uploaded successfully", meta.Filename),
+		FileId:      meta.Filename, // Filename is still the ID
+		Generation:  meta.Generation,
+		Chunks:      int32(len(meta.Chunks)),
+		DedupChunks: int32(u.dedupChunks),
+		DedupBytes:  u.dedupBytes,
 	})
 }
 
 // Download handles streaming file downloads to clients.
 // The server sends: 1) metadata message, then 2) multiple chunk messages.
+//
+// Offset and length in the request select a byte range; by default the
+// whole file is sent. A version in the request reads that old version.
+//
+// The master proxies the data here, reading each chunk from a chunkserver.
+// Clients that can reach chunkservers directly should prefer
+// GetChunkLocations and read chunks themselves.
+//
+// The metadata message carries the file's SHA-256 so the client can verify
+// what it received. We also check it ourselves as the data passes through
+// and fail the stream on a mismatch, rather than quietly end it.
 func (s *Server) Download(req *api.DownloadRequest, stream api.FileService_DownloadServer) error {
-	filename := req.Filename
-
 	// Get file metadata
-	meta, err := s.metadata.Get(filename)
+	meta, err := s.getVersion(req.Filename, req.Version)
 	if err != nil {
-		if errors.Is(err, ErrFileNotFound) {
-			return fmt.Errorf("file not found: %s", filename)
-		}
-		return fmt.Errorf("failed to get metadata: %w", err)
+		return err
 	}
+	filename := meta.Filename
 
-	// Open the file for reading
-	filePath := filepath.Join(s.dataDir, filename)
-	file, err := os.Open(filePath)
+	// Work out which bytes to send
+	start, end, err := fileRange(meta.Size, req.Offset, req.Length)
+	if err != nil {
+		return err
+	}
+	key, err := s.dataKey(stream.Context(), meta)
 	if err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
+		return err
 	}
-	defer file.Close() // Always close file when function returns
 
-	// Send metadata as first message
+	// Send metadata as first message. Size is the whole file's, even when
+	// only a range is read, so clients can tell where the range falls.
 	if err := stream.Send(&api.DownloadResponse{
 		Data: &api.DownloadResponse_Metadata{
 			Metadata: &api.FileMetadata{
 				Filename: meta.Filename,
 				Size:     meta.Size,
+				Checksum: meta.Checksum,
 			},
 		},
 	}); err != nil {
 		return fmt.Errorf("failed to send metadata: %w", err)
 	}
 
-	// Stream file data in chunks
-	// Using a buffer avoids allocating memory for each chunk
-	buf := make([]byte, defaultChunkSize)
-	for {
-		// Read up to defaultChunkSize bytes
-		n, err := file.Read(buf)
-		if err == io.EOF {
-			// Finished reading file
-			break
+	// Stream the chunks overlapping the range in order, passing data
+	// straight through. Chunks before the range are skipped without
+	// reading them, so a read near the end of a huge file is cheap.
+	hash := sha256.New()
+	var chunkStart int64
+	for _, chunk := range meta.Chunks {
+		chunkEnd := chunkStart + chunk.Size
+		if chunkEnd <= start || chunkStart >= end {
+			chunkStart = chunkEnd
+			continue
 		}
+
+		offset := max(start-chunkStart, 0)
+		length := min(end, chunkEnd) - chunkStart - offset
+		err := s.readChunk(stream.Context(), chunk, key, offset, length, func(data []byte) error {
+			hash.Write(data)
+			if err := stream.Send(&api.DownloadResponse{
+				Data: &api.DownloadResponse_Chunk{
+					Chunk: data,
+				},
+			}); err != nil {
+				return fmt.Errorf("failed to send chunk: %w", err)
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("failed to read file: %w", err)
+			return err
 		}
+		chunkStart = chunkEnd
+	}
 
-		// Send the chunk (only the bytes we read, not the full buffer)
-		if err := stream.Send(&api.DownloadResponse{
-			Data: &api.DownloadResponse_Chunk{
-				Chunk: buf[:n],
-			},
-		}); err != nil {
-			return fmt.Errorf("failed to send chunk: %w", err)
+	// The stored checksum covers the whole file; a range can't be checked
+	// against it (chunkservers still verify every chunk they serve).
+	if meta.Checksum != "" && start == 0 && end == meta.Size {
+		if sum := hex.EncodeToString(hash.Sum(nil)); sum != meta.Checksum {
+			return fmt.Errorf("checksum mismatch for %s: stored %s, read %s", filename, meta.Checksum, sum)
 		}
 	}
 
 	return nil
 }
 
-// List returns metadata for all files matching the prefix filter.
-func (s *Server) List(ctx context.Context, req *api.ListRequest) (*api.ListResponse, error) {
-	// The context carries cancellation signals and deadlines.
-	// We should check ctx.Done() for long operations, but List is fast.
-
-	files, err := s.metadata.List(req.Prefix)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list files: %w", err)
+// fileRange resolves a requested byte range against a file of the given
+// size, returning the half-open interval [start, end) to read. A negative
+// offset counts back from the end; a zero length means "to the end".
+func fileRange(size, offset, length int64) (start, end int64, err error) {
+	if length < 0 {
+		return 0, 0, status.Errorf(codes.InvalidArgument, "invalid length %d", length)
 	}
 
-	// Convert internal FileMeta to API FileInfo
-	fileInfos := make([]*api.FileInfo, 0, len(files))
-	for _, f := range files {
-		fileInfos = append(fileInfos, &api.FileInfo{
-			Filename:   f.Filename,
-			Size:       f.Size,
-			CreatedAt:  f.CreatedAt.Unix(),
-			ModifiedAt: f.ModifiedAt.Unix(),
-		})
+	start = offset
+	if offset < 0 {
+		// Asking for the last N bytes of a shorter file gets all of it
+		start = max(size+offset, 0)
+	}
+	if start > size {
+		return 0, 0, status.Errorf(codes.OutOfRange, "offset %d is past the end of the file (%d bytes)", offset, size)
 	}
 
-	return &api.ListResponse{
-		Files: fileInfos,
-	}, nil
+	end = size
+	if length > 0 {
+		end = min(start+length, size)
+	}
+	return start, end, nil
 }
 
-// Delete removes a file from the DFS.
+// Delete removes a file from the DFS. Directories are removed with Rmdir.
+// By default the file only moves to the trash (see trash.go); permanent
+// deletes it, and its old versions, at once.
 func (s *Server) Delete(ctx context.Context, req *api.DeleteRequest) (*api.DeleteResponse, error) {
-	filename := req.Filename
+	filename, err := cleanFilePath(req.Filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
 
-	// Check if file exists
-	if !s.metadata.Exists(filename) {
+	// Check if file exists (we also need its chunk list below)
+	meta, err := s.metadata.Get(filename)
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) {
+			return &api.DeleteResponse{
+				Success: false,
+				Message: fmt.Sprintf("file not found: %s", filename),
+			}, nil
+		}
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if meta.IsDir {
 		return &api.DeleteResponse{
 			Success: false,
-			Message: fmt.Sprintf("file not found: %s", filename),
+			Message: fmt.Sprintf("%s is a directory (use rmdir)", filename),
 		}, nil
 	}
 
-	// Delete the actual file data first
-	filePath := filepath.Join(s.dataDir, filename)
-	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
-		return nil, fmt.Errorf("failed to delete file data: %w", err)
+	// Unless asked otherwise, keep the file (and its chunks) in the trash
+	// for a while, in case the delete was a mistake
+	if !req.Permanent {
+		s.changes.Lock()
+		entry, err := s.metadata.Trash(filename)
+		if err != nil {
+			s.changes.Unlock()
+			return nil, metadataStatus(fmt.Errorf("failed to move to trash: %w", err))
+		}
+		s.publish(api.WatchEventType_WATCH_EVENT_DELETE, entry.File, "")
+		s.changes.Unlock()
+		return &api.DeleteResponse{
+			Success: true,
+			Message: fmt.Sprintf("File '%s' moved to trash (id %s)", filename, entry.ID),
+			TrashId: entry.ID,
+		}, nil
 	}
 
-	// Delete metadata
-	if err := s.metadata.Delete(filename); err != nil {
-		return nil, fmt.Errorf("failed to delete metadata: %w", err)
+	// Delete metadata first: once it's gone the file is invisible, so
+	// no new reader can look up the chunks we're about to remove.
+	s.changes.Lock()
+	removed, err := s.metadata.Delete(filename)
+	if err != nil {
+		s.changes.Unlock()
+		return nil, metadataStatus(fmt.Errorf("failed to delete metadata: %w", err))
 	}
+	s.publish(api.WatchEventType_WATCH_EVENT_DELETE, meta, "")
+	s.changes.Unlock()
+
+	// Then delete the chunk data (the file's and its old versions')
+	// from chunkservers
+	s.releaseFiles(removed)
 
 	return &api.DeleteResponse{
 		Success: true,
@@ -251,11 +526,29 @@ func (s *Server) Delete(ctx context.Context, req *api.DeleteRequest) (*api.Delet
 	}, nil
 }
 
-// Stat returns metadata for a specific file.
+// Stat returns metadata for a specific file or directory, or for an old
+// version of a file.
 func (s *Server) Stat(ctx context.Context, req *api.StatRequest) (*api.StatResponse, error) {
-	meta, err := s.metadata.Get(req.Filename)
+	p, err := cleanPath(req.Filename)
 	if err != nil {
-		if errors.Is(err, ErrFileNotFound) {
+		return nil, metadataStatus(err)
+	}
+	if p == rootDir {
+		// The root isn't stored anywhere, but it certainly exists
+		return &api.StatResponse{
+			Exists: true,
+			File:   fileInfo(&FileMeta{Filename: rootDir, IsDir: true}),
+		}, nil
+	}
+
+	var meta *FileMeta
+	if req.Version != 0 {
+		meta, err = s.metadata.GetVersion(p, req.Version)
+	} else {
+		meta, err = s.metadata.Get(p)
+	}
+	if err != nil {
+		if errors.Is(err, ErrFileNotFound) || errors.Is(err, ErrVersionNotFound) {
 			return &api.StatResponse{
 				Exists: false,
 			}, nil
@@ -265,11 +558,97 @@ func (s *Server) Stat(ctx context.Context, req *api.StatRequest) (*api.StatRespo
 
 	return &api.StatResponse{
 		Exists: true,
-		File: &api.FileInfo{
-			Filename:   meta.Filename,
-			Size:       meta.Size,
-			CreatedAt:  meta.CreatedAt.Unix(),
-			ModifiedAt: meta.ModifiedAt.Unix(),
-		},
+		File:   fileInfo(meta),
+		Chunks: s.chunkLocations(meta),
+	}, nil
+}
+
+// GetChunkLocations returns a file's chunks and where each is stored.
+// Clients use this to read chunk data straight from chunkservers, so the
+// bytes don't have to flow through the master.
+//
+// For an encrypted file that means handing over its data key, unwrapped,
+// so the client can decrypt the chunks itself. It is the key to that one
+// file only, which the client could read through Download anyway - and
+// like Download, this is open to any caller: see keys.go for what
+// encryption does and doesn't protect against.
+func (s *Server) GetChunkLocations(ctx context.Context, req *api.GetChunkLocationsRequest) (*api.GetChunkLocationsResponse, error) {
+	meta, err := s.getVersion(req.Filename, req.Version)
+	if err != nil {
+		return nil, err
+	}
+	key, err := s.dataKey(ctx, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.GetChunkLocationsResponse{
+		File:    fileInfo(meta),
+		Chunks:  s.chunkLocations(meta),
+		DataKey: key,
 	}, nil
 }
+
+// chunkLocations describes where each of a file's chunks lives.
+// Replicas are ordered healthy-first so that clients try them first.
+func (s *Server) chunkLocations(meta *FileMeta) []*api.ChunkLocation {
+	chunks := make([]*api.ChunkLocation, 0, len(meta.Chunks))
+	var offset int64
+	for _, chunk := range meta.Chunks {
+		// Healthy means the chunkserver is heartbeating and holds the chunk
+		var healthy, unhealthy []string
+		for _, addr := range chunk.Locations {
+			if s.registry.hasReplica(addr, chunk.ID) {
+				healthy = append(healthy, addr)
+			} else {
+				unhealthy = append(unhealthy, addr)
+			}
+		}
+
+		chunks = append(chunks, &api.ChunkLocation{
+			ChunkId:         chunk.ID,
+			Offset:          offset,
+			Size:            chunk.Size,
+			Addresses:       append(healthy, unhealthy...),
+			HealthyReplicas: int32(len(healthy)),
+			Crc32C:          chunk.CRC32C,
+			Hash:            chunk.Hash,
+			Refs:            int32(s.metadata.ChunkRefs(chunk.ID)),
+			Compression:     chunkCompression(chunk.Codec),
+			StoredSize:      chunk.storedSize(),
+		})
+		offset += chunk.Size
+	}
+	return chunks
+}
+
+// fileInfo converts internal FileMeta to the API's FileInfo.
+func fileInfo(meta *FileMeta) *api.FileInfo {
+	var supersededAt int64
+	if !meta.SupersededAt.IsZero() {
+		supersededAt = meta.SupersededAt.Unix()
+	}
+	var stored int64
+	for _, chunk := range meta.Chunks {
+		stored += chunk.storedSize()
+	}
+	var keyID string
+	if meta.DataKey != nil {
+		keyID = meta.DataKey.KeyID
+	}
+	return &api.FileInfo{
+		Filename:     meta.Filename,
+		Size:         meta.Size,
+		CreatedAt:    meta.CreatedAt.Unix(),
+		ModifiedAt:   meta.ModifiedAt.Unix(),
+		Replication:  int32(meta.Replication),
+		Checksum:     meta.Checksum,
+		Generation:   meta.generation(),
+		IsDir:        meta.IsDir,
+		SupersededAt: supersededAt,
+		Attributes:   meta.Attributes,
+		Tags:         meta.Tags,
+		StoredSize:   stored,
+		KeyId:        keyID,
+	}
+}
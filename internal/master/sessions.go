@@ -0,0 +1,269 @@
+package master
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// DefaultUploadSessionTimeout is how long an upload session may sit idle
+// before the master gives up on it and deletes the chunks it wrote.
+const DefaultUploadSessionTimeout = 24 * time.Hour
+
+// uploadSession is a resumable upload. Its data survives client
+// disconnects (but not a master restart: sessions live in memory, and the
+// client starts over if its session is gone).
+type uploadSession struct {
+	// mu is held by whichever call is working on the upload. A client
+	// that reconnects after a dropped stream simply waits here until the
+	// server notices the old stream is dead and rolls it back.
+	mu     sync.Mutex
+	upload *upload
+	closed bool // Finished, aborted or expired; guarded by mu
+
+	// lastActive is guarded by uploadSessions.mu.
+	lastActive time.Time
+}
+
+// uploadSessions holds the open upload sessions by ID.
+type uploadSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*uploadSession
+}
+
+func newUploadSessions() *uploadSessions {
+	return &uploadSessions{sessions: make(map[string]*uploadSession)}
+}
+
+// add registers a new session for u and returns its ID.
+func (ss *uploadSessions) add(u *upload) string {
+	// rand.Text is 26 random base32 characters: unguessable, so a session
+	// ID is also the capability to write to it.
+	id := rand.Text()
+
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.sessions[id] = &uploadSession{upload: u, lastActive: time.Now()}
+	return id
+}
+
+// acquire locks the session with the given ID for the caller's exclusive
+// use. The caller must pass it to release when done.
+func (ss *uploadSessions) acquire(id string) (*uploadSession, error) {
+	ss.mu.Lock()
+	sess, ok := ss.sessions[id]
+	ss.mu.Unlock()
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "upload session %q not found", id)
+	}
+
+	sess.mu.Lock()
+	if sess.closed {
+		// Finished or expired while we waited for it
+		sess.mu.Unlock()
+		return nil, status.Errorf(codes.NotFound, "upload session %q not found", id)
+	}
+	return sess, nil
+}
+
+// release unlocks a session taken with acquire.
+func (ss *uploadSessions) release(sess *uploadSession) {
+	ss.mu.Lock()
+	sess.lastActive = time.Now()
+	ss.mu.Unlock()
+	sess.mu.Unlock()
+}
+
+// remove forgets a session. The caller must have acquired it.
+func (ss *uploadSessions) remove(id string, sess *uploadSession) {
+	sess.closed = true
+	ss.mu.Lock()
+	delete(ss.sessions, id)
+	ss.mu.Unlock()
+}
+
+// expire removes sessions idle for longer than timeout and returns them,
+// still locked, so the caller can abort them.
+func (ss *uploadSessions) expire(timeout time.Duration) []*uploadSession {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+
+	now := time.Now()
+	var expired []*uploadSession
+	for id, sess := range ss.sessions {
+		if now.Sub(sess.lastActive) <= timeout {
+			continue
+		}
+		// A session in use isn't idle, whatever its timestamp says
+		if !sess.mu.TryLock() {
+			continue
+		}
+		sess.closed = true
+		delete(ss.sessions, id)
+		expired = append(expired, sess)
+	}
+	return expired
+}
+
+// expireUploadSessions aborts upload sessions that clients abandoned.
+func (s *Server) expireUploadSessions() {
+	for _, sess := range s.sessions.expire(s.sessionTimeout) {
+		log.Printf("master: upload session for %s expired, deleting its chunks", sess.upload.filename)
+		sess.upload.abort()
+		sess.mu.Unlock()
+	}
+}
+
+// StartUpload opens a resumable upload session.
+// The metadata is checked up front, exactly as for Upload.
+func (s *Server) StartUpload(ctx context.Context, req *api.StartUploadRequest) (*api.StartUploadResponse, error) {
+	if req.Metadata == nil {
+		return nil, status.Error(codes.InvalidArgument, "missing file metadata")
+	}
+
+	u, err := s.newUpload(ctx, req.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.StartUploadResponse{
+		SessionId: s.sessions.add(u),
+	}, nil
+}
+
+// WriteUpload streams data into an upload session.
+// The first message names the session and the file offset its data starts
+// at. That offset may be before the committed offset - data the session
+// already has is skipped - but not after it, since that would leave a hole.
+//
+// When the stream ends cleanly, everything received is committed. When it
+// fails, the session rolls back to the last full chunk, and the client
+// should call QueryUpload to learn where to resume.
+func (s *Server) WriteUpload(stream api.FileService_WriteUploadServer) error {
+	req, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "write ended before naming a session")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to receive data: %w", err)
+	}
+
+	sess, err := s.sessions.acquire(req.SessionId)
+	if err != nil {
+		return err
+	}
+	defer s.sessions.release(sess)
+	u := sess.upload
+
+	if req.Offset < 0 || req.Offset > u.committed {
+		return status.Errorf(codes.OutOfRange,
+			"offset %d is past the committed offset %d", req.Offset, u.committed)
+	}
+	skip := u.committed - req.Offset
+
+	for {
+		data := req.Data
+		if skip > 0 {
+			n := min(skip, int64(len(data)))
+			data = data[n:]
+			skip -= n
+		}
+		if len(data) > 0 {
+			if err := u.write(stream.Context(), data); err != nil {
+				u.rollback()
+				return err
+			}
+		}
+
+		req, err = stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			u.rollback()
+			return fmt.Errorf("failed to receive data: %w", err)
+		}
+	}
+
+	// Close the open chunk so everything received counts as committed.
+	// This may leave a short chunk in the middle of the file, which is
+	// fine: chunk sizes are recorded individually.
+	if err := u.flush(); err != nil {
+		u.rollback()
+		return err
+	}
+
+	return stream.SendAndClose(&api.WriteUploadResponse{
+		CommittedOffset: u.committed,
+	})
+}
+
+// QueryUpload reports how much of an upload session is committed.
+func (s *Server) QueryUpload(ctx context.Context, req *api.QueryUploadRequest) (*api.QueryUploadResponse, error) {
+	sess, err := s.sessions.acquire(req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer s.sessions.release(sess)
+
+	return &api.QueryUploadResponse{
+		CommittedOffset: sess.upload.committed,
+		Size:            sess.upload.size,
+	}, nil
+}
+
+// FinishUpload commits an upload session's file and closes the session.
+// If data is still missing the session stays open, so the client can
+// resume and try again.
+func (s *Server) FinishUpload(ctx context.Context, req *api.FinishUploadRequest) (*api.UploadResponse, error) {
+	sess, err := s.sessions.acquire(req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer s.sessions.release(sess)
+	u := sess.upload
+
+	meta, err := u.finish()
+	if u.done {
+		s.sessions.remove(req.SessionId, sess)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.UploadResponse{
+		Success:     true,
+		Message:     fmt.Sprintf("File '%s' uploaded successfully", meta.Filename),
+		FileId:      meta.Filename,
+		Generation:  meta.Generation,
+		Chunks:      int32(len(meta.Chunks)),
+		DedupChunks: int32(sess.upload.dedupChunks),
+		DedupBytes:  sess.upload.dedupBytes,
+	}, nil
+}
+
+// AbortUpload cancels an upload session and deletes the data it stored.
+func (s *Server) AbortUpload(ctx context.Context, req *api.AbortUploadRequest) (*api.AbortUploadResponse, error) {
+	sess, err := s.sessions.acquire(req.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer s.sessions.release(sess)
+
+	sess.upload.abort()
+	s.sessions.remove(req.SessionId, sess)
+
+	return &api.AbortUploadResponse{
+		Success: true,
+		Message: fmt.Sprintf("Upload of '%s' aborted", sess.upload.filename),
+	}, nil
+}
@@ -0,0 +1,116 @@
+package master
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// writeUpload sends data, starting at offset, to an upload session in one
+// WriteUpload stream, and returns the committed offset.
+func writeUpload(ctx context.Context, c *testCluster, id string, offset int64, data []byte) (int64, error) {
+	stream, err := c.client.WriteUpload(ctx)
+	if err != nil {
+		return 0, err
+	}
+	err = stream.Send(&api.WriteUploadRequest{SessionId: id, Offset: offset})
+	for rest := data; len(rest) > 0 && err == nil; {
+		n := min(len(rest), 16*1024)
+		err = stream.Send(&api.WriteUploadRequest{Data: rest[:n]})
+		rest = rest[n:]
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		return 0, err
+	}
+	return resp.CommittedOffset, nil
+}
+
+// committed asks the master how much of an upload session it has.
+func committed(t *testing.T, c *testCluster, id string) int64 {
+	t.Helper()
+	resp, err := c.client.QueryUpload(context.Background(), &api.QueryUploadRequest{SessionId: id})
+	if err != nil {
+		t.Fatalf("QueryUpload: %v", err)
+	}
+	return resp.CommittedOffset
+}
+
+func TestUploadSessionResumes(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024}, 1, 0)
+	ctx := context.Background()
+
+	const size = 300 * 1024
+	data := randomData(1, size)
+	start, err := c.client.StartUpload(ctx, &api.StartUploadRequest{
+		Metadata: &api.FileMetadata{Filename: "/f", Size: size, Replication: 1},
+	})
+	if err != nil {
+		t.Fatalf("StartUpload: %v", err)
+	}
+	id := start.SessionId
+
+	if n, err := writeUpload(ctx, c, id, 0, data[:100*1024]); err != nil || n != 100*1024 {
+		t.Fatalf("first write: committed %d, %v; want %d", n, err, 100*1024)
+	}
+
+	// Data sent again from before the committed offset is skipped, not
+	// written twice
+	if n, err := writeUpload(ctx, c, id, 50*1024, data[50*1024:200*1024]); err != nil || n != 200*1024 {
+		t.Fatalf("overlapping write: committed %d, %v; want %d", n, err, 200*1024)
+	}
+
+	// Data from past it would leave a hole
+	_, err = writeUpload(ctx, c, id, 250*1024, data[250*1024:])
+	if status.Code(err) != codes.OutOfRange {
+		t.Fatalf("write past the committed offset: err = %v, want OutOfRange", err)
+	}
+	if n := committed(t, c, id); n != 200*1024 {
+		t.Fatalf("committed %d after a refused write, want %d", n, 200*1024)
+	}
+
+	// A stream that breaks off goes back to the last full chunk
+	broken, cancel := context.WithCancel(ctx)
+	stream, err := c.client.WriteUpload(broken)
+	if err != nil {
+		t.Fatalf("WriteUpload: %v", err)
+	}
+	stream.Send(&api.WriteUploadRequest{SessionId: id, Offset: 200 * 1024, Data: data[200*1024 : 280*1024]})
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+	n := committed(t, c, id)
+	if n < 200*1024 || n >= 280*1024 || n%(64*1024) != 200*1024%(64*1024) {
+		t.Fatalf("committed %d after a broken write, want a chunk boundary from %d", n, 200*1024)
+	}
+
+	// Resuming a little before it, as a client unsure of it would, ends
+	// with the file exactly as it should be, nothing in it twice
+	if _, err := writeUpload(ctx, c, id, n-1000, data[n-1000:]); err != nil {
+		t.Fatalf("resumed write: %v", err)
+	}
+	if _, err := c.client.FinishUpload(ctx, &api.FinishUploadRequest{SessionId: id}); err != nil {
+		t.Fatalf("FinishUpload: %v", err)
+	}
+	meta, err := c.master.metadata.Get("/f")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	if meta.Size != size || meta.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("resumed upload stored as %d bytes with checksum %s, not what was written", meta.Size, meta.Checksum)
+	}
+	var stored int64
+	for _, chunk := range meta.Chunks {
+		stored += chunk.Size
+	}
+	if stored != size {
+		t.Errorf("chunks hold %d bytes, want %d", stored, size)
+	}
+}
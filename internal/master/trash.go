@@ -0,0 +1,123 @@
+package master
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Deleting a file moves it, with its old versions, into the trash rather
+// than deleting it outright. It keeps its chunks there, so undeleting it is
+// a metadata change, and it disappears from the namespace straight away,
+// so its path is free to be written again. A sweeper purges what has been
+// in the trash longer than the grace period, and only then are the chunks
+// released. Until then the repairer keeps them replicated like any other
+// file's, so a file in the trash survives losing a chunkserver.
+
+// DefaultTrashGrace is how long deleted files stay in the trash.
+const DefaultTrashGrace = 7 * 24 * time.Hour
+
+// sweepTrash purges the trash entries older than the grace period.
+func (s *Server) sweepTrash() {
+	entries, err := s.metadata.ListTrash()
+	if err != nil {
+		log.Printf("master: failed to list trash: %v", err)
+		return
+	}
+
+	now := time.Now()
+	var expired []string
+	for _, entry := range entries {
+		if now.Sub(entry.DeletedAt) > s.trashGrace {
+			expired = append(expired, entry.ID)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	removed, err := s.metadata.PurgeTrash(expired)
+	if err != nil {
+		log.Printf("master: failed to purge trash: %v", err)
+		return
+	}
+	s.releaseFiles(removed)
+	log.Printf("master: purged %d file(s) from the trash", len(expired))
+}
+
+// ListTrash lists the files in the trash, most recently deleted first.
+func (s *Server) ListTrash(ctx context.Context, req *api.ListTrashRequest) (*api.ListTrashResponse, error) {
+	prefix := req.Prefix
+	if prefix != "" {
+		var err error
+		if prefix, err = cleanPrefix(prefix); err != nil {
+			return nil, metadataStatus(err)
+		}
+	}
+
+	entries, err := s.metadata.ListTrash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash: %w", err)
+	}
+
+	infos := make([]*api.TrashEntry, 0, len(entries))
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.File.Filename, prefix) {
+			continue
+		}
+		infos = append(infos, &api.TrashEntry{
+			Id:        entry.ID,
+			File:      fileInfo(entry.File),
+			DeletedAt: entry.DeletedAt.Unix(),
+			PurgeAt:   entry.DeletedAt.Add(s.trashGrace).Unix(),
+			Versions:  int32(len(entry.Versions)),
+		})
+	}
+	return &api.ListTrashResponse{
+		Entries: infos,
+	}, nil
+}
+
+// Undelete moves a file from the trash back into the namespace, at its
+// original path or a new one. Its old versions come back with it.
+func (s *Server) Undelete(ctx context.Context, req *api.UndeleteRequest) (*api.UndeleteResponse, error) {
+	entry, err := s.metadata.GetTrash(req.Id)
+	if err != nil {
+		return nil, metadataStatus(fmt.Errorf("failed to undelete: %w", err))
+	}
+
+	dst := entry.File.Filename
+	if req.Destination != "" {
+		dst, err = cleanFilePath(req.Destination)
+		if err != nil {
+			return nil, metadataStatus(err)
+		}
+	}
+	s.changes.Lock()
+	defer s.changes.Unlock()
+	if req.Parents {
+		created, err := s.metadata.Mkdir(path.Dir(dst), true)
+		if err != nil {
+			return nil, metadataStatus(fmt.Errorf("failed to create parent directories: %w", err))
+		}
+		s.publishCreated(created)
+	}
+
+	meta, err := s.metadata.Undelete(req.Id, dst)
+	if err != nil {
+		return nil, metadataStatus(fmt.Errorf("failed to undelete: %w", err))
+	}
+	s.publish(api.WatchEventType_WATCH_EVENT_CREATE, meta, "")
+
+	return &api.UndeleteResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Restored '%s' from the trash", meta.Filename),
+		Filename:   meta.Filename,
+		Generation: meta.generation(),
+	}, nil
+}
@@ -0,0 +1,444 @@
+package master
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"maps"
+	"path"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// upload is one file being written. It cuts incoming data into chunks,
+// streams each one down a replication pipeline, and finally commits the
+// file's metadata.
+//
+// The Upload RPC drives an upload from a single stream. An upload session
+// keeps one alive across several streams, so a client can resume after a
+// disconnect: whenever a stream ends, the open chunk is either closed
+// (clean end) or rolled back (error), leaving the upload at a chunk
+// boundary that every replica has confirmed.
+//
+// An upload is not safe for concurrent use.
+type upload struct {
+	s *Server
+
+	filename     string
+	size         int64 // Declared size
+	replication  int
+	checksum     string // Client-declared SHA-256, if any
+	mode         api.WriteMode
+	ifGeneration int64
+	parents      bool // Create missing parent directories
+	attributes   map[string]string
+	tags         []string
+
+	// hash covers the whole file, so a mismatch between what the
+	// client meant to send and what we stored is caught end to end.
+	hash hash.Hash
+
+	received int64        // Bytes written so far, including the open chunk
+	chunks   []ChunkMeta  // Chunks fully written
+	current  *chunkWriter // Chunk being written, if any
+
+	// codec is how to compress the file's chunks (see package compression).
+	codec string
+
+	// dataKey encrypts the file's chunks, and wrappedKey is what goes in
+	// its metadata (see keys.go). Both are nil without encryption.
+	dataKey    []byte
+	wrappedKey *WrappedKey
+
+	// chunker finds the chunk boundaries for content-defined chunking;
+	// with fixed chunking it is nil.
+	chunker *cdcChunker
+
+	// dedupChunks and dedupBytes count the chunks that turned out to be
+	// stored already, and are shared instead.
+	dedupChunks int
+	dedupBytes  int64
+
+	// committed and committedHash capture the upload at the last chunk
+	// boundary; rollback returns there when the open chunk is lost.
+	committed     int64
+	committedHash []byte
+
+	// done is set once the upload is committed or aborted.
+	done bool
+}
+
+// newUpload validates an upload's metadata and starts it.
+func (s *Server) newUpload(ctx context.Context, md *api.FileMetadata) (*upload, error) {
+	// Validate the path to prevent path traversal attacks
+	// This is a security best practice!
+	filename, err := cleanFilePath(md.Filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+	if md.Size < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid size %d", md.Size)
+	}
+
+	replication := int(md.Replication)
+	if replication == 0 {
+		replication = s.defaultReplication
+	}
+	if replication < 0 || replication > MaxReplication {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid replication factor %d: must be between 1 and %d", replication, MaxReplication)
+	}
+
+	// Fail fast instead of shipping the whole file to chunkservers
+	// only to be rejected at the end.
+	if err := s.checkWriteMode(filename, md.Mode, md.IfGeneration, md.Parents); err != nil {
+		return nil, err
+	}
+	tags := sortTags(md.Tags)
+	if err := checkAttributes(md.Attributes, tags); err != nil {
+		return nil, metadataStatus(err)
+	}
+	chunking, err := s.chunkingFor(md.Chunking)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	var chunker *cdcChunker
+	if chunking == ContentDefinedChunking {
+		chunker = newCDCChunker(s.cdcAverageSize, s.chunkSize)
+	}
+	codec, err := s.compressionFor(filename, md.Compression)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	dataKey, wrappedKey, err := s.newDataKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &upload{
+		s:            s,
+		filename:     filename,
+		size:         md.Size,
+		replication:  replication,
+		checksum:     strings.ToLower(md.Checksum),
+		mode:         md.Mode,
+		ifGeneration: md.IfGeneration,
+		parents:      md.Parents,
+		attributes:   maps.Clone(md.Attributes),
+		tags:         tags,
+		hash:         sha256.New(),
+		chunker:      chunker,
+		codec:        codec,
+		dataKey:      dataKey,
+		wrappedKey:   wrappedKey,
+	}, nil
+}
+
+// write appends data to the file.
+func (u *upload) write(ctx context.Context, data []byte) error {
+	// Stop as soon as the client overruns its declared size,
+	// rather than writing data we'll throw away.
+	if u.received+int64(len(data)) > u.size {
+		return status.Errorf(codes.InvalidArgument, "upload exceeds declared size of %d bytes", u.size)
+	}
+
+	// A single message may straddle a chunk boundary, so keep
+	// splitting until all of it has been written. Each piece counts as
+	// received as it goes into a chunk: a checkpoint at the boundary
+	// mustn't cover the rest of the message, which is in the next one.
+	for len(data) > 0 {
+		if u.current == nil {
+			// The chunk holds at most a chunk's worth of what is
+			// left of the file; content-defined chunks are cut at
+			// that size too
+			size := min(u.s.chunkSize, u.size-u.received)
+			var err error
+			u.current, err = u.s.newChunkWriter(ctx, u.replication, size, u.codec, u.dataKey)
+			if err != nil {
+				return err
+			}
+		}
+
+		n, full := u.cut(data)
+		if err := u.current.Write(data[:n]); err != nil {
+			return err
+		}
+		u.received += int64(n)
+		u.hash.Write(data[:n])
+		data = data[n:]
+
+		// Chunk full: commit it and start a new one next time
+		if full {
+			if err := u.flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// cut returns how much of data goes into the open chunk, and whether that
+// ends the chunk.
+func (u *upload) cut(data []byte) (int, bool) {
+	if u.chunker != nil {
+		return u.chunker.cut(data)
+	}
+	n := min(int64(len(data)), u.s.chunkSize-u.current.Size())
+	return int(n), u.current.Size()+n == u.s.chunkSize
+}
+
+// flush closes the open chunk, if any, and records a checkpoint there.
+func (u *upload) flush() error {
+	if u.current != nil {
+		chunk, err := u.current.Close()
+		if err != nil {
+			return err
+		}
+		u.current = nil
+		u.chunks = append(u.chunks, u.dedup(chunk))
+	}
+
+	// A chunk closed early, at the end of a stream, starts the next
+	// one afresh
+	if u.chunker != nil {
+		u.chunker.reset()
+	}
+
+	u.committed = u.received
+	state, err := u.hash.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return fmt.Errorf("failed to save hash state: %w", err)
+	}
+	u.committedHash = state
+	return nil
+}
+
+// dedup is dedupChunk, also sharing chunks within the upload itself:
+// its own chunks aren't committed, so the metadata can't find them yet.
+// An encrypted file's chunks are only shared within the file, since no
+// other file has its key.
+func (u *upload) dedup(chunk ChunkMeta) ChunkMeta {
+	shared := chunk
+	for _, prev := range u.chunks {
+		if prev.Hash == chunk.Hash {
+			u.s.pending.add(prev.ID) // Held once per use, released once per use
+			u.s.deleteChunks([]ChunkMeta{chunk})
+			u.s.pending.remove(chunk.ID)
+			shared = prev
+			break
+		}
+	}
+	if shared.ID == chunk.ID && u.dataKey == nil {
+		shared = u.s.dedupChunk(chunk)
+	}
+
+	if shared.ID != chunk.ID {
+		u.dedupChunks++
+		u.dedupBytes += shared.Size
+	}
+	return shared
+}
+
+// rollback discards the open chunk and everything written since the last
+// checkpoint.
+func (u *upload) rollback() {
+	u.dropCurrent()
+	u.received = u.committed
+	if u.chunker != nil {
+		u.chunker.reset()
+	}
+
+	u.hash.Reset()
+	if u.committedHash != nil {
+		// The state came from the same hash type; this can't fail
+		u.hash.(encoding.BinaryUnmarshaler).UnmarshalBinary(u.committedHash)
+	}
+}
+
+// dropCurrent aborts the open chunk. Nothing references it, so it leaves
+// pending right away and heartbeat GC will clean up any partial copies.
+func (u *upload) dropCurrent() {
+	if u.current != nil {
+		u.current.Abort()
+		u.s.pending.remove(u.current.id)
+		u.current = nil
+	}
+}
+
+// finish commits the file once all of it has arrived.
+//
+// A short upload is reported with codes.InvalidArgument and left intact,
+// so a session can still be resumed. Any other failure aborts the upload.
+func (u *upload) finish() (*FileMeta, error) {
+	// The stream ended cleanly but short of the declared size: the client
+	// gave up (or lied). Don't commit a truncated file.
+	if u.received != u.size {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"upload truncated: received %d of %d declared bytes", u.received, u.size)
+	}
+
+	// Commit the final, partially filled chunk
+	if err := u.flush(); err != nil {
+		u.abort()
+		return nil, err
+	}
+
+	// Refuse to commit data that isn't what the client says it sent
+	sum := hex.EncodeToString(u.hash.Sum(nil))
+	if u.checksum != "" && u.checksum != sum {
+		u.abort()
+		return nil, status.Errorf(codes.DataLoss, "checksum mismatch: client sent %s, server received %s", u.checksum, sum)
+	}
+
+	meta := &FileMeta{
+		Filename:    u.filename,
+		Size:        u.received,
+		Replication: u.replication,
+		Chunks:      u.chunks,
+		Checksum:    sum,
+		Attributes:  u.attributes,
+		Tags:        u.tags,
+		DataKey:     u.wrappedKey,
+	}
+	old, err := u.s.commitFile(meta, u.mode, u.ifGeneration, u.parents)
+	if err != nil {
+		// If the metadata write fails, remove the chunks we wrote.
+		// Another upload of the same name may have beaten us to it,
+		// or someone removed the directory it was going into.
+		u.abort()
+		return nil, metadataStatus(fmt.Errorf("failed to store metadata: %w", err))
+	}
+
+	// The chunks are referenced by metadata now, so GC leaves them alone
+	for _, chunk := range u.chunks {
+		u.s.pending.remove(chunk.ID)
+	}
+	u.done = true
+
+	// The previous contents are an old version now; there may be one
+	// too many of those
+	if old != nil {
+		u.s.applyRetention(u.filename)
+	}
+	return meta, nil
+}
+
+// abort gives up on the upload and deletes everything it wrote.
+// Chunks it shares with other files are released rather than deleted:
+// they go only if those files have gone meanwhile.
+func (u *upload) abort() {
+	if u.done {
+		return
+	}
+	u.done = true
+
+	u.dropCurrent()
+	for _, chunk := range u.chunks {
+		u.s.pending.remove(chunk.ID)
+	}
+	u.s.releaseChunks(u.chunks)
+	u.chunks = nil
+}
+
+// checkWriteMode reports whether an upload in the given mode could succeed
+// right now, so a doomed upload fails before it ships any data. commitFile
+// checks again atomically; the file may change while the data streams in.
+func (s *Server) checkWriteMode(filename string, mode api.WriteMode, ifGeneration int64, parents bool) error {
+	// A directory can't be overwritten by a file in any mode
+	existing, err := s.metadata.Get(filename)
+	if err == nil && existing.IsDir {
+		return metadataStatus(fmt.Errorf("%s: %w", filename, ErrIsADirectory))
+	}
+
+	// Nor can a file go where there's no directory for it
+	if !parents {
+		if err := s.checkDir(path.Dir(filename)); err != nil {
+			return err
+		}
+	}
+
+	switch mode {
+	case api.WriteMode_WRITE_MODE_CREATE:
+		if existing != nil {
+			return metadataStatus(fmt.Errorf("failed to store metadata: %w", ErrFileAlreadyExists))
+		}
+
+	case api.WriteMode_WRITE_MODE_OVERWRITE:
+		// Always allowed
+
+	case api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH:
+		if ifGeneration < 0 {
+			return status.Errorf(codes.InvalidArgument, "invalid generation %d", ifGeneration)
+		}
+		var current int64
+		if existing != nil {
+			current = existing.generation()
+		} else if !errors.Is(err, ErrFileNotFound) {
+			return fmt.Errorf("failed to get metadata: %w", err)
+		}
+		if current != ifGeneration {
+			return metadataStatus(fmt.Errorf("%w: expected %d, current is %d", ErrGenerationMismatch, ifGeneration, current))
+		}
+
+	default:
+		return status.Errorf(codes.InvalidArgument, "unknown write mode %v", mode)
+	}
+	return nil
+}
+
+// checkDir reports whether dir exists and is a directory.
+func (s *Server) checkDir(dir string) error {
+	if dir == rootDir {
+		return nil
+	}
+	meta, err := s.metadata.Get(dir)
+	if errors.Is(err, ErrFileNotFound) {
+		return metadataStatus(fmt.Errorf("%s: %w", dir, ErrParentNotFound))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get metadata: %w", err)
+	}
+	if !meta.IsDir {
+		return metadataStatus(fmt.Errorf("%s: %w", dir, ErrNotADirectory))
+	}
+	return nil
+}
+
+// commitFile writes an uploaded file's metadata according to the write
+// mode, and publishes it, returning the metadata it replaced, if any. With
+// parents set, any missing directories on the way to the file are created
+// first.
+func (s *Server) commitFile(meta *FileMeta, mode api.WriteMode, ifGeneration int64, parents bool) (*FileMeta, error) {
+	s.changes.Lock()
+	defer s.changes.Unlock()
+	if parents {
+		created, err := s.metadata.Mkdir(path.Dir(meta.Filename), true)
+		if err != nil {
+			return nil, err
+		}
+		s.publishCreated(created)
+	}
+
+	var old *FileMeta
+	var err error
+	switch mode {
+	case api.WriteMode_WRITE_MODE_OVERWRITE:
+		old, err = s.metadata.Replace(meta, AnyGeneration)
+	case api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH:
+		old, err = s.metadata.Replace(meta, ifGeneration)
+	default:
+		err = s.metadata.Create(meta)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.publish(writeEvent(meta), meta, "")
+	return old, nil
+}
@@ -0,0 +1,246 @@
+package master
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+	"github.com/darshanmadesh/godfs/internal/compression"
+)
+
+func TestUploadReplicatesEachChunk(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024, DefaultReplication: 3}, 3, 0)
+
+	for _, tc := range []struct {
+		name        string
+		replication int32
+		want        int
+	}{
+		{"/two", 2, 2},
+		{"/default", 0, 3},
+	} {
+		data := randomData(int64(tc.want), 150*1024)
+		c.upload(t, &api.FileMetadata{Filename: tc.name, Replication: tc.replication}, data)
+		meta, err := c.master.metadata.Get(tc.name)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		if meta.Replication != tc.want {
+			t.Errorf("%s has replication %d, want %d", tc.name, meta.Replication, tc.want)
+		}
+
+		// Every location has the chunk, byte for byte, and no other
+		// chunkserver does
+		for i, chunk := range meta.Chunks {
+			if distinct := slices.Compact(slices.Sorted(slices.Values(chunk.Locations))); len(distinct) != tc.want || len(chunk.Locations) != tc.want {
+				t.Errorf("%s chunk %d on %v, want %d distinct chunkservers", tc.name, i, chunk.Locations, tc.want)
+			}
+			want := data[i*64*1024 : min((i+1)*64*1024, len(data))]
+			for addr := range c.chunkservers {
+				got := c.stored(t, addr, chunk.ID)
+				listed := slices.Contains(chunk.Locations, addr)
+				if listed && !bytes.Equal(got, want) {
+					t.Errorf("%s chunk %d: %s holds %d bytes, not the chunk", tc.name, i, addr, len(got))
+				}
+				if !listed && got != nil {
+					t.Errorf("%s chunk %d: stored on %s, which isn't one of its locations", tc.name, i, addr)
+				}
+			}
+		}
+	}
+
+	// A file can't have more replicas than there are chunkservers, nor
+	// more than the maximum
+	_, err := c.tryUpload(&api.FileMetadata{Filename: "/four", Size: 10, Replication: 4}, make([]byte, 10))
+	if err == nil || !strings.Contains(err.Error(), ErrNotEnoughChunkservers.Error()) {
+		t.Errorf("upload with 4 replicas on 3 chunkservers: err = %v, want %v", err, ErrNotEnoughChunkservers)
+	}
+	_, err = c.tryUpload(&api.FileMetadata{Filename: "/many", Size: 10, Replication: MaxReplication + 1}, make([]byte, 10))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("upload with %d replicas: err = %v, want InvalidArgument", MaxReplication+1, err)
+	}
+}
+
+func TestUploadChecksumsTheFile(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024}, 1, 0)
+	data := randomData(1, 100*1024)
+	sum := sha256.Sum256(data)
+	want := hex.EncodeToString(sum[:])
+
+	// A checksum the client declares is checked, in either case
+	c.upload(t, &api.FileMetadata{Filename: "/f", Replication: 1, Checksum: strings.ToUpper(want)}, data)
+	meta, err := c.master.metadata.Get("/f")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if meta.Checksum != want {
+		t.Errorf("stored checksum %s, want %s", meta.Checksum, want)
+	}
+
+	// Data that doesn't match it is refused, and leaves no file
+	damaged := bytes.Clone(data)
+	damaged[5000] ^= 1
+	_, err = c.tryUpload(&api.FileMetadata{Filename: "/g", Size: int64(len(damaged)), Replication: 1, Checksum: want}, damaged)
+	if status.Code(err) != codes.DataLoss {
+		t.Errorf("upload not matching its checksum: err = %v, want DataLoss", err)
+	}
+	if _, err := c.master.metadata.Get("/g"); !errors.Is(err, ErrFileNotFound) {
+		t.Errorf("upload not matching its checksum left a file: Get err = %v", err)
+	}
+}
+
+func TestDownloadSkipsACorruptReplica(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024}, 2, 0)
+	data := randomData(2, 150*1024)
+	c.upload(t, &api.FileMetadata{Filename: "/f", Replication: 2}, data)
+	meta, err := c.master.metadata.Get("/f")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+
+	// The disk of the replica read first flips a bit in the second chunk
+	chunk := meta.Chunks[1]
+	path := filepath.Join(c.chunkservers[chunk.Locations[0]].dir, chunk.ID)
+	stored, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored[100] ^= 1
+	if err := os.WriteFile(path, stored, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The other replica serves it, and the bad copy is taken out of use
+	if got := c.download(t, &api.DownloadRequest{Filename: "/f"}); !bytes.Equal(got, data) {
+		t.Errorf("file reads back as %d bytes, not what was written", len(got))
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("corrupt chunk still in place: %v", err)
+	}
+}
+
+func TestUploadMustMatchItsDeclaredSize(t *testing.T) {
+	c := startCluster(t, Config{ChunkSize: 64 * 1024}, 1, 0)
+	data := randomData(3, 150*1024)
+
+	for _, tc := range []struct {
+		name string
+		size int64
+	}{
+		{"/short", 200 * 1024}, // The client stopped early
+		{"/long", 100 * 1024},  // The client sent more than it said
+	} {
+		_, err := c.tryUpload(&api.FileMetadata{Filename: tc.name, Size: tc.size, Replication: 1}, data)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("%s: err = %v, want InvalidArgument", tc.name, err)
+		}
+		if _, err := c.master.metadata.Get(tc.name); !errors.Is(err, ErrFileNotFound) {
+			t.Errorf("%s: file committed anyway: Get err = %v", tc.name, err)
+		}
+	}
+
+	// Neither leaves chunks behind
+	for addr, cs := range c.chunkservers {
+		if left, _ := filepath.Glob(filepath.Join(cs.dir, "[0-9a-f]*")); len(left) != 0 {
+			t.Errorf("refused uploads left %d chunk files on %s", len(left), addr)
+		}
+	}
+}
+
+func TestWriteModeRefusesDoomedUploadsUpFront(t *testing.T) {
+	s := newTestServer(t, Config{})
+	mkdirs(t, s.metadata, "/d")
+	createFile(t, s.metadata, "/d/f", 1)
+
+	for _, tc := range []struct {
+		name         string
+		mode         api.WriteMode
+		ifGeneration int64
+		want         codes.Code
+	}{
+		{"/d/f", api.WriteMode_WRITE_MODE_CREATE, 0, codes.AlreadyExists},
+		{"/d/g", api.WriteMode_WRITE_MODE_CREATE, 0, codes.OK},
+		{"/d/f", api.WriteMode_WRITE_MODE_OVERWRITE, 0, codes.OK},
+		{"/d/f", api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH, 1, codes.OK},
+		{"/d/f", api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH, 2, codes.FailedPrecondition},
+		{"/d/g", api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH, 0, codes.OK},
+		{"/d/f", api.WriteMode_WRITE_MODE_IF_GENERATION_MATCH, -1, codes.InvalidArgument},
+		{"/d", api.WriteMode_WRITE_MODE_OVERWRITE, 0, codes.FailedPrecondition},
+		{"/e/f", api.WriteMode_WRITE_MODE_CREATE, 0, codes.NotFound},
+	} {
+		err := s.checkWriteMode(tc.name, tc.mode, tc.ifGeneration, false)
+		if status.Code(err) != tc.want {
+			t.Errorf("%s, %v at generation %d: err = %v, want %v", tc.name, tc.mode, tc.ifGeneration, err, tc.want)
+		}
+	}
+}
+
+// wireCounter counts the bytes of RPC messages, and what they took up on
+// the wire. It is a gRPC stats handler.
+type wireCounter struct {
+	sent, sentWire         atomic.Int64
+	received, receivedWire atomic.Int64
+}
+
+func (w *wireCounter) HandleRPC(ctx context.Context, s stats.RPCStats) {
+	switch p := s.(type) {
+	case *stats.OutPayload:
+		w.sent.Add(int64(p.Length))
+		w.sentWire.Add(int64(p.WireLength))
+	case *stats.InPayload:
+		w.received.Add(int64(p.Length))
+		w.receivedWire.Add(int64(p.WireLength))
+	}
+}
+
+func (w *wireCounter) TagRPC(ctx context.Context, _ *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireCounter) TagConn(ctx context.Context, _ *stats.ConnTagInfo) context.Context {
+	return ctx
+}
+
+func (w *wireCounter) HandleConn(context.Context, stats.ConnStats) {}
+
+func TestTransfersOverACompressedWire(t *testing.T) {
+	c := startCluster(t, Config{}, 1, 0)
+	text := bytes.Repeat([]byte("2026-10-16 request served in 12ms\n"), 30000)
+
+	for _, codec := range []string{compression.Gzip, compression.Zstd} {
+		t.Run(codec, func(t *testing.T) {
+			// A client asking for the codec, against the usual master
+			wire := &wireCounter{}
+			client := *c
+			client.client = c.connect(t, grpc.WithStatsHandler(wire), grpc.WithDefaultCallOptions(grpc.UseCompressor(codec)))
+
+			// The upload is compressed on the way in, and the master's
+			// replies on the way out
+			name := "/" + codec
+			client.upload(t, &api.FileMetadata{Filename: name, Replication: 1}, text)
+			if sent, onWire := wire.sent.Load(), wire.sentWire.Load(); onWire > sent/10 {
+				t.Errorf("upload sent %d bytes on the wire for %d", onWire, sent)
+			}
+			if got := client.download(t, &api.DownloadRequest{Filename: name}); !bytes.Equal(got, text) {
+				t.Errorf("downloaded %d bytes, not the %d uploaded", len(got), len(text))
+			}
+			if received, onWire := wire.received.Load(), wire.receivedWire.Load(); onWire > received/10 {
+				t.Errorf("download received %d bytes on the wire for %d", onWire, received)
+			}
+		})
+	}
+}
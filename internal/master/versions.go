@@ -0,0 +1,224 @@
+package master
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/darshanmadesh/godfs/api"
+)
+
+// Every write to a file - an upload, or a rename or copy onto it - keeps
+// what it replaced as an old version, identified by its generation. Old
+// versions are immutable and share chunks with whatever is unchanged, so
+// restoring one is a metadata change, like Copy.
+//
+// Retention rules decide how many old versions are kept, and for how long.
+
+// DefaultKeepVersions is how many old versions of a file are kept when no
+// retention rule says otherwise.
+const DefaultKeepVersions = 10
+
+// RetentionRule says which old versions of the files under a path to keep.
+// A version is deleted as soon as it is outside either limit.
+type RetentionRule struct {
+	// Prefix is the directory (or file) the rule covers. Where several
+	// rules cover a file, the one with the longest prefix applies.
+	Prefix string
+
+	// KeepVersions is how many old versions to keep, newest first.
+	// Zero means no limit.
+	KeepVersions int
+
+	// KeepFor is how long to keep a version once a newer one has replaced
+	// it. Zero means no limit.
+	KeepFor time.Duration
+}
+
+// ParseRetentionRule parses a rule written as "PREFIX=keep:N,days:D", where
+// either of keep and days may be left out: "/team/ml=keep:5,days:30".
+func ParseRetentionRule(s string) (RetentionRule, error) {
+	prefix, limits, ok := strings.Cut(s, "=")
+	if !ok || prefix == "" {
+		return RetentionRule{}, fmt.Errorf("invalid retention rule %q: want PREFIX=keep:N,days:D", s)
+	}
+
+	rule := RetentionRule{Prefix: prefix}
+	for _, limit := range strings.Split(limits, ",") {
+		key, value, _ := strings.Cut(limit, ":")
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return RetentionRule{}, fmt.Errorf("invalid retention rule %q: bad value in %q", s, limit)
+		}
+		switch key {
+		case "keep":
+			rule.KeepVersions = n
+		case "days":
+			rule.KeepFor = time.Duration(n) * 24 * time.Hour
+		default:
+			return RetentionRule{}, fmt.Errorf("invalid retention rule %q: unknown limit %q", s, key)
+		}
+	}
+	return rule, nil
+}
+
+// covers reports whether the rule applies to filename.
+func (r RetentionRule) covers(filename string) bool {
+	return r.Prefix == rootDir || filename == r.Prefix || strings.HasPrefix(filename, r.Prefix+"/")
+}
+
+// expired returns the generations among a file's old versions, newest
+// first, that the rule no longer keeps.
+func (r RetentionRule) expired(versions []*FileMeta, now time.Time) []int64 {
+	var gens []int64
+	for i, v := range versions {
+		tooMany := r.KeepVersions > 0 && i >= r.KeepVersions
+		tooOld := r.KeepFor > 0 && now.Sub(v.SupersededAt) > r.KeepFor
+		if tooMany || tooOld {
+			gens = append(gens, v.Generation)
+		}
+	}
+	return gens
+}
+
+// retentionFor returns the retention rule for a file.
+func (s *Server) retentionFor(filename string) RetentionRule {
+	best := RetentionRule{Prefix: rootDir, KeepVersions: DefaultKeepVersions}
+	found := false
+	for _, rule := range s.retention {
+		if rule.covers(filename) && (!found || len(rule.Prefix) > len(best.Prefix)) {
+			best, found = rule, true
+		}
+	}
+	return best
+}
+
+// applyRetention deletes the old versions of a file its retention rule no
+// longer keeps. Writes call it after adding a version.
+func (s *Server) applyRetention(filename string) {
+	versions, err := s.metadata.ListVersions(filename)
+	if err != nil {
+		return // Deleted meanwhile, and its versions with it
+	}
+	s.pruneVersions(filename, versions[1:], time.Now())
+}
+
+// expireVersions applies the retention rules to every file's old versions,
+// to catch those that have aged out since they were written.
+func (s *Server) expireVersions() {
+	versions, err := s.metadata.ListOldVersions()
+	if err != nil {
+		log.Printf("master: failed to list old versions: %v", err)
+		return
+	}
+
+	// Versions come grouped by file, newest first
+	now := time.Now()
+	for start := 0; start < len(versions); {
+		end := start + 1
+		for end < len(versions) && versions[end].Filename == versions[start].Filename {
+			end++
+		}
+		s.pruneVersions(versions[start].Filename, versions[start:end], now)
+		start = end
+	}
+}
+
+// pruneVersions deletes the old versions, newest first, that a file's
+// retention rule no longer keeps.
+func (s *Server) pruneVersions(filename string, versions []*FileMeta, now time.Time) {
+	gens := s.retentionFor(filename).expired(versions, now)
+	if len(gens) == 0 {
+		return
+	}
+
+	removed, err := s.metadata.DeleteVersions(filename, gens)
+	if err != nil {
+		log.Printf("master: failed to delete old versions of %s: %v", filename, err)
+		return
+	}
+	s.releaseFiles(removed)
+}
+
+// releaseFiles releases the chunks of files (or versions) that are gone.
+func (s *Server) releaseFiles(files []*FileMeta) {
+	for _, meta := range files {
+		s.releaseChunks(meta.Chunks)
+	}
+}
+
+// ListVersions returns every version of a file kept, newest first.
+// The first is the current version.
+func (s *Server) ListVersions(ctx context.Context, req *api.ListVersionsRequest) (*api.ListVersionsResponse, error) {
+	filename, err := cleanFilePath(req.Filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	versions, err := s.metadata.ListVersions(filename)
+	if err != nil {
+		return nil, metadataStatus(fmt.Errorf("failed to list versions: %w", err))
+	}
+
+	infos := make([]*api.FileInfo, 0, len(versions))
+	for _, v := range versions {
+		infos = append(infos, fileInfo(v))
+	}
+	return &api.ListVersionsResponse{
+		Versions: infos,
+	}, nil
+}
+
+// Restore makes an old version of a file current again. It is written as
+// a new generation, so what it replaces is kept as a version in turn and
+// the restore itself can be undone.
+func (s *Server) Restore(ctx context.Context, req *api.RestoreRequest) (*api.RestoreResponse, error) {
+	filename, err := cleanFilePath(req.Filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+
+	s.changes.Lock()
+	meta, err := s.metadata.Restore(filename, req.Version)
+	if err != nil {
+		s.changes.Unlock()
+		return nil, metadataStatus(fmt.Errorf("failed to restore: %w", err))
+	}
+	s.publish(api.WatchEventType_WATCH_EVENT_UPDATE, meta, "")
+	s.changes.Unlock()
+	s.applyRetention(filename)
+
+	return &api.RestoreResponse{
+		Success:    true,
+		Message:    fmt.Sprintf("Restored '%s' to generation %d as generation %d", filename, req.Version, meta.Generation),
+		Generation: meta.Generation,
+	}, nil
+}
+
+// getVersion looks up a version of a file, or the current one if version
+// is zero, with errors as for getFile.
+func (s *Server) getVersion(filename string, version int64) (*FileMeta, error) {
+	if version == 0 {
+		return s.getFile(filename)
+	}
+
+	p, err := cleanFilePath(filename)
+	if err != nil {
+		return nil, metadataStatus(err)
+	}
+	meta, err := s.metadata.GetVersion(p, version)
+	if errors.Is(err, ErrVersionNotFound) {
+		return nil, status.Errorf(codes.NotFound, "version %d of %s not found", version, filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata: %w", err)
+	}
+	return meta, nil
+}
@@ -0,0 +1,475 @@
+package master
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File names used inside the metadata directory.
+const (
+	walFileName      = "metadata.wal"
+	snapshotFileName = "metadata.snapshot"
+)
+
+// DefaultSnapshotEvery is the number of WAL records after which the log is
+// compacted into a fresh snapshot.
+const DefaultSnapshotEvery = 1000
+
+// WALMetadataStore is a durable MetadataStore.
+//
+// Reads are served from an in-memory map exactly like InMemoryMetadataStore.
+// Every write is first appended to a write-ahead log (WAL) and fsync'd, so
+// once Create/Update/Delete returns the change survives a crash. To keep
+// startup fast the log is periodically compacted: the whole map is written
+// to a snapshot file and the log is truncated.
+//
+// On startup the store loads the latest snapshot and replays the records
+// written after it.
+//
+// On-disk layout (inside dir):
+//
+//	metadata.snapshot  - JSON snapshot of all files and versions + the last sequence it covers
+//	metadata.wal       - one record per line: "<crc32> <json>\n"
+type WALMetadataStore struct {
+	// Embedding gives us all the read methods (Get, List, Exists) for free.
+	// Writes still go through the embedded store; we only hook in as its journal.
+	*InMemoryMetadataStore
+
+	dir string
+	wal *os.File
+
+	// seq is the sequence number of the last record written to the WAL.
+	seq uint64
+
+	// size is the length of the WAL up to the end of its last record: where
+	// a failed append is cut back to.
+	size int64
+
+	// failed is set once the WAL can no longer be trusted to hold what we
+	// write to it; every append fails from then on (see append).
+	failed error
+
+	// snapshotEvery and pending control compaction: once pending records
+	// have accumulated since the last snapshot, a new one is taken.
+	snapshotEvery int
+	pending       int
+
+	// stop/done coordinate the periodic snapshot goroutine with Close.
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// walRecord is a single entry in the write-ahead log.
+type walRecord struct {
+	Seq      uint64    `json:"seq"`
+	Mutation *mutation `json:"mutation"`
+}
+
+// snapshot is the on-disk format of a compacted metadata store.
+type snapshot struct {
+	// Seq is the sequence number of the last WAL record included.
+	Seq   uint64      `json:"seq"`
+	Files []*FileMeta `json:"files"`
+
+	// Versions holds the files' old versions.
+	Versions []*FileMeta `json:"versions,omitempty"`
+
+	// Trash holds deleted files waiting to be undeleted or purged.
+	Trash []*TrashEntry `json:"trash,omitempty"`
+}
+
+// NewWALMetadataStore opens (or creates) a durable metadata store in dir,
+// replaying any existing snapshot and log.
+// snapshotEvery <= 0 uses DefaultSnapshotEvery. snapshotInterval > 0 also
+// compacts the log on a timer, so a quiet cluster doesn't carry a long WAL.
+func NewWALMetadataStore(dir string, snapshotEvery int, snapshotInterval time.Duration) (*WALMetadataStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create metadata directory: %w", err)
+	}
+	if snapshotEvery <= 0 {
+		snapshotEvery = DefaultSnapshotEvery
+	}
+
+	w := &WALMetadataStore{
+		InMemoryMetadataStore: NewInMemoryMetadataStore(),
+		dir:                   dir,
+		snapshotEvery:         snapshotEvery,
+		stop:                  make(chan struct{}),
+		done:                  make(chan struct{}),
+	}
+
+	// Rebuild state before we start accepting writes.
+	if err := w.recover(); err != nil {
+		return nil, err
+	}
+
+	wal, err := os.OpenFile(w.walPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	info, err := wal.Stat()
+	if err != nil {
+		wal.Close()
+		return nil, fmt.Errorf("failed to stat WAL: %w", err)
+	}
+	w.wal = wal
+	w.size = info.Size()
+
+	// From now on every mutation of the embedded store goes through us.
+	w.InMemoryMetadataStore.journal = w
+
+	go w.snapshotLoop(snapshotInterval)
+
+	return w, nil
+}
+
+// Close takes a final snapshot and releases the WAL file.
+// It is safe to call more than once.
+func (w *WALMetadataStore) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+
+		w.mu.Lock()
+		defer w.mu.Unlock()
+
+		if w.pending > 0 || w.failed != nil {
+			err = w.snapshotLocked()
+		}
+		if cerr := w.wal.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// append implements journal. It writes the record and fsyncs before
+// returning so the caller only applies mutations that are on disk.
+//
+// A record that fails to go down whole mustn't stay in the log: replay
+// stops at a torn record, so everything appended after it would be lost
+// on restart, acknowledged or not. The log is cut back to the end of the
+// last good record. A failed fsync is worse - the kernel may have dropped
+// pages it was holding for earlier records too, and won't say which - so
+// then the WAL is marked failed and refuses every further write, rather
+// than acknowledge changes it may not keep. A snapshot, which writes out
+// the whole state afresh, puts it back in service.
+func (w *WALMetadataStore) append(m *mutation) error {
+	if w.failed != nil {
+		return fmt.Errorf("WAL is unusable after an earlier failure: %w", w.failed)
+	}
+
+	data, err := json.Marshal(walRecord{Seq: w.seq + 1, Mutation: m})
+	if err != nil {
+		return fmt.Errorf("failed to encode WAL record: %w", err)
+	}
+
+	// The checksum lets recovery tell a torn final write apart from a
+	// valid record. Format: "<crc32 hex> <json>\n"
+	line := fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(data), data)
+	if _, err := w.wal.WriteString(line); err != nil {
+		err = fmt.Errorf("failed to write WAL: %w", err)
+		w.rollback(err)
+		return err
+	}
+
+	// Sync forces the data out of the OS page cache onto the disk.
+	// Without it a power loss could drop writes we already acknowledged.
+	if err := w.wal.Sync(); err != nil {
+		err = fmt.Errorf("failed to sync WAL: %w", err)
+		w.rollback(err)
+		w.failed = err
+		return err
+	}
+
+	w.seq++
+	w.pending++
+	w.size += int64(len(line))
+	return nil
+}
+
+// rollback cuts the WAL back to the end of the last good record after a
+// failed append. If even that fails, the WAL is marked failed.
+func (w *WALMetadataStore) rollback(cause error) {
+	if err := truncateFile(w.wal, w.size); err != nil {
+		log.Printf("metadata: failed to cut WAL back after %v: %v", cause, err)
+		w.failed = cause
+	}
+}
+
+// applied implements journal. It compacts the log once enough records
+// have piled up since the last snapshot.
+func (w *WALMetadataStore) applied() {
+	if w.pending < w.snapshotEvery {
+		return
+	}
+	// A failed snapshot is not fatal: the WAL still holds every record,
+	// so we just log it and try again after the next write.
+	if err := w.snapshotLocked(); err != nil {
+		log.Printf("metadata: snapshot failed: %v", err)
+	}
+}
+
+// snapshotLoop compacts the log on a timer until Close is called.
+func (w *WALMetadataStore) snapshotLoop(interval time.Duration) {
+	defer close(w.done)
+
+	if interval <= 0 {
+		<-w.stop
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.pending > 0 || w.failed != nil {
+				if err := w.snapshotLocked(); err != nil {
+					log.Printf("metadata: snapshot failed: %v", err)
+				}
+			}
+			w.mu.Unlock()
+		}
+	}
+}
+
+// snapshotLocked writes the full state to a new snapshot and truncates the WAL.
+// Callers must hold the write lock.
+//
+// The order of operations makes this crash-safe at every step:
+//  1. write the snapshot to a temp file and fsync it
+//  2. atomically rename it over the old snapshot
+//  3. truncate the WAL
+//
+// If we crash after 2 but before 3, recovery skips WAL records whose
+// sequence number is already covered by the snapshot.
+func (w *WALMetadataStore) snapshotLocked() error {
+	snap := snapshot{
+		Seq:   w.seq,
+		Files: make([]*FileMeta, 0, len(w.files)),
+	}
+	// In path order, so loading it back appends to the path index
+	// rather than inserting into the middle of it
+	for _, p := range w.index.paths {
+		snap.Files = append(snap.Files, w.files[p])
+	}
+	for _, versions := range w.versions {
+		for _, v := range versions {
+			snap.Versions = append(snap.Versions, v)
+		}
+	}
+	for _, entry := range w.trash {
+		snap.Trash = append(snap.Trash, entry)
+	}
+
+	if err := writeFileAtomic(w.snapshotPath(), func(f *os.File) error {
+		return json.NewEncoder(f).Encode(&snap)
+	}); err != nil {
+		return fmt.Errorf("failed to write snapshot: %w", err)
+	}
+
+	// Everything in the WAL is now covered by the snapshot.
+	if err := w.wal.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	w.size = 0
+	if err := w.wal.Sync(); err != nil {
+		return fmt.Errorf("failed to sync WAL: %w", err)
+	}
+
+	// The snapshot holds everything applied, so whatever happened to
+	// the WAL before doesn't matter any more
+	if w.failed != nil {
+		log.Printf("metadata: WAL back in service after snapshot (it failed with: %v)", w.failed)
+		w.failed = nil
+	}
+	w.pending = 0
+	return nil
+}
+
+// recover loads the snapshot (if any) and replays the WAL on top of it.
+func (w *WALMetadataStore) recover() error {
+	if err := w.loadSnapshot(); err != nil {
+		return err
+	}
+	return w.replayWAL()
+}
+
+// loadSnapshot populates the store from the snapshot file, if present.
+func (w *WALMetadataStore) loadSnapshot() error {
+	f, err := os.Open(w.snapshotPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Fresh store
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot: %w", err)
+	}
+	defer f.Close()
+
+	var snap snapshot
+	if err := json.NewDecoder(f).Decode(&snap); err != nil {
+		return fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	sortByFilename(snap.Files) // Snapshots from before the path index weren't
+	w.apply(&mutation{Put: snap.Files, PutVersions: snap.Versions, PutTrash: snap.Trash})
+	w.seq = snap.Seq
+	return nil
+}
+
+// replayWAL applies every record newer than the snapshot.
+//
+// A crash in the middle of append can leave a partial last line. That
+// record was never acknowledged, so it is safe to drop: we truncate the
+// log back to the end of the last good record. Damage anywhere else means
+// acknowledged writes are unreadable, which we refuse to paper over.
+func (w *WALMetadataStore) replayWAL() error {
+	f, err := os.OpenFile(w.walPath(), os.O_RDWR, 0644)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+
+	reader := bufio.NewReader(f)
+	var good int64 // Byte offset just past the last valid record
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			if line != "" {
+				// Partial line with no newline: a torn write.
+				log.Printf("metadata: discarding torn WAL record at offset %d", good)
+				return truncateFile(f, good)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read WAL: %w", err)
+		}
+
+		rec, err := decodeWALRecord(line)
+		if err != nil {
+			// Only tolerate a bad record if it is the last thing in the file.
+			if _, peekErr := reader.Peek(1); peekErr == io.EOF {
+				log.Printf("metadata: discarding torn WAL record at offset %d: %v", good, err)
+				return truncateFile(f, good)
+			}
+			return fmt.Errorf("corrupt WAL record at offset %d: %w", good, err)
+		}
+		good += int64(len(line))
+
+		if rec.Seq <= w.seq {
+			continue // Already included in the snapshot
+		}
+		if rec.Seq != w.seq+1 {
+			return fmt.Errorf("WAL sequence gap: expected %d, got %d", w.seq+1, rec.Seq)
+		}
+
+		w.apply(rec.Mutation)
+		w.seq = rec.Seq
+		w.pending++
+	}
+}
+
+// decodeWALRecord parses and verifies a single "<crc32> <json>\n" line.
+func decodeWALRecord(line string) (*walRecord, error) {
+	sum, data, ok := strings.Cut(strings.TrimSuffix(line, "\n"), " ")
+	if !ok {
+		return nil, errors.New("malformed record")
+	}
+
+	want, err := strconv.ParseUint(sum, 16, 32)
+	if err != nil {
+		return nil, fmt.Errorf("malformed checksum: %w", err)
+	}
+	if crc32.ChecksumIEEE([]byte(data)) != uint32(want) {
+		return nil, errors.New("checksum mismatch")
+	}
+
+	var rec walRecord
+	if err := json.Unmarshal([]byte(data), &rec); err != nil {
+		return nil, fmt.Errorf("malformed record: %w", err)
+	}
+	if rec.Mutation == nil {
+		return nil, errors.New("record has no mutation")
+	}
+	return &rec, nil
+}
+
+// truncateFile cuts f back to size bytes and syncs the result.
+func truncateFile(f *os.File, size int64) error {
+	if err := f.Truncate(size); err != nil {
+		return fmt.Errorf("failed to truncate WAL: %w", err)
+	}
+	return f.Sync()
+}
+
+// writeFileAtomic writes a file via a temp file + rename so readers never
+// observe a half-written file, even across crashes.
+func writeFileAtomic(path string, write func(f *os.File) error) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// Clean up the temp file on any failure; after a successful rename
+	// this Remove is a harmless no-op.
+	defer os.Remove(tmp.Name())
+
+	if err := write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir fsyncs a directory so that a rename inside it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+func (w *WALMetadataStore) walPath() string {
+	return filepath.Join(w.dir, walFileName)
+}
+
+func (w *WALMetadataStore) snapshotPath() string {
+	return filepath.Join(w.dir, snapshotFileName)
+}
+
+// Compile-time check that WALMetadataStore implements MetadataStore.
+var _ MetadataStore = (*WALMetadataStore)(nil)
@@ -0,0 +1,236 @@
+package master
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openWAL opens a WAL store in dir that only snapshots when told to, so
+// tests control what is in the log.
+func openWAL(t *testing.T, dir string) *WALMetadataStore {
+	t.Helper()
+	w, err := NewWALMetadataStore(dir, 1<<20, 0)
+	if err != nil {
+		t.Fatalf("NewWALMetadataStore: %v", err)
+	}
+	return w
+}
+
+// crash abandons a store the way a killed process would: no final
+// snapshot, just whatever already reached the WAL.
+func crash(w *WALMetadataStore) {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+		w.wal.Close()
+	})
+}
+
+// createFile adds a small file to the store, failing the test on error.
+func createFile(t *testing.T, store MetadataStore, name string, size int64) {
+	t.Helper()
+	if err := store.Create(&FileMeta{Filename: name, Size: size, Replication: 1}); err != nil {
+		t.Fatalf("Create(%s): %v", name, err)
+	}
+}
+
+// wantFiles checks the store holds exactly the named files and
+// directories, besides the root.
+func wantFiles(t *testing.T, store MetadataStore, want ...string) {
+	t.Helper()
+	files, err := store.List("")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var got []string
+	for _, f := range files {
+		if f.Filename != "/" {
+			got = append(got, f.Filename)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("files = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestWALReplaysAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	if _, err := w.Mkdir("/logs", false); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	createFile(t, w, "/logs/a", 10)
+	createFile(t, w, "/logs/b", 20)
+	if _, err := w.Replace(&FileMeta{Filename: "/logs/a", Size: 11, Replication: 1}, AnyGeneration); err != nil {
+		t.Fatalf("Replace: %v", err)
+	}
+	if _, err := w.Delete("/logs/b"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	crash(w)
+
+	w = openWAL(t, dir)
+	defer w.Close()
+	wantFiles(t, w, "/logs", "/logs/a")
+	a, err := w.Get("/logs/a")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if a.Size != 11 || a.Generation != 2 {
+		t.Errorf("replayed /logs/a has size %d, generation %d; want 11, 2", a.Size, a.Generation)
+	}
+	if versions, _ := w.ListVersions("/logs/a"); len(versions) != 2 {
+		t.Errorf("replayed /logs/a has %d versions, want 2", len(versions))
+	}
+}
+
+func TestWALReplaysOnTopOfSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	createFile(t, w, "/a", 1)
+	w.mu.Lock()
+	if err := w.snapshotLocked(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	w.mu.Unlock()
+	createFile(t, w, "/b", 2)
+	crash(w)
+
+	w = openWAL(t, dir)
+	defer w.Close()
+	wantFiles(t, w, "/a", "/b")
+	if w.seq != 2 {
+		t.Errorf("seq = %d after replay, want 2", w.seq)
+	}
+}
+
+func TestWALDiscardsTornRecord(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	createFile(t, w, "/a", 1)
+	createFile(t, w, "/b", 2)
+	crash(w)
+
+	// A crash in the middle of writing a third record
+	path := filepath.Join(dir, walFileName)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	good := info.Size()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`0badc0de {"seq":3,"mutation":{"Put":[{"Filen`)
+	f.Close()
+
+	w = openWAL(t, dir)
+	wantFiles(t, w, "/a", "/b")
+	if info, _ := os.Stat(path); info.Size() != good {
+		t.Errorf("WAL is %d bytes after recovery, want it cut back to %d", info.Size(), good)
+	}
+
+	// New records go after the last good one, and survive another crash
+	createFile(t, w, "/c", 3)
+	crash(w)
+	w = openWAL(t, dir)
+	defer w.Close()
+	wantFiles(t, w, "/a", "/b", "/c")
+}
+
+func TestWALRefusesCorruptionBeforeTheEnd(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	createFile(t, w, "/a", 1)
+	createFile(t, w, "/b", 2)
+	crash(w)
+
+	// Damage the first record: an acknowledged write, not a torn one
+	path := filepath.Join(dir, walFileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewWALMetadataStore(dir, 1<<20, 0); err == nil {
+		t.Fatal("opened a WAL with a corrupt record in the middle")
+	}
+}
+
+func TestWALFailedAppendStopsWrites(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	createFile(t, w, "/a", 1)
+
+	// Swap in a descriptor that can't be written, or cut back: the
+	// append fails, and so does rolling it back
+	path := filepath.Join(dir, walFileName)
+	writable := w.wal
+	readOnly, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.wal = readOnly
+	if err := w.Create(&FileMeta{Filename: "/b", Replication: 1}); err == nil {
+		t.Fatal("Create succeeded with an unwritable WAL")
+	}
+	if w.Exists("/b") {
+		t.Error("failed write was applied")
+	}
+
+	// Even with the WAL writable again, it stays out of service: what is
+	// on disk can't be trusted until a snapshot replaces it
+	w.wal = writable
+	readOnly.Close()
+	err = w.Create(&FileMeta{Filename: "/c", Replication: 1})
+	if err == nil {
+		t.Fatal("Create succeeded after the WAL failed")
+	}
+
+	w.mu.Lock()
+	if err := w.snapshotLocked(); err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	w.mu.Unlock()
+	createFile(t, w, "/d", 4)
+	crash(w)
+
+	w = openWAL(t, dir)
+	defer w.Close()
+	wantFiles(t, w, "/a", "/d")
+}
+
+func TestWALRollsBackFailedWrite(t *testing.T) {
+	dir := t.TempDir()
+	w := openWAL(t, dir)
+	createFile(t, w, "/a", 1)
+
+	// A record that doesn't make it to disk whole is cut off again, so
+	// the next one isn't stranded behind it
+	w.mu.Lock()
+	w.wal.WriteString(`0badc0de {"seq":2,"mut`)
+	w.rollback(errors.New("injected write failure"))
+	failed := w.failed
+	w.mu.Unlock()
+	if failed != nil {
+		t.Fatalf("rollback marked the WAL failed: %v", failed)
+	}
+
+	createFile(t, w, "/b", 2)
+	crash(w)
+	w = openWAL(t, dir)
+	defer w.Close()
+	wantFiles(t, w, "/a", "/b")
+}